@@ -0,0 +1,78 @@
+// fixturegen writes synthetic Chromium User Data directories with known keys and secrets, for
+// exercising the decrypt paths without a real browser profile.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"go-cookie-monster/pkg/fixture"
+)
+
+func main() {
+	outputDir := flag.String("out", "fixture", "output directory")
+	platform := flag.String("platform", fixture.PlatformWindows, "platform to generate for: windows, linux, mac or all (one subdirectory each)")
+	seed := flag.Int64("seed", 1, "seed the keys and secrets are derived from")
+	verify := flag.Bool("verify", false, "decrypt the generated fixture and compare it with the known answers")
+	flag.Parse()
+
+	platforms := []string{*platform}
+	if *platform == "all" {
+		platforms = []string{fixture.PlatformWindows, fixture.PlatformLinux, fixture.PlatformMac}
+	}
+
+	failed := false
+	for _, p := range platforms {
+		dir := *outputDir
+		if len(platforms) > 1 {
+			dir = filepath.Join(dir, p)
+		}
+
+		f, err := fixture.Generate(dir, fixture.Options{Platform: p, Seed: *seed})
+		if err != nil {
+			log.Fatalf("[-] Error generating %s fixture: %v", p, err)
+		}
+		fmt.Printf("[+] Wrote %s fixture to %s\n", p, f.UserDataDir)
+		printUsage(f)
+
+		if *verify {
+			checked, errs := fixture.Verify(f)
+			for _, err := range errs {
+				log.Printf("[-] Mismatch: %v", err)
+			}
+			if len(errs) > 0 {
+				failed = true
+				continue
+			}
+			fmt.Printf("[+] Verified %d values\n", checked)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// printUsage prints the keys of a fixture and how to decrypt it
+func printUsage(f *fixture.Fixture) {
+	switch f.Platform {
+	case fixture.PlatformWindows:
+		fmt.Printf("[*] DPAPI masterkey: %s\n", f.MasterKey)
+		fmt.Printf("[*] SYSTEM DPAPI masterkey: %s\n", f.SystemMasterKey)
+		fmt.Printf("[*] os_crypt key: %s\n", f.OSCryptKey)
+		fmt.Printf("[*] App-bound key: %s\n", f.AppBoundKey)
+		fmt.Printf("[*] go-cookie-monster decrypt-profile -userdata \"%s\" -masterkeys \"%s\" -systemmasterkeys \"%s\"\n",
+			f.UserDataDir, f.MasterKey, f.SystemMasterKey)
+	case fixture.PlatformLinux:
+		fmt.Printf("[*] Keyring password: %s\n", f.KeyringPassword)
+		fmt.Printf("[*] go-cookie-monster cookies -platform linux -keyring \"%s\" -dbpath \"%s\"\n",
+			f.KeyringPassword, filepath.Join(f.UserDataDir, "Default", "Network", "Cookies"))
+	case fixture.PlatformMac:
+		fmt.Printf("[*] Safe Storage password: %s\n", f.SafeStoragePassword)
+		fmt.Printf("[*] go-cookie-monster cookies -platform mac -keyring \"%s\" -dbpath \"%s\"\n",
+			f.SafeStoragePassword, filepath.Join(f.UserDataDir, "Default", "Network", "Cookies"))
+	}
+}
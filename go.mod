@@ -5,6 +5,7 @@ go 1.23.3
 require (
 	github.com/go-ole/go-ole v1.3.0
 	github.com/mattn/go-sqlite3 v1.14.24
+	golang.org/x/crypto v0.29.0
 	golang.org/x/sys v0.27.0
 	golang.org/x/text v0.21.0
 )
@@ -0,0 +1,59 @@
+package browsers
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Lookup finds a browser in the registry by name or process name, case-insensitively
+func Lookup(name string) (Browser, bool) {
+	for _, browser := range Registry {
+		if strings.EqualFold(browser.Name, name) || strings.EqualFold(browser.ProcessName, name) {
+			return browser, true
+		}
+	}
+	return Browser{}, false
+}
+
+// UserDataPath returns the browser's User Data directory under the given %LOCALAPPDATA%
+func (b Browser) UserDataPath(localAppData string) string {
+	return filepath.Join(append([]string{localAppData}, b.UserDataDir...)...)
+}
+
+// UserDataPaths returns the app's user data directories that exist under a user's profile
+// directory
+func (a App) UserDataPaths(userProfile string) []string {
+	var paths []string
+	for _, dir := range a.UserDataDirs {
+		path := filepath.Join(append([]string{userProfile}, dir...)...)
+		if dirExists(path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// IdentifyApp returns the registered app whose user data path userDataDir ends with
+func IdentifyApp(userDataDir string) (App, bool) {
+	path := strings.ToLower(filepath.ToSlash(filepath.Clean(userDataDir)))
+	for _, app := range Apps {
+		for _, dir := range app.UserDataDirs {
+			suffix := strings.ToLower(strings.Join(dir, "/"))
+			if strings.HasSuffix(path, "/"+suffix) || path == suffix {
+				return app, true
+			}
+		}
+	}
+	return App{}, false
+}
+
+// IsTokenCookie reports whether a cookie is one of the app's token cookies
+func (a App) IsTokenCookie(name, domain string) bool {
+	domain = strings.TrimPrefix(strings.ToLower(domain), ".")
+	for _, tc := range a.TokenCookies {
+		if name == tc.Name && (domain == tc.Domain || strings.HasSuffix(domain, "."+tc.Domain)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package browsers
+
+const (
+	// WebView2 keeps an app's user data in <app>.exe.WebView2\EBWebView unless the host picks
+	// another folder; many hosts keep the EBWebView name
+	webView2DataDir = "EBWebView"
+	webView2Suffix  = ".WebView2"
+)
+
+// Browser describes a Chromium-based browser: where its data lives and the constants its
+// app-bound encryption uses
+type Browser struct {
+	Name        string   // short name used on the command line
+	ProcessName string   // executable name
+	UserDataDir []string // User Data path segments relative to %LOCALAPPDATA%
+
+	// Elevation service COM identifiers. Edge's IElevator derives from a base interface with
+	// three methods of its own, which shift DecryptData down the vtable.
+	ElevatorCLSID       string
+	ElevatorIID         string
+	ElevatorBaseMethods int
+
+	// App-bound post-processing keys indexed by envelope flag (1: AES-GCM, 2: ChaCha20-Poly1305,
+	// 3: XOR key for the CNG-decrypted key). Missing flags fall back to the Chrome defaults.
+	AppBoundFlagKeys map[byte][]byte
+
+	// CNG key protecting flag 3 app-bound keys; empty uses the Chrome default
+	CNGKeyName string
+
+	// macOS keychain service holding the os_crypt password
+	SafeStorageService string
+}
+
+var (
+	Chrome = Browser{
+		Name:          "chrome",
+		ProcessName:   "chrome.exe",
+		UserDataDir:   []string{"Google", "Chrome", "User Data"},
+		ElevatorCLSID: "{708860E0-F641-4611-8895-7D867DD3675B}",
+		ElevatorIID:   "{463ABECF-410D-407F-8AF5-0DF35A005CC8}",
+		CNGKeyName:    "Google Chromekey1",
+
+		SafeStorageService: "Chrome Safe Storage",
+	}
+
+	Edge = Browser{
+		Name:                "edge",
+		ProcessName:         "msedge.exe",
+		UserDataDir:         []string{"Microsoft", "Edge", "User Data"},
+		ElevatorCLSID:       "{1FCBE96C-1697-43AF-9140-2897C7C69767}",
+		ElevatorIID:         "{C9C2B807-7731-4F34-81B7-44FF7779522B}",
+		ElevatorBaseMethods: 3,
+
+		SafeStorageService: "Microsoft Edge Safe Storage",
+	}
+
+	Brave = Browser{
+		Name:          "brave",
+		ProcessName:   "brave.exe",
+		UserDataDir:   []string{"BraveSoftware", "Brave-Browser", "User Data"},
+		ElevatorCLSID: "{576B31AF-6369-4B6B-8560-E4B203A97A8B}",
+		ElevatorIID:   "{F396861E-0C8E-4C71-8256-2FAE6D759CE9}",
+
+		SafeStorageService: "Brave Safe Storage",
+	}
+
+	Chromium = Browser{
+		Name:        "chromium",
+		ProcessName: "chrome.exe",
+		UserDataDir: []string{"Chromium", "User Data"},
+
+		SafeStorageService: "Chromium Safe Storage",
+	}
+
+	// Registry lists every known browser, in lookup order
+	Registry = []Browser{Chrome, Edge, Brave, Chromium}
+)
+
+// App describes an Electron (or WebView2) application that keeps Chromium-format data: a
+// Local State, Cookies and Local Storage under its own user data directory
+type App struct {
+	Name        string // short name used in results
+	ProcessName string // executable name
+
+	// Candidate user data paths, as segments relative to the user's profile directory. Store
+	// (MSIX) installs are redirected under AppData\Local\Packages.
+	UserDataDirs [][]string
+
+	// macOS keychain service holding the safeStorage password
+	SafeStorageService string
+
+	// Cookies and Local Storage keys that hold the app's session or API tokens
+	TokenCookies     []TokenCookie
+	TokenStorageKeys []string
+
+	// Prefix of Local Storage values encrypted with safeStorage, followed by base64
+	EncryptedStoragePrefix string
+}
+
+// TokenCookie matches a session cookie by name and domain suffix
+type TokenCookie struct {
+	Name   string
+	Domain string
+}
+
+var (
+	Slack = App{
+		Name:        "slack",
+		ProcessName: "slack.exe",
+		UserDataDirs: [][]string{
+			{"AppData", "Roaming", "Slack"},
+			{"AppData", "Local", "Packages", "91750D7E.Slack_8she8kybcnzg4", "LocalCache", "Roaming", "Slack"},
+		},
+		SafeStorageService: "Slack Safe Storage",
+		// The d cookie is the session; xoxc tokens are only valid alongside it
+		TokenCookies: []TokenCookie{{Name: "d", Domain: "slack.com"}, {Name: "d-s", Domain: "slack.com"}},
+	}
+
+	Teams = App{
+		Name:        "teams",
+		ProcessName: "ms-teams.exe",
+		UserDataDirs: [][]string{
+			// New Teams is a WebView2 host with a regular User Data layout
+			{"AppData", "Local", "Packages", "MSTeams_8wekyb3d8bbwe", "LocalCache", "Microsoft", "MSTeams", "EBWebView"},
+			// Classic Teams is an Electron app
+			{"AppData", "Roaming", "Microsoft", "Teams"},
+		},
+		SafeStorageService: "Microsoft Teams Safe Storage",
+		TokenCookies: []TokenCookie{
+			{Name: "authtoken", Domain: "teams.microsoft.com"},
+			{Name: "skypetoken_asm", Domain: "teams.microsoft.com"},
+			{Name: "SSOAUTHCOOKIE", Domain: "teams.microsoft.com"},
+		},
+	}
+
+	Discord = App{
+		Name:        "discord",
+		ProcessName: "Discord.exe",
+		UserDataDirs: [][]string{
+			{"AppData", "Roaming", "discord"},
+			{"AppData", "Roaming", "discordptb"},
+			{"AppData", "Roaming", "discordcanary"},
+		},
+		SafeStorageService:     "discord Safe Storage",
+		TokenStorageKeys:       []string{"token"},
+		EncryptedStoragePrefix: "dQw4w9WgXcQ:",
+	}
+
+	VSCode = App{
+		Name:        "vscode",
+		ProcessName: "Code.exe",
+		UserDataDirs: [][]string{
+			{"AppData", "Roaming", "Code"},
+			{"AppData", "Roaming", "Code - Insiders"},
+		},
+		SafeStorageService: "Code Safe Storage",
+	}
+
+	// Apps lists every known Electron and WebView2 application
+	Apps = []App{Slack, Teams, Discord, VSCode}
+)
+
+// DiscoveredDir is a Chromium-format user data folder found by DiscoverUserDataDirs
+type DiscoveredDir struct {
+	Path        string `json:"path"`
+	Application string `json:"application"` // path of the owning application
+}
@@ -0,0 +1,115 @@
+package browsers
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxSearchDepth bounds how deep FindUserDataDirs looks below its root
+const maxSearchDepth = 10
+
+// FindUserDataDirs returns every Chromium User Data directory (a directory holding a Local
+// State file) at or below root
+func FindUserDataDirs(root string) ([]string, error) {
+	var dirs []string
+	rootDepth := strings.Count(filepath.Clean(root), string(filepath.Separator))
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable directories in a collection are skipped, not fatal
+			if d != nil && d.IsDir() && path != root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if strings.Count(path, string(filepath.Separator))-rootDepth > maxSearchDepth {
+			return filepath.SkipDir
+		}
+
+		if fileExists(filepath.Join(path, "Local State")) {
+			dirs = append(dirs, path)
+			// Profiles never contain further User Data directories
+			return filepath.SkipDir
+		}
+		return nil
+	})
+
+	return dirs, err
+}
+
+// ListProfiles returns the profile directory names of a User Data directory: the profiles
+// listed in Local State, plus Default, "Profile N" and WebView2 "WV2Profile_*" directories
+// found on disk, and "." for an Electron app whose profile is the directory itself
+func ListProfiles(userDataDir string) []string {
+	seen := make(map[string]bool)
+	var profiles []string
+	add := func(name string) {
+		if !seen[name] && dirExists(filepath.Join(userDataDir, name)) {
+			seen[name] = true
+			profiles = append(profiles, name)
+		}
+	}
+
+	if content, err := os.ReadFile(filepath.Join(userDataDir, "Local State")); err == nil {
+		var localState struct {
+			Profile struct {
+				InfoCache map[string]json.RawMessage `json:"info_cache"`
+			} `json:"profile"`
+		}
+		if json.Unmarshal(content, &localState) == nil {
+			names := make([]string, 0, len(localState.Profile.InfoCache))
+			for name := range localState.Profile.InfoCache {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				add(name)
+			}
+		}
+	}
+
+	add("Default")
+	if entries, err := os.ReadDir(userDataDir); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() && (strings.HasPrefix(entry.Name(), "Profile ") || strings.HasPrefix(entry.Name(), "WV2Profile_")) {
+				add(entry.Name())
+			}
+		}
+	}
+
+	// Electron apps keep their single profile in the User Data directory itself
+	if fileExists(filepath.Join(userDataDir, "Network", "Cookies")) || fileExists(filepath.Join(userDataDir, "Cookies")) {
+		add(".")
+	}
+
+	return profiles
+}
+
+// Identify returns the registered browser whose User Data path userDataDir ends with
+func Identify(userDataDir string) (Browser, bool) {
+	path := strings.ToLower(filepath.ToSlash(filepath.Clean(userDataDir)))
+	for _, browser := range Registry {
+		suffix := strings.ToLower(strings.Join(browser.UserDataDir, "/"))
+		if strings.HasSuffix(path, "/"+suffix) || path == suffix {
+			return browser, true
+		}
+	}
+	return Browser{}, false
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
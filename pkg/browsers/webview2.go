@@ -0,0 +1,59 @@
+package browsers
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// DiscoverUserDataDirs returns every Chromium-format user data folder at or below root that
+// holds cookies: a Local State file alongside Network\Cookies, in the folder itself (Electron)
+// or in one of its profiles (browsers, WebView2's EBWebView). Each is labelled with the
+// application that owns it.
+func DiscoverUserDataDirs(root string) ([]DiscoveredDir, error) {
+	userDataDirs, err := FindUserDataDirs(root)
+
+	var dirs []DiscoveredDir
+	for _, userDataDir := range userDataDirs {
+		if !hasNetworkCookies(userDataDir) {
+			continue
+		}
+		dirs = append(dirs, DiscoveredDir{
+			Path:        userDataDir,
+			Application: ApplicationPath(userDataDir),
+		})
+	}
+
+	return dirs, err
+}
+
+// ApplicationPath returns the path of the application owning a user data folder. WebView2
+// keeps it in <app>.exe.WebView2\EBWebView next to the executable's data, giving <app>.exe;
+// hosts naming the folder themselves (new Teams) own the folder above EBWebView, and
+// browsers the folder above User Data.
+func ApplicationPath(userDataDir string) string {
+	userDataDir = filepath.Clean(userDataDir)
+	parent := filepath.Dir(userDataDir)
+
+	switch {
+	case strings.EqualFold(filepath.Base(userDataDir), webView2DataDir):
+		if name := filepath.Base(parent); len(name) > len(webView2Suffix) && strings.EqualFold(name[len(name)-len(webView2Suffix):], webView2Suffix) {
+			return filepath.Join(filepath.Dir(parent), name[:len(name)-len(webView2Suffix)])
+		}
+		return parent
+	case strings.EqualFold(filepath.Base(userDataDir), "User Data"):
+		return parent
+	default:
+		return userDataDir
+	}
+}
+
+// hasNetworkCookies reports whether a user data folder or one of its profiles has a
+// Network\Cookies database
+func hasNetworkCookies(userDataDir string) bool {
+	for _, profile := range ListProfiles(userDataDir) {
+		if fileExists(filepath.Join(userDataDir, profile, "Network", "Cookies")) {
+			return true
+		}
+	}
+	return false
+}
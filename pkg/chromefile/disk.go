@@ -7,8 +7,19 @@ import (
 	"syscall"
 )
 
-// getFileFromDisk attempts to read a file directly from the filesystem
+// getFileFromDisk attempts to read a file directly from the filesystem, trying the legacy location second
 func getFileFromDisk(filePath FilePath) ([]byte, string, error) {
+	data, path, err := readUserDataFile(filePath.RelativePath)
+	if err != nil && filePath.LegacyRelativePath != "" {
+		if legacyData, legacyPath, legacyErr := readUserDataFile(filePath.LegacyRelativePath); legacyErr == nil {
+			return legacyData, legacyPath, nil
+		}
+	}
+	return data, path, err
+}
+
+// readUserDataFile reads a file relative to the Chrome User Data directory
+func readUserDataFile(relativePath string) ([]byte, string, error) {
 	// Get user's Chrome path
 	localAppData := os.Getenv("LOCALAPPDATA")
 	if localAppData == "" {
@@ -18,7 +29,7 @@ func getFileFromDisk(filePath FilePath) ([]byte, string, error) {
 
 	// Build full path
 	chromeUserData := filepath.Join(localAppData, "Google", "Chrome", "User Data")
-	fullPath := filepath.Join(chromeUserData, filePath.RelativePath)
+	fullPath := filepath.Join(chromeUserData, relativePath)
 	//fmt.Printf("DEBUG: Attempting to read file: %s\n", fullPath)
 
 	// Check if file exists first
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package chromefile
+
+import "fmt"
+
+// getFileFromProcess needs Windows handle duplication; files are only read from disk elsewhere
+func getFileFromProcess(pid uint32, filePath FilePath) ([]byte, string, error) {
+	return nil, "", fmt.Errorf("reading files through process handles is only supported on Windows")
+}
@@ -65,7 +65,8 @@ func getFileFromProcess(pid uint32, filePath FilePath) ([]byte, string, error) {
 		pathCount++
 		//fmt.Printf("[DEBUG] Got handle path: %s\n", name)
 
-		if !isTargetFile(name, filePath.RelativePath) {
+		if !isTargetFile(name, filePath.RelativePath) &&
+			(filePath.LegacyRelativePath == "" || !isTargetFile(name, filePath.LegacyRelativePath)) {
 			windows.CloseHandle(dupHandle)
 			continue
 		}
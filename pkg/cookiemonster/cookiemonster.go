@@ -3,58 +3,192 @@ package cookiemonster
 import (
 	"flag"
 	"fmt"
+	"go-cookie-monster/pkg/browsers"
+	"go-cookie-monster/pkg/decrypt"
+	"log"
 	"os"
 )
 
 func ModeExecute() {
 	if len(os.Args) > 1 {
 		var (
-			//browserName        string
+			browserName        string
 			localStateFilePath string
 			outputDir          string
 			databasePath       string
 			key                string
+			profileDir         string
+			userDataDir        string
+			triageRoot         string
+			credsFile          string
+			extensionIDs       string
+			format             string
+			platform           string
+			keyringPassword    string
+			primaryPassword    string
+			keychainOpts       KeychainOptions
+			dpapiOpts          DPAPIOptions
 		)
 		mode := os.Args[1]
 
 		// Define flags that can be used for any mode
 		//pid := flag.Int("pid", 0, "process ID to analyze (used in 'files' and 'all' modes)")
-		//flag.StringVar(&browserName, "browser", "chrome", "browser name")
+		flag.StringVar(&browserName, "browser", browsers.Chrome.Name, "browser to target: chrome, edge, brave or chromium (used in 'keys', 'files' and 'all' modes, and picks the Safe Storage password read with -keychain)")
 		flag.StringVar(&localStateFilePath, "statefile", "", "path to the Local State file (used in 'keys' mode)")
-		flag.StringVar(&outputDir, "outputdir", "", "output directory for files (used in 'files' mode)")
-		flag.StringVar(&key, "key", "", "decryption key (required in 'cookies' mode)")
-		flag.StringVar(&databasePath, "dbpath", "", "path to the database (required in 'cookies' mode)")
+		flag.StringVar(&outputDir, "outputdir", "", "output directory for files (used in 'files', 'decrypt-profile', 'triage', 'apps' and 'discover' modes)")
+		flag.StringVar(&key, "key", "", "decryption key (required in 'cookies', 'logindata' and 'webdata' modes on windows); in 'decrypt-profile' mode a comma-separated list of hex keys or a file holding them")
+		flag.StringVar(&platform, "platform", PlatformWindows, "platform the databases come from: windows, linux or mac (used in 'cookies', 'logindata' and 'webdata' modes)")
+		flag.StringVar(&keyringPassword, "keyring", "", "Linux Secret Service/KWallet password for v11 values (v10 values use \"peanuts\"), or the macOS \"Chrome Safe Storage\" password (used with -platform linux or mac)")
+		flag.StringVar(&keychainOpts.Path, "keychain", "", "path to a macOS login.keychain-db (required in 'keychain' mode, supplies the Safe Storage password with -platform mac)")
+		flag.StringVar(&keychainOpts.Password, "keychainpassword", "", "user login password to unlock -keychain with")
+		flag.StringVar(&keychainOpts.MasterKey, "keychainkey", "", "hex keychain master key to unlock -keychain with, instead of the password")
+		flag.StringVar(&databasePath, "dbpath", "", "path to the database (required in 'cookies', 'logindata' and 'webdata' modes); in 'safari' mode a Cookies.binarycookies file")
+		flag.StringVar(&dpapiOpts.MasterKeys, "masterkeys", "", "comma-separated {GUID}:hex DPAPI masterkeys (or their SHA1) for offline decryption (used in 'keys', 'all', 'decrypt-profile', 'triage', 'apps' and 'discover' modes)")
+		flag.StringVar(&dpapiOpts.ProtectDir, "protectdir", "", "path to a Protect\\<SID> directory of masterkey files to decrypt offline (used in 'keys', 'all', 'apps' and 'discover' modes)")
+		flag.StringVar(&dpapiOpts.SID, "sid", "", "user SID for masterkey decryption, defaults to the -protectdir directory name (used in 'keys' and 'all' modes)")
+		flag.StringVar(&dpapiOpts.Password, "password", "", "user password for masterkey decryption (used in 'keys' and 'all' modes)")
+		flag.StringVar(&dpapiOpts.NTHash, "nthash", "", "hex NT hash of the user password for masterkey decryption (used in 'keys' and 'all' modes)")
+		flag.StringVar(&dpapiOpts.SHA1, "sha1", "", "hex SHA1 of the user password for masterkey decryption (used in 'keys' and 'all' modes)")
+		flag.StringVar(&dpapiOpts.BackupKey, "backupkey", "", "path to the domain DPAPI backup key (PVK or PEM) to decrypt -protectdir masterkeys with (used in 'keys', 'all', 'decrypt-profile' and 'triage' modes)")
+		flag.StringVar(&dpapiOpts.SystemMasterKeys, "systemmasterkeys", "", "comma-separated {GUID}:hex SYSTEM DPAPI masterkeys (or their SHA1) for offline app-bound key decryption (used in 'keys', 'all', 'decrypt-profile', 'triage' and 'discover' modes)")
+		flag.StringVar(&dpapiOpts.SystemKey, "systemkey", "", "hex DPAPI_SYSTEM LSA secret for offline app-bound key decryption (used in 'keys', 'all', 'decrypt-profile', 'triage' and 'discover' modes)")
+		flag.StringVar(&dpapiOpts.SystemProtectDir, "systemprotectdir", "", "path to the Protect\\S-1-5-18 directory of SYSTEM masterkey files (used with -systemkey)")
+		flag.StringVar(&format, "format", "json", "cookie output format: json, cdp, playwright or netscape (used in 'cookies', 'firefox', 'safari' and 'all' modes)")
+		flag.StringVar(&triageRoot, "triage", "", "path to a KAPE-style collection holding Users\\<user>\\AppData (required in 'triage' mode)")
+		flag.StringVar(&credsFile, "creds", "", "file of <SID or username>:<password|nthash|sha1>:<value> lines for masterkey decryption (used in 'triage' mode)")
+		flag.StringVar(&userDataDir, "userdata", "", "path to a copied User Data directory, or a tree holding several (required in 'decrypt-profile' mode)")
+		flag.StringVar(&primaryPassword, "primarypassword", "", "Firefox primary password protecting key4.db, if one is set (used in 'firefox' mode)")
+		flag.StringVar(&profileDir, "profiledir", "", "path to the browser profile directory (used in 'storage' and 'extensions' modes); in 'firefox' mode a Firefox profile or the Firefox directory holding profiles.ini; in 'safari' mode a macOS home directory; in 'apps' and 'discover' modes a user profile directory")
+		flag.StringVar(&extensionIDs, "extensionids", "", "comma-separated extension IDs to limit extraction to (used in 'extensions' mode)")
 
 		// Parse all flags starting from the second argument
 		flag.CommandLine.Parse(os.Args[2:])
 
+		browser, ok := browsers.Lookup(browserName)
+		if !ok {
+			log.Fatalf("unknown browser: %s", browserName)
+		}
+
+		// On macOS the Safe Storage password can come from the user's keychain. A User Data tree
+		// may hold several browsers, so decrypt-profile tries every Safe Storage password.
+		var safeStorageKeys []decrypt.Key
+		if platform == PlatformMac && keyringPassword == "" && keychainOpts.Path != "" && mode != "keychain" {
+			if mode == "decrypt-profile" {
+				safeStorageKeys = safeStorageKeysFromKeychain(keychainOpts)
+			} else {
+				keyringPassword = safeStorageFromKeychain(keychainOpts, browser)
+			}
+		}
+
 		switch mode {
 		case "keys":
-			ProcessKeysMode(localStateFilePath)
+			ProcessKeysMode(localStateFilePath, browser, dpapiOpts)
 		case "files":
-			ProcessFileMode("chrome.exe", outputDir, true)
+			ProcessFileMode(browser.ProcessName, outputDir, true)
 		case "cookies":
-			ProcessCookiesMode(key, databasePath, nil)
+			ProcessCookiesMode(buildKey(key, platform, keyringPassword), databasePath, nil, format)
 		case "logindata":
-			fmt.Println("Login Data")
+			ProcessLoginDataMode(buildKey(key, platform, keyringPassword), databasePath, nil)
+		case "webdata":
+			ProcessWebDataMode(buildKey(key, platform, keyringPassword), databasePath, nil)
+		case "decrypt-profile":
+			profileKeys := parseKeyList(key)
+			if platformKey := buildKey("", platform, keyringPassword); !platformKey.IsZero() {
+				profileKeys = append(profileKeys, platformKey)
+			}
+			profileKeys = append(profileKeys, safeStorageKeys...)
+			ProcessDecryptProfileMode(userDataDir, profileKeys, dpapiOpts, outputDir)
+		case "triage":
+			ProcessTriageMode(triageRoot, credsFile, dpapiOpts, outputDir)
+		case "keychain":
+			ProcessKeychainMode(keychainOpts)
+		case "firefox":
+			ProcessFirefoxMode(profileDir, format, primaryPassword)
+		case "discover":
+			ProcessDiscoverMode(profileDir, dpapiOpts, outputDir)
+		case "apps":
+			ProcessAppsMode(profileDir, dpapiOpts, outputDir)
+		case "safari":
+			ProcessSafariMode(databasePath, profileDir, format)
+		case "storage":
+			ProcessStorageMode(profileDir)
+		case "extensions":
+			ProcessExtensionsMode(profileDir, extensionIDs)
 		case "all":
 			fmt.Println("All")
 
-			ExecuteAllModes(localStateFilePath, "chrome.exe", outputDir)
+			ExecuteAllModes(localStateFilePath, browserName, outputDir, format, dpapiOpts)
 		default:
 			fmt.Println("Help")
-			fmt.Println("Usage: go-cookie-monster [all|keys|files|cookies|logindata]")
+			fmt.Println("Usage: go-cookie-monster [all|keys|files|cookies|logindata|webdata|decrypt-profile|triage|keychain|apps|discover|firefox|safari|storage|extensions]")
 			os.Exit(1)
 		}
 	} else {
-		ExecuteAllModes("", "chrome.exe", "")
+		ExecuteAllModes("", browsers.Chrome.Name, "", decrypt.FormatJSON, DPAPIOptions{})
 	}
 }
 
-func ExecuteAllModes(localStateFilePath, browserName, outputDir string) {
-	key := ProcessKeysMode(localStateFilePath)
+func ExecuteAllModes(localStateFilePath, browserName, outputDir, format string, dpapiOpts DPAPIOptions) {
+	result, err := ExecuteAllModesWith(NewDependencies(dpapiOpts), localStateFilePath, browserName, outputDir)
+	if err != nil {
+		log.Printf("[-] %v", err)
+		return
+	}
+	for _, err := range result.Errors {
+		log.Printf("[-] %v", err)
+	}
+
+	if err := printCookies(result.Cookies, format); err != nil {
+		log.Printf("[-] %v", err)
+	}
+	if len(result.Logins) > 0 {
+		if err := printLogins(result.Logins); err != nil {
+			log.Printf("[-] %v", err)
+		}
+	}
+}
 
-	browserFiles := ProcessFileMode(browserName, outputDir, false)
+// ExecuteAllModesWith recovers the keys, acquires the databases and decrypts them through deps.
+// Cookies and logins that fail to decrypt are listed in the result's Errors; an error is returned
+// when the browser is unknown, no key was recovered or the Cookies database could not be read.
+func ExecuteAllModesWith(deps Dependencies, localStateFilePath, browserName, outputDir string) (*AllModesResult, error) {
+	browser, ok := browsers.Lookup(browserName)
+	if !ok {
+		return nil, fmt.Errorf("unknown browser: %s", browserName)
+	}
+
+	key, err := recoverKeys(deps, localStateFilePath, browser)
+	if err != nil {
+		return nil, err
+	}
+
+	browserFiles, err := acquireFiles(deps, browser.ProcessName, outputDir, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(browserFiles.Cookies.Data) == 0 {
+		return nil, fmt.Errorf("error reading Cookies: %s", browserFiles.Cookies.Error)
+	}
+
+	result := &AllModesResult{Key: key}
+
+	fmt.Printf("\n[*] Attempting to decrypt cookies...\n")
+	cookies, rowErrs, err := decryptCookies(key, "", browserFiles.Cookies.Data)
+	if err != nil {
+		return nil, fmt.Errorf("error extracting cookies: %v", err)
+	}
+	result.Cookies = cookies
+	result.Errors = append(result.Errors, rowErrs...)
+
+	if len(browserFiles.LoginData.Data) > 0 {
+		fmt.Printf("\n[*] Attempting to decrypt login data...\n")
+		logins, rowErrs, err := decryptLogins(key, "", browserFiles.LoginData.Data)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("error querying logins: %v", err))
+		}
+		result.Logins = logins
+		result.Errors = append(result.Errors, rowErrs...)
+	}
 
-	ProcessCookiesMode(key, "", browserFiles.Cookies.Data)
+	return result, nil
 }
@@ -0,0 +1,133 @@
+package cookiemonster_test
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"testing"
+
+	"go-cookie-monster/pkg/cookiemonster"
+	"go-cookie-monster/pkg/fakes"
+	"go-cookie-monster/pkg/fixture"
+	"go-cookie-monster/pkg/processes"
+)
+
+// escapeKey converts a hex key to the "\xHH" form key providers return
+func escapeKey(key string) string {
+	return regexp.MustCompile(`..`).ReplaceAllString(key, `\x$0`)
+}
+
+func TestExecuteAllModesWith(t *testing.T) {
+	f, want := generateWindows(t, t.TempDir(), 5)
+	localStatePath := filepath.Join(f.UserDataDir, "Local State")
+	localState, err := os.ReadFile(localStatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookiesDB, err := os.ReadFile(filepath.Join(f.UserDataDir, "Default", "Network", "Cookies"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	loginDB, err := os.ReadFile(filepath.Join(f.UserDataDir, "Default", "Login Data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v20 int
+	for _, c := range want.Cookies {
+		if c.Scheme == fixture.SchemeV20 {
+			v20++
+		}
+	}
+
+	running := []processes.Process{{Name: "chrome.exe", ID: 10}, {Name: "msedge.exe", ID: 20}}
+
+	tests := []struct {
+		name       string
+		browser    string
+		keys       *fakes.Keys
+		localState string
+		wantErr    bool
+		wantPIDs   []uint32
+		rowErrors  int
+	}{
+		{
+			name:       "master and app-bound keys",
+			browser:    "chrome",
+			keys:       &fakes.Keys{DefaultMasterKey: escapeKey(f.OSCryptKey), DefaultAppBoundKey: escapeKey(f.AppBoundKey)},
+			localState: localStatePath,
+			wantPIDs:   []uint32{10},
+		},
+		{
+			name:       "master key only",
+			browser:    "chrome",
+			keys:       &fakes.Keys{DefaultMasterKey: escapeKey(f.OSCryptKey)},
+			localState: localStatePath,
+			wantPIDs:   []uint32{10},
+			rowErrors:  v20,
+		},
+		{
+			name:       "browser resolved by name",
+			browser:    "edge",
+			keys:       &fakes.Keys{DefaultMasterKey: escapeKey(f.OSCryptKey), DefaultAppBoundKey: escapeKey(f.AppBoundKey)},
+			localState: localStatePath,
+			wantPIDs:   []uint32{20},
+		},
+		{
+			name:       "unknown browser",
+			browser:    "netscape",
+			keys:       &fakes.Keys{DefaultMasterKey: escapeKey(f.OSCryptKey)},
+			localState: localStatePath,
+			wantErr:    true,
+		},
+		{
+			name:       "missing Local State",
+			browser:    "chrome",
+			keys:       &fakes.Keys{DefaultMasterKey: escapeKey(f.OSCryptKey)},
+			localState: filepath.Join(f.UserDataDir, "missing"),
+			wantErr:    true,
+		},
+		{
+			name:       "no keys",
+			browser:    "chrome",
+			keys:       &fakes.Keys{},
+			localState: localStatePath,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := &fakes.Files{
+				LocalStates: map[string][]byte{localStatePath: localState},
+				Cookies:     cookiesDB,
+				LoginData:   loginDB,
+			}
+			deps := cookiemonster.Dependencies{Keys: tt.keys, Files: files, Processes: &fakes.Processes{Running: running}}
+
+			result, err := cookiemonster.ExecuteAllModesWith(deps, tt.localState, tt.browser, "")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExecuteAllModesWith: %v", err)
+			}
+
+			if !slices.Equal(files.PIDs, tt.wantPIDs) {
+				t.Errorf("got PIDs %v, want %v", files.PIDs, tt.wantPIDs)
+			}
+			if len(result.Errors) != tt.rowErrors {
+				t.Errorf("got %d errors, want %d: %v", len(result.Errors), tt.rowErrors, result.Errors)
+			}
+			if got, want := len(result.Cookies), len(want.Cookies)-tt.rowErrors; got != want {
+				t.Errorf("got %d cookies, want %d", got, want)
+			}
+			if got, want := len(result.Logins), len(want.Logins); got != want {
+				t.Errorf("got %d logins, want %d", got, want)
+			}
+		})
+	}
+}
@@ -4,48 +4,282 @@ import (
 	"encoding/hex"
 	"fmt"
 	"go-cookie-monster/pkg/decrypt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-func ProcessCookiesMode(key, databasePath string, databaseBytes []byte) {
-	var (
-		reader    *decrypt.DBReader
-		err       error
-		tempDbUse bool
-	)
-
+func ProcessCookiesMode(key decrypt.Key, databasePath string, databaseBytes []byte, format string) {
 	fmt.Printf("\n[*] Attempting to decrypt cookies...\n")
 
 	// ensure we have a key
-	if key == "" {
+	if key.IsZero() {
 		log.Fatalf("key is required for cookies mode")
 	}
 
-	// parse the key
-	keyBytes, err := parseKey(key)
+	// ensure we have a database path or bytes
+	if databasePath == "" && len(databaseBytes) == 0 {
+		log.Fatalf("database path is required for cookies mode")
+	}
+
+	cookies, rowErrs, err := decryptCookies(key, databasePath, databaseBytes)
 	if err != nil {
-		log.Fatalf("error parsing key: %v", err)
+		log.Fatalf("error extracting cookies: %v", err)
+	}
+	for _, err := range rowErrs {
+		log.Printf("[-] Error extracting cookie: %v", err)
+	}
+
+	if err := printCookies(cookies, format); err != nil {
+		log.Fatalf("%v", err)
+	}
+}
+
+func ProcessLoginDataMode(key decrypt.Key, databasePath string, databaseBytes []byte) {
+	fmt.Printf("\n[*] Attempting to decrypt login data...\n")
+
+	// ensure we have a key
+	if key.IsZero() {
+		log.Fatalf("key is required for logindata mode")
 	}
 
 	// ensure we have a database path or bytes
 	if databasePath == "" && len(databaseBytes) == 0 {
-		log.Fatalf("database path is required for cookies mode")
+		log.Fatalf("database path is required for logindata mode")
+	}
+
+	logins, rowErrs, err := decryptLogins(key, databasePath, databaseBytes)
+	if err != nil {
+		log.Fatalf("error querying logins: %v", err)
+	}
+	for _, err := range rowErrs {
+		log.Printf("[-] Error extracting login: %v", err)
+	}
+
+	if err := printLogins(logins); err != nil {
+		log.Fatalf("%v", err)
+	}
+}
+
+func ProcessWebDataMode(key decrypt.Key, databasePath string, databaseBytes []byte) {
+	fmt.Printf("\n[*] Attempting to decrypt web data...\n")
+
+	// ensure we have a key
+	if key.IsZero() {
+		log.Fatalf("key is required for webdata mode")
+	}
+
+	// ensure we have a database path or bytes
+	if databasePath == "" && len(databaseBytes) == 0 {
+		log.Fatalf("database path is required for webdata mode")
+	}
+
+	reader, cleanup, err := openDatabase(databasePath, databaseBytes)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	defer cleanup()
+
+	formatter, rowErrs := extractWebData(reader, key)
+	printSchema(reader.Schema("autofill"))
+	for _, err := range rowErrs {
+		log.Printf("[-] %v", err)
+	}
+
+	output, err := formatter.Format()
+	if err != nil {
+		log.Fatalf("error formatting web data: %v", err)
+	}
+
+	fmt.Printf("[+] Decrypted %d autofill entries, %d credit cards, %d tokens:\n",
+		len(formatter.Autofill), len(formatter.CreditCards), len(formatter.Tokens))
+	fmt.Println(output)
+}
+
+// decryptCookies opens a Cookies database from a path or bytes and decrypts its cookies
+func decryptCookies(key decrypt.Key, databasePath string, databaseBytes []byte) ([]decrypt.Cookie, []error, error) {
+	reader, cleanup, err := openDatabase(databasePath, databaseBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cleanup()
+
+	cookies, rowErrs, err := extractCookies(reader, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	printSchema(reader.Schema("cookies"))
+
+	return cookies, rowErrs, nil
+}
+
+// decryptLogins opens a Login Data database from a path or bytes and decrypts its logins
+func decryptLogins(key decrypt.Key, databasePath string, databaseBytes []byte) ([]decrypt.Login, []error, error) {
+	reader, cleanup, err := openDatabase(databasePath, databaseBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cleanup()
+
+	logins, rowErrs, err := extractLogins(reader, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	printSchema(reader.Schema("logins"))
+
+	return logins, rowErrs, nil
+}
+
+// printCookies prints the cookies in the given output format
+func printCookies(cookies []decrypt.Cookie, format string) error {
+	formatter, err := decrypt.NewCookieFormatter(format, cookies)
+	if err != nil {
+		return fmt.Errorf("error creating cookie formatter: %v", err)
+	}
+	output, err := formatter.Format()
+	if err != nil {
+		return fmt.Errorf("error formatting cookies: %v", err)
+	}
+
+	fmt.Printf("[+] Decrypted %d cookies:\n", len(cookies))
+	fmt.Println(output)
+	return nil
+}
+
+// printLogins prints the logins as JSON
+func printLogins(logins []decrypt.Login) error {
+	formatter := &decrypt.LoginJSONFormatter{Logins: logins}
+	output, err := formatter.Format()
+	if err != nil {
+		return fmt.Errorf("error formatting logins: %v", err)
+	}
+
+	fmt.Printf("[+] Decrypted %d logins:\n", len(logins))
+	fmt.Println(output)
+	return nil
+}
+
+// extractCookies decrypts every cookie in the database and drops duplicates, keeping
+// partitioned cookies apart from unpartitioned ones. Cookies that fail to decrypt are skipped
+// and reported in the returned error slice.
+func extractCookies(reader *decrypt.DBReader, key decrypt.Key) ([]decrypt.Cookie, []error, error) {
+	rows, err := reader.QueryCookies()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error querying cookies: %v", err)
+	}
+	defer rows.Close()
+
+	extractor := &decrypt.CookieExtractor{Rows: rows, HostHash: reader.Schema("cookies").HostHash}
+	var cookies []decrypt.Cookie
+	var rowErrs []error
+	for rows.Next() {
+		cookie, err := extractor.ExtractCookie(key)
+		if err != nil {
+			rowErrs = append(rowErrs, err)
+			continue
+		}
+		cookies = append(cookies, *cookie)
+	}
+
+	return decrypt.DedupCookies(cookies), rowErrs, nil
+}
+
+// extractLogins decrypts every login in the database. Logins that fail to decrypt are skipped
+// and reported in the returned error slice.
+func extractLogins(reader *decrypt.DBReader, key decrypt.Key) ([]decrypt.Login, []error, error) {
+	rows, err := reader.QueryLogins()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	extractor := &decrypt.LoginExtractor{Rows: rows}
+	var logins []decrypt.Login
+	var rowErrs []error
+	for rows.Next() {
+		login, err := extractor.ExtractLogin(key)
+		if err != nil {
+			rowErrs = append(rowErrs, err)
+			continue
+		}
+		logins = append(logins, *login)
+	}
+
+	return logins, rowErrs, nil
+}
+
+// extractWebData reads autofill entries and decrypts credit cards and tokens. Tables that cannot
+// be queried and rows that fail to decrypt are reported in the returned error slice.
+func extractWebData(reader *decrypt.DBReader, key decrypt.Key) (*decrypt.WebDataJSONFormatter, []error) {
+	formatter := &decrypt.WebDataJSONFormatter{}
+	var errs []error
+
+	// autofill entries are not encrypted
+	if rows, err := reader.QueryAutofill(); err != nil {
+		errs = append(errs, fmt.Errorf("error querying autofill: %v", err))
+	} else {
+		extractor := &decrypt.WebDataExtractor{Rows: rows}
+		for rows.Next() {
+			if entry, err := extractor.ExtractAutofill(); err == nil {
+				formatter.Autofill = append(formatter.Autofill, *entry)
+			}
+		}
+		rows.Close()
 	}
 
+	if rows, err := reader.QueryCreditCards(); err != nil {
+		errs = append(errs, fmt.Errorf("error querying credit cards: %v", err))
+	} else {
+		extractor := &decrypt.WebDataExtractor{Rows: rows}
+		for rows.Next() {
+			card, err := extractor.ExtractCreditCard(key)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("error extracting credit card: %v", err))
+				continue
+			}
+			formatter.CreditCards = append(formatter.CreditCards, *card)
+		}
+		rows.Close()
+	}
+
+	if rows, err := reader.QueryTokens(); err != nil {
+		errs = append(errs, fmt.Errorf("error querying tokens: %v", err))
+	} else {
+		extractor := &decrypt.WebDataExtractor{Rows: rows}
+		for rows.Next() {
+			token, err := extractor.ExtractToken(key)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("error extracting token: %v", err))
+				continue
+			}
+			formatter.Tokens = append(formatter.Tokens, *token)
+		}
+		rows.Close()
+	}
+
+	return formatter, errs
+}
+
+// openDatabase opens a database from a path, or from bytes written to a temp file.
+// The returned cleanup function closes the reader and removes any temp file.
+func openDatabase(databasePath string, databaseBytes []byte) (*decrypt.DBReader, func(), error) {
+	var tempDbUse bool
+
 	// no database path but bytes, write the bytes to a tmep file
 	if databasePath == "" && databaseBytes != nil {
 		tmpFile, err := os.CreateTemp("", "")
 		if err != nil {
-			log.Fatalf("error creating temp file: %v", err)
+			return nil, nil, fmt.Errorf("error creating temp file: %v", err)
 		}
-		//defer tmpFile.Close()
 
 		if _, err := tmpFile.Write(databaseBytes); err != nil {
-			log.Fatalf("error writing to temp file: %v", err)
+			tmpFile.Close()
+			os.Remove(tmpFile.Name())
+			return nil, nil, fmt.Errorf("error writing to temp file: %v", err)
 		}
 		tmpFile.Close()
 		fmt.Println("[*] Wrote database bytes to temp file:", tmpFile.Name())
@@ -55,52 +289,119 @@ func ProcessCookiesMode(key, databasePath string, databaseBytes []byte) {
 	}
 
 	// read in the database file
-	reader, err = decrypt.NewDBReader(databasePath)
+	reader, err := decrypt.NewDBReader(databasePath)
 	if err != nil {
-		log.Fatalf("error opening database: %v", err)
+		if tempDbUse {
+			os.Remove(databasePath)
+		}
+		return nil, nil, fmt.Errorf("error opening database: %v", err)
 	}
-	//defer reader.Close()
 
-	// query the cookies
-	rows, err := reader.QueryCookies()
+	return reader, func() {
+		reader.Close()
+
+		// if we created a temp file, remove it
+		if tempDbUse {
+			if err := os.Remove(databasePath); err != nil {
+				log.Printf("[warning] error removing temp database file: %v", err)
+			}
+		}
+	}, nil
+}
+
+// openDatabaseCopy opens a copy of a database so the original is never written to, not even
+// by SQLite replaying a hot journal. The database and any -wal or -journal file are copied to
+// a temporary directory; the returned cleanup closes the reader and removes the copy.
+func openDatabaseCopy(databasePath string) (*decrypt.DBReader, func(), error) {
+	tmpDir, err := os.MkdirTemp("", "")
 	if err != nil {
-		log.Fatalf("error querying cookies: %v", err)
+		return nil, nil, fmt.Errorf("error creating temp directory: %v", err)
 	}
-	//defer rows.Close()
-
-	// extract the cookies
-	extractor := &decrypt.CookieExtractor{Rows: rows}
-	var cookies []decrypt.Cookie
+	removeTmp := func() { os.RemoveAll(tmpDir) }
 
-	for rows.Next() {
-		cookie, err := extractor.ExtractCookie(keyBytes)
-		if err != nil {
-			// print the key
-			log.Printf("key: %s", key)
-			log.Fatalf("error extracting cookie: %v", err)
+	copyPath := filepath.Join(tmpDir, filepath.Base(databasePath))
+	if err := copyFile(databasePath, copyPath); err != nil {
+		removeTmp()
+		return nil, nil, err
+	}
+	for _, suffix := range []string{"-wal", "-journal"} {
+		if _, err := os.Stat(databasePath + suffix); err != nil {
+			continue
+		}
+		if err := copyFile(databasePath+suffix, copyPath+suffix); err != nil {
+			removeTmp()
+			return nil, nil, err
 		}
-		cookies = append(cookies, *cookie)
 	}
 
-	formatter := &decrypt.JSONFormatter{Cookies: cookies}
-	output, err := formatter.Format()
+	reader, err := decrypt.NewDBReader(copyPath)
 	if err != nil {
-		log.Fatalf("error formatting cookies: %v", err)
+		removeTmp()
+		return nil, nil, fmt.Errorf("error opening database: %v", err)
 	}
 
-	fmt.Printf("[+] Decrypted %d cookies:\n", len(cookies))
-	fmt.Println(output)
+	return reader, func() {
+		reader.Close()
+		removeTmp()
+	}, nil
+}
 
-	// cleanup
-	rows.Close()
-	reader.Close()
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
 
-	// if we created a temp file, remove it
-	if tempDbUse {
-		err = os.Remove(databasePath)
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// printSchema reports the schema version a table was read with
+func printSchema(schema *decrypt.SchemaInfo) {
+	if schema == nil {
+		return
+	}
+	fmt.Printf("[+] %s schema version %d (adapter: %s)\n", schema.Table, schema.Version, schema.Adapter)
+	if schema.BestEffort {
+		fmt.Printf("[!] %s schema version %d is newer than any known version, reading on a best-effort basis\n", schema.Table, schema.Version)
+	}
+	if len(schema.MissingColumns) > 0 {
+		fmt.Printf("[!] %s is missing columns, using defaults: %s\n", schema.Table, strings.Join(schema.MissingColumns, ", "))
+	}
+}
+
+// buildKey turns the command-line key options into the key for the profile's platform:
+// the "\xHH" os_crypt or app-bound key on Windows, the keyring password on Linux and the
+// Safe Storage password on macOS
+func buildKey(key, platform, keyringPassword string) decrypt.Key {
+	switch platform {
+	case PlatformWindows:
+		if key == "" {
+			return decrypt.Key{}
+		}
+		keyBytes, err := parseKey(key)
 		if err != nil {
-			log.Printf("[warning] error removing temp database file: %v", err)
+			log.Fatalf("error parsing key: %v", err)
+		}
+		return decrypt.WindowsKey(keyBytes)
+	case PlatformLinux:
+		return decrypt.LinuxKey(keyringPassword)
+	case PlatformMac:
+		if keyringPassword == "" {
+			return decrypt.Key{}
 		}
+		return decrypt.MacKey(keyringPassword)
+	default:
+		log.Fatalf("unknown platform: %s", platform)
+		return decrypt.Key{}
 	}
 }
 
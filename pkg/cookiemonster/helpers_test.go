@@ -0,0 +1,63 @@
+package cookiemonster_test
+
+import (
+	"testing"
+
+	"go-cookie-monster/pkg/cookiemonster"
+	"go-cookie-monster/pkg/decrypt"
+	"go-cookie-monster/pkg/fixture"
+)
+
+// generateWindows writes a Windows fixture below dir and returns it with its Default profile,
+// whose cookies mix v20, v10 and plaintext values
+func generateWindows(t *testing.T, dir string, seed int64) (*fixture.Fixture, fixture.Profile) {
+	t.Helper()
+	f, err := fixture.Generate(dir, fixture.Options{Platform: fixture.PlatformWindows, Seed: seed})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	p, ok := f.Profile("Default")
+	if !ok {
+		t.Fatal("fixture has no Default profile")
+	}
+	return f, p
+}
+
+// findProfile returns the result for the named profile
+func findProfile(t *testing.T, results []cookiemonster.ProfileResult, name string) cookiemonster.ProfileResult {
+	t.Helper()
+	for _, result := range results {
+		if result.Profile == name {
+			return result
+		}
+	}
+	t.Fatalf("%s profile was not decrypted", name)
+	return cookiemonster.ProfileResult{}
+}
+
+// checkCookies compares decrypted cookies, by name, with the secrets written. Cookies encrypted
+// with the skip scheme must be missing; it returns how many there are.
+func checkCookies(t *testing.T, label string, cookies []decrypt.Cookie, want []fixture.Secret, skip string) int {
+	t.Helper()
+	got := make(map[string]string)
+	for _, c := range cookies {
+		got[c.Name] = c.Value
+	}
+
+	var skipped int
+	for _, w := range want {
+		value, ok := got[w.Name]
+		switch {
+		case skip != "" && w.Scheme == skip:
+			skipped++
+			if ok {
+				t.Errorf("%s %s (%s): decrypted without its key", label, w.Name, w.Scheme)
+			}
+		case !ok:
+			t.Errorf("%s %s (%s): missing", label, w.Name, w.Scheme)
+		case value != w.Plaintext:
+			t.Errorf("%s %s (%s): got %q, want %q", label, w.Name, w.Scheme, value, w.Plaintext)
+		}
+	}
+	return skipped
+}
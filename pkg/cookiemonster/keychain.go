@@ -0,0 +1,96 @@
+package cookiemonster
+
+import (
+	"encoding/hex"
+	"fmt"
+	"go-cookie-monster/pkg/browsers"
+	"go-cookie-monster/pkg/decrypt"
+	"go-cookie-monster/pkg/keychain"
+	"log"
+	"sort"
+)
+
+func ProcessKeychainMode(keychainOpts KeychainOptions) {
+	passwords := readKeychain(keychainOpts)
+
+	formatter := &keychain.JSONFormatter{Passwords: passwords}
+	output, err := formatter.Format()
+	if err != nil {
+		log.Fatalf("error formatting keychain passwords: %v", err)
+	}
+
+	fmt.Printf("[+] Decrypted %d generic passwords:\n", len(passwords))
+	fmt.Println(output)
+
+	for service := range keychain.SafeStoragePasswords(passwords) {
+		fmt.Printf("[+] Found %s password, usable with -platform mac -keychain\n", service)
+	}
+}
+
+// safeStorageFromKeychain unlocks the keychain and returns the browser's Safe Storage password
+func safeStorageFromKeychain(keychainOpts KeychainOptions, browser browsers.Browser) string {
+	passwords := readKeychain(keychainOpts)
+
+	password, err := keychain.SafeStoragePassword(passwords, browser.SafeStorageService)
+	if err != nil {
+		log.Fatalf("Error finding Safe Storage password: %v", err)
+	}
+	fmt.Printf("[+] Using %s password from keychain\n", browser.SafeStorageService)
+
+	return password
+}
+
+// safeStorageKeysFromKeychain unlocks the keychain and returns a key for every Safe Storage
+// password in it, sorted by service
+func safeStorageKeysFromKeychain(keychainOpts KeychainOptions) []decrypt.Key {
+	safeStorage := keychain.SafeStoragePasswords(readKeychain(keychainOpts))
+	if len(safeStorage) == 0 {
+		log.Fatalf("Error finding Safe Storage password: %v", keychain.ErrNoSafeStorage)
+	}
+
+	services := make([]string, 0, len(safeStorage))
+	for service := range safeStorage {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	var profileKeys []decrypt.Key
+	for _, service := range services {
+		fmt.Printf("[+] Using %s password from keychain\n", service)
+		profileKeys = append(profileKeys, decrypt.MacKey(safeStorage[service]))
+	}
+	return profileKeys
+}
+
+// readKeychain opens and unlocks the keychain and decrypts its generic passwords
+func readKeychain(keychainOpts KeychainOptions) []keychain.GenericPassword {
+	if keychainOpts.Path == "" {
+		log.Fatalf("keychain path is required")
+	}
+
+	fmt.Printf("\n[*] Attempting to unlock keychain: \"%s\"\n", keychainOpts.Path)
+	kc, err := keychain.Open(keychainOpts.Path)
+	if err != nil {
+		log.Fatalf("Error opening keychain: %v", err)
+	}
+
+	if keychainOpts.MasterKey != "" {
+		masterKey, err := hex.DecodeString(keychainOpts.MasterKey)
+		if err != nil {
+			log.Fatalf("Invalid keychain master key: %v", err)
+		}
+		err = kc.UnlockWithKey(masterKey)
+	} else {
+		err = kc.Unlock(keychainOpts.Password)
+	}
+	if err != nil {
+		log.Fatalf("Error unlocking keychain: %v", err)
+	}
+
+	passwords, err := kc.GenericPasswords()
+	if err != nil {
+		log.Fatalf("Error reading keychain passwords: %v", err)
+	}
+
+	return passwords
+}
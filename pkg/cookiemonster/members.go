@@ -1,6 +1,131 @@
 package cookiemonster
 
+import (
+	"go-cookie-monster/pkg/browsers"
+	"go-cookie-monster/pkg/chromefile"
+	"go-cookie-monster/pkg/decrypt"
+	"go-cookie-monster/pkg/dpapi"
+	"go-cookie-monster/pkg/processes"
+	"go-cookie-monster/pkg/webstorage"
+)
+
+const (
+	// Platforms a copied profile can come from
+	PlatformWindows = "windows"
+	PlatformLinux   = "linux"
+	PlatformMac     = "mac"
+)
+
 var (
 	outputCookieDbName = "cookies.db"
 	outputLoginDbName  = "logindata.db"
 )
+
+// DPAPIOptions holds the offline DPAPI inputs used instead of CryptUnprotectData
+type DPAPIOptions struct {
+	MasterKeys string // comma-separated {GUID}:hex masterkeys or their SHA1
+	ProtectDir string // directory of masterkey files to decrypt with the credentials below
+	SID        string
+	Password   string
+	NTHash     string // hex
+	SHA1       string // hex
+	BackupKey  string // path to the domain backup key (PVK or PEM)
+
+	SystemMasterKeys string // comma-separated {GUID}:hex SYSTEM masterkeys or their SHA1
+	SystemKey        string // DPAPI_SYSTEM LSA secret, for the app-bound key's SYSTEM layer
+	SystemProtectDir string // Protect\S-1-5-18 directory of SYSTEM masterkey files
+}
+
+// KeyProvider recovers the keys protecting a browser's data: the os_crypt and app-bound keys
+// from Local State (base64, as stored there) and bare DPAPI blobs. CanDecryptAppBound reports
+// whether the provider holds what the app-bound key needs, such as SYSTEM masterkeys offline.
+type KeyProvider interface {
+	MasterKey(encryptedKey string) (string, error)
+	AppBoundKey(encryptedKey string, browser browsers.Browser) (string, error)
+	CanDecryptAppBound() bool
+	Unprotect(data []byte) ([]byte, error)
+}
+
+// FileSource reads a browser's Local State and acquires its databases, through the handles of
+// the given processes where it can
+type FileSource interface {
+	ReadLocalState(path string) ([]byte, error)
+	BrowserFiles(pids []uint32) (*chromefile.ChromeFiles, error)
+}
+
+// ProcessLister finds running processes by executable name
+type ProcessLister interface {
+	FindProcess(name string) ([]processes.Process, error)
+}
+
+// Dependencies are the system access the keys, files and all modes go through. Tools and tests
+// replace them to run the pipeline against offline or in-memory inputs.
+type Dependencies struct {
+	Keys      KeyProvider
+	Files     FileSource
+	Processes ProcessLister
+}
+
+// LiveKeys recovers keys with CryptUnprotectData and the browser's elevation service
+type LiveKeys struct{}
+
+// OfflineKeys recovers keys with DPAPI masterkeys recovered offline. The app-bound key also
+// needs the SYSTEM masterkeys.
+type OfflineKeys struct {
+	UserKeys   *dpapi.MasterKeyCache
+	SystemKeys *dpapi.MasterKeyCache
+}
+
+// LiveFiles reads Local State from disk and the databases of the running browser
+type LiveFiles struct{}
+
+// LiveProcesses lists the processes running on this machine
+type LiveProcesses struct{}
+
+// KeychainOptions locate and unlock a macOS login keychain
+type KeychainOptions struct {
+	Path      string // login.keychain-db
+	Password  string // user's login password
+	MasterKey string // hex 24-byte keychain master key, instead of the password
+}
+
+// AllModesResult is what the all mode recovered from the running browser. Errors lists the
+// rows and databases that could not be decrypted.
+type AllModesResult struct {
+	Key     decrypt.Key
+	Cookies []decrypt.Cookie
+	Logins  []decrypt.Login
+	Errors  []error
+}
+
+// ProfileResult is everything decrypted from one browser profile
+type ProfileResult struct {
+	Browser     string                        `json:"browser"`
+	UserDataDir string                        `json:"userDataDir"`
+	Application string                        `json:"application,omitempty"`
+	Profile     string                        `json:"profile"`
+	Cookies     []decrypt.Cookie              `json:"cookies"`
+	Logins      []decrypt.Login               `json:"logins"`
+	WebData     *decrypt.WebDataJSONFormatter `json:"webData,omitempty"`
+	Storage     []webstorage.Record           `json:"storage,omitempty"`
+	Tokens      []AppToken                    `json:"tokens,omitempty"`
+	Errors      []string                      `json:"errors,omitempty"`
+}
+
+// AppToken is a session or API token found in an application's cookies or Local Storage
+type AppToken struct {
+	Source string `json:"source"` // "cookie" or "localStorage"
+	Name   string `json:"name"`
+	Host   string `json:"host"`
+	Value  string `json:"value"`
+}
+
+// TriageResult is everything decrypted for one user of a triage collection
+type TriageResult struct {
+	User        string          `json:"user"`
+	ProfilePath string          `json:"profilePath"`
+	SIDs        []string        `json:"sids"`
+	MasterKeys  int             `json:"masterKeys"`
+	Profiles    []ProfileResult `json:"profiles"`
+	Errors      []string        `json:"errors,omitempty"`
+}
@@ -0,0 +1,284 @@
+package cookiemonster
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go-cookie-monster/pkg/browsers"
+	"go-cookie-monster/pkg/decrypt"
+	"go-cookie-monster/pkg/dpapi"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func ProcessDecryptProfileMode(userDataRoot string, keys []decrypt.Key, dpapiOpts DPAPIOptions, outputDir string) {
+	if userDataRoot == "" {
+		log.Fatalf("user data directory is required for decrypt-profile mode")
+	}
+
+	fmt.Printf("\n[*] Searching for User Data directories in: \"%s\"\n", userDataRoot)
+	userDataDirs, err := browsers.FindUserDataDirs(userDataRoot)
+	if err != nil {
+		log.Printf("[-] Error searching %s: %v", userDataRoot, err)
+	}
+	if len(userDataDirs) == 0 {
+		log.Fatalf("no User Data directories (with a Local State file) found in %s", userDataRoot)
+	}
+	fmt.Printf("[+] Found %d User Data directories\n", len(userDataDirs))
+
+	// Offline DPAPI material lets every Local State supply its own key
+	masterKeys := loadOfflineMasterKeys(dpapiOpts)
+	var systemKeys *dpapi.MasterKeyCache
+	if masterKeys != nil {
+		systemKeys = loadSystemMasterKeys(dpapiOpts)
+	}
+	if len(keys) == 0 && masterKeys == nil {
+		log.Fatalf("a key (-key, -keyring) or offline DPAPI masterkeys are required for decrypt-profile mode")
+	}
+
+	var results []ProfileResult
+	for _, userDataDir := range userDataDirs {
+		results = append(results, DecryptUserDataDir(userDataDir, keys, masterKeys, systemKeys)...)
+	}
+
+	writeProfileResults(results, userDataRoot, outputDir)
+}
+
+// DecryptUserDataDir decrypts every profile of a User Data directory with the supplied keys and,
+// given offline masterkeys, the keys recovered from its Local State: the os_crypt key with the
+// user's masterkeys and the app-bound key when SYSTEM masterkeys are given too
+func DecryptUserDataDir(userDataDir string, keys []decrypt.Key, masterKeys, systemKeys *dpapi.MasterKeyCache) []ProfileResult {
+	var provider KeyProvider
+	if masterKeys != nil {
+		provider = newKeyProvider(masterKeys, systemKeys)
+	}
+	return decryptUserDataDir(userDataDir, keys, provider)
+}
+
+// decryptUserDataDir is DecryptUserDataDir with the Local State keys, when provider is not nil,
+// recovered and DPAPI-protected values unprotected through provider
+func decryptUserDataDir(userDataDir string, keys []decrypt.Key, provider KeyProvider) []ProfileResult {
+	browser, browserName := identifyUserDataDir(userDataDir)
+	fmt.Printf("\n[*] Processing %s User Data directory: \"%s\"\n", browserName, userDataDir)
+
+	dirKeys := append([]decrypt.Key(nil), keys...)
+	if provider != nil {
+		dirKeys = append(dirKeys, localStateKeys(userDataDir, browser, provider)...)
+		dirKeys = withDPAPI(dirKeys, provider.Unprotect)
+	}
+
+	var results []ProfileResult
+	for _, profile := range browsers.ListProfiles(userDataDir) {
+		fmt.Printf("[*] Decrypting profile \"%s\"\n", profile)
+		result := decryptProfile(filepath.Join(userDataDir, profile), dirKeys)
+		result.Browser = browserName
+		result.UserDataDir = userDataDir
+		result.Application = browsers.ApplicationPath(userDataDir)
+		result.Profile = profile
+		fmt.Printf("[+] %s/%s: %d cookies, %d logins\n", browserName, profile, len(result.Cookies), len(result.Logins))
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// identifyUserDataDir names the browser or app owning a User Data directory, falling back to
+// its owning application's file name. Unknown directories are decrypted as Chrome's.
+func identifyUserDataDir(userDataDir string) (browsers.Browser, string) {
+	if browser, ok := browsers.Identify(userDataDir); ok {
+		return browser, browser.Name
+	}
+	if app, ok := browsers.IdentifyApp(userDataDir); ok {
+		return browsers.Chrome, app.Name
+	}
+	return browsers.Chrome, filepath.Base(browsers.ApplicationPath(userDataDir))
+}
+
+// localStateKeys decrypts the Local State master key, and the app-bound key when SYSTEM
+// masterkeys are available, with offline DPAPI masterkeys. Both go into one key, as v10 and v20
+// values sit side by side in the databases of Chrome 127 and later.
+func localStateKeys(userDataDir string, browser browsers.Browser, provider KeyProvider) []decrypt.Key {
+	content, err := os.ReadFile(filepath.Join(userDataDir, "Local State"))
+	if err != nil {
+		log.Printf("[-] Error reading Local State: %v", err)
+		return nil
+	}
+
+	var found decrypt.Key
+	if masterKey, err := fetchMasterKey(content, provider); err != nil {
+		log.Printf("[-] Error decrypting master key: %v", err)
+	} else if keyBytes, err := parseKey(masterKey); err == nil {
+		fmt.Printf("[+] Master Key: %s\n", masterKey)
+		found.GCM = keyBytes
+	}
+
+	if provider.CanDecryptAppBound() {
+		if appBoundKey, err := fetchAppBoundKey(content, browser, provider); err != nil {
+			log.Printf("[-] Error decrypting app-bound key: %v", err)
+		} else if keyBytes, err := parseKey(appBoundKey); err == nil {
+			fmt.Printf("[+] App-Bound Key: %s\n", appBoundKey)
+			found.AppBound = keyBytes
+		}
+	}
+
+	if found.IsZero() {
+		return nil
+	}
+	return []decrypt.Key{found}
+}
+
+// decryptProfile decrypts a profile's Cookies, Login Data and Web Data. Each value is decrypted
+// with the first key that can, so databases mixing v10 and v20 values decrypt fully; values no
+// key decrypts are reported as errors and the rest are kept.
+func decryptProfile(profileDir string, keys []decrypt.Key) ProfileResult {
+	var result ProfileResult
+	addErr := func(format string, args ...interface{}) {
+		result.Errors = append(result.Errors, fmt.Sprintf(format, args...))
+	}
+	key := decrypt.AnyOf(keys...)
+
+	if path := firstExisting(filepath.Join(profileDir, "Network", "Cookies"), filepath.Join(profileDir, "Cookies")); path != "" {
+		withDatabase(path, addErr, func(reader *decrypt.DBReader) {
+			cookies, rowErrs, err := extractCookies(reader, key)
+			if err != nil {
+				addErr("cookies: %v", err)
+				return
+			}
+			result.Cookies = cookies
+			for _, err := range rowErrs {
+				addErr("cookies: %v", err)
+			}
+		})
+	}
+
+	if path := firstExisting(filepath.Join(profileDir, "Login Data")); path != "" {
+		withDatabase(path, addErr, func(reader *decrypt.DBReader) {
+			logins, rowErrs, err := extractLogins(reader, key)
+			if err != nil {
+				addErr("logins: %v", err)
+				return
+			}
+			result.Logins = logins
+			for _, err := range rowErrs {
+				addErr("logins: %v", err)
+			}
+		})
+	}
+
+	if path := firstExisting(filepath.Join(profileDir, "Web Data")); path != "" {
+		withDatabase(path, addErr, func(reader *decrypt.DBReader) {
+			webData, errs := extractWebData(reader, key)
+			result.WebData = webData
+			for _, err := range errs {
+				addErr("web data: %v", err)
+			}
+		})
+	}
+
+	return result
+}
+
+// withDatabase opens a copy of an evidence database and calls fn with it, reporting open
+// errors through addErr
+func withDatabase(path string, addErr func(string, ...interface{}), fn func(*decrypt.DBReader)) {
+	reader, cleanup, err := openDatabaseCopy(path)
+	if err != nil {
+		addErr("%s: %v", path, err)
+		return
+	}
+	defer cleanup()
+	fn(reader)
+}
+
+// writeProfileResults prints the results, or writes one JSON file per profile to outputDir
+func writeProfileResults(results []ProfileResult, userDataRoot, outputDir string) {
+	if outputDir == "" {
+		jsonData, err := json.MarshalIndent(results, "", "    ")
+		if err != nil {
+			log.Fatalf("error formatting profile results: %v", err)
+		}
+		fmt.Printf("\n[+] Decrypted %d profiles:\n", len(results))
+		fmt.Println(string(jsonData))
+		return
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		log.Fatalf("error creating output directory: %v", err)
+	}
+	for _, result := range results {
+		name := result.Browser
+		if rel, err := filepath.Rel(userDataRoot, result.UserDataDir); err == nil && rel != "." {
+			name = rel
+		}
+		if result.Profile != "." {
+			name += "_" + result.Profile
+		}
+		name = strings.NewReplacer(string(filepath.Separator), "_", "/", "_", " ", "_").Replace(name) + ".json"
+
+		jsonData, err := json.MarshalIndent(result, "", "    ")
+		if err != nil {
+			log.Printf("[-] Error formatting %s: %v", name, err)
+			continue
+		}
+		path := filepath.Join(outputDir, name)
+		if err := os.WriteFile(path, jsonData, 0644); err != nil {
+			log.Printf("[-] Error writing %s: %v", path, err)
+			continue
+		}
+		fmt.Printf("[+] Wrote %s\n", path)
+	}
+}
+
+// parseKeyList parses -key for decrypt-profile mode: one or more keys separated by commas or
+// whitespace, given directly or in a file, each as "\xHH" bytes or plain hex
+func parseKeyList(keyArg string) []decrypt.Key {
+	if keyArg == "" {
+		return nil
+	}
+	if content, err := os.ReadFile(keyArg); err == nil {
+		keyArg = string(content)
+	}
+
+	var keys []decrypt.Key
+	for _, field := range strings.FieldsFunc(keyArg, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\r' || r == '\n'
+	}) {
+		if decoded, err := hex.DecodeString(field); err == nil && len(decoded) == 32 {
+			keys = append(keys, decrypt.WindowsKey(decoded))
+			continue
+		}
+		keyBytes, err := parseKey(field)
+		if err != nil {
+			log.Fatalf("error parsing key %s: %v", field, err)
+		}
+		keys = append(keys, decrypt.WindowsKey(keyBytes))
+	}
+	return keys
+}
+
+// withDPAPI lets every key decrypt bare DPAPI blob values (old Chromium, Electron apps without a
+// Local State) with unprotect, adding a DPAPI-only key when there are no others
+func withDPAPI(keys []decrypt.Key, unprotect func([]byte) ([]byte, error)) []decrypt.Key {
+	if len(keys) == 0 {
+		return []decrypt.Key{{DPAPI: unprotect}}
+	}
+	out := make([]decrypt.Key, len(keys))
+	for i, key := range keys {
+		if key.DPAPI == nil {
+			key.DPAPI = unprotect
+		}
+		out[i] = key
+	}
+	return out
+}
+
+func firstExisting(paths ...string) string {
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
@@ -0,0 +1,132 @@
+package cookiemonster_test
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-cookie-monster/pkg/cookiemonster"
+	"go-cookie-monster/pkg/dpapi"
+	"go-cookie-monster/pkg/fixture"
+)
+
+func TestDecryptUserDataDirMixedCookies(t *testing.T) {
+	f, want := generateWindows(t, t.TempDir(), 1)
+	masterKeys, err := dpapi.ParseMasterKeys(f.MasterKey)
+	if err != nil {
+		t.Fatalf("ParseMasterKeys: %v", err)
+	}
+	systemKeys, err := dpapi.ParseMasterKeys(f.SystemMasterKey)
+	if err != nil {
+		t.Fatalf("ParseMasterKeys: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		systemKeys *dpapi.MasterKeyCache
+		skip       string // scheme expected to fail without its key
+	}{
+		{"user and SYSTEM masterkeys", systemKeys, ""},
+		{"user masterkeys only", nil, fixture.SchemeV20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := cookiemonster.DecryptUserDataDir(f.UserDataDir, nil, masterKeys, tt.systemKeys)
+			result := findProfile(t, results, "Default")
+			failed := checkCookies(t, "Default", result.Cookies, want.Cookies, tt.skip)
+
+			var cookieErrors int
+			for _, e := range result.Errors {
+				if strings.HasPrefix(e, "cookies:") {
+					cookieErrors++
+				}
+			}
+			if cookieErrors != failed {
+				t.Errorf("got %d cookie errors, want %d: %v", cookieErrors, failed, result.Errors)
+			}
+		})
+	}
+}
+
+// snapshot hashes every file under dir by relative path
+func snapshot(t *testing.T, dir string) map[string][32]byte {
+	t.Helper()
+	files := make(map[string][32]byte)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files[rel] = sha256.Sum256(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// TestDecryptUserDataDirLeavesEvidence decrypts a profile whose cookie database was captured
+// with writes still in its -wal file. Opening it in place would checkpoint the WAL into the
+// database and delete it.
+func TestDecryptUserDataDirLeavesEvidence(t *testing.T) {
+	f, _ := generateWindows(t, t.TempDir(), 1)
+	masterKeys, err := dpapi.ParseMasterKeys(f.MasterKey)
+	if err != nil {
+		t.Fatalf("ParseMasterKeys: %v", err)
+	}
+
+	cookies := filepath.Join(f.UserDataDir, "Default", "Network", "Cookies")
+	db, err := sql.Open("sqlite3", cookies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	for _, stmt := range []string{"PRAGMA journal_mode=WAL", "PRAGMA wal_autocheckpoint=0", "CREATE TABLE wal_marker (x)"} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	// Keep the database and its -wal as they are before the close checkpoints them
+	captured := make(map[string][]byte)
+	for _, path := range []string{cookies, cookies + "-wal"} {
+		if captured[path], err = os.ReadFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+	os.Remove(cookies + "-shm")
+	for path, data := range captured {
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	before := snapshot(t, f.UserDataDir)
+	results := cookiemonster.DecryptUserDataDir(f.UserDataDir, nil, masterKeys, nil)
+	if len(results) == 0 {
+		t.Fatal("no profiles were decrypted")
+	}
+	after := snapshot(t, f.UserDataDir)
+
+	for path, sum := range before {
+		if got, ok := after[path]; !ok {
+			t.Errorf("%s was removed", path)
+		} else if got != sum {
+			t.Errorf("%s was modified", path)
+		}
+	}
+	for path := range after {
+		if _, ok := before[path]; !ok {
+			t.Errorf("%s was created", path)
+		}
+	}
+}
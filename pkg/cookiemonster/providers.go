@@ -0,0 +1,82 @@
+package cookiemonster
+
+import (
+	"errors"
+	"go-cookie-monster/pkg/browsers"
+	"go-cookie-monster/pkg/chromefile"
+	"go-cookie-monster/pkg/dpapi"
+	"go-cookie-monster/pkg/keys"
+	"go-cookie-monster/pkg/processes"
+	"os"
+)
+
+// NewDependencies returns the live system access, with keys recovered offline when dpapiOpts
+// supplies DPAPI masterkeys
+func NewDependencies(dpapiOpts DPAPIOptions) Dependencies {
+	masterKeys := loadOfflineMasterKeys(dpapiOpts)
+	var systemKeys *dpapi.MasterKeyCache
+	if masterKeys != nil {
+		systemKeys = loadSystemMasterKeys(dpapiOpts)
+	}
+
+	return Dependencies{
+		Keys:      newKeyProvider(masterKeys, systemKeys),
+		Files:     LiveFiles{},
+		Processes: LiveProcesses{},
+	}
+}
+
+// newKeyProvider returns the live key provider when no offline masterkeys are available
+func newKeyProvider(userKeys, systemKeys *dpapi.MasterKeyCache) KeyProvider {
+	if userKeys == nil {
+		return LiveKeys{}
+	}
+	return OfflineKeys{UserKeys: userKeys, SystemKeys: systemKeys}
+}
+
+func (LiveKeys) MasterKey(encryptedKey string) (string, error) {
+	return keys.GetMasterKey(encryptedKey)
+}
+
+func (LiveKeys) AppBoundKey(encryptedKey string, browser browsers.Browser) (string, error) {
+	return keys.GetAppBoundKey(encryptedKey, browser)
+}
+
+func (LiveKeys) CanDecryptAppBound() bool {
+	return true
+}
+
+func (LiveKeys) Unprotect(data []byte) ([]byte, error) {
+	return keys.UnprotectData(data)
+}
+
+func (k OfflineKeys) MasterKey(encryptedKey string) (string, error) {
+	return keys.GetMasterKeyOffline(encryptedKey, k.UserKeys)
+}
+
+func (k OfflineKeys) AppBoundKey(encryptedKey string, browser browsers.Browser) (string, error) {
+	if k.SystemKeys == nil {
+		return "", errors.New("the app-bound key needs SYSTEM masterkeys (-systemmasterkeys, or -systemkey and -systemprotectdir)")
+	}
+	return keys.GetAppBoundKeyOffline(encryptedKey, k.SystemKeys, k.UserKeys, browser.AppBoundFlagKeys)
+}
+
+func (k OfflineKeys) CanDecryptAppBound() bool {
+	return k.SystemKeys != nil
+}
+
+func (k OfflineKeys) Unprotect(data []byte) ([]byte, error) {
+	return k.UserKeys.Unprotect(data)
+}
+
+func (LiveFiles) ReadLocalState(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+func (LiveFiles) BrowserFiles(pids []uint32) (*chromefile.ChromeFiles, error) {
+	return chromefile.GetChromeFiles(pids)
+}
+
+func (LiveProcesses) FindProcess(name string) ([]processes.Process, error) {
+	return processes.FindProcess(name)
+}
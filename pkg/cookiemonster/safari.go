@@ -0,0 +1,52 @@
+package cookiemonster
+
+import (
+	"fmt"
+	"go-cookie-monster/pkg/decrypt"
+	"go-cookie-monster/pkg/safari"
+	"log"
+	"os"
+)
+
+// ProcessSafariMode prints the cookies of a Cookies.binarycookies file, or of every Safari
+// cookie file in a macOS home directory (the current user's if neither is given)
+func ProcessSafariMode(databasePath, homeDir, format string) {
+	var files []string
+	if databasePath != "" {
+		files = []string{databasePath}
+	} else {
+		if homeDir == "" {
+			var err error
+			homeDir, err = os.UserHomeDir()
+			if err != nil {
+				log.Fatalf("Error finding home directory: %v", err)
+			}
+		}
+		files = safari.CookieFiles(homeDir)
+		if len(files) == 0 {
+			log.Fatalf("no Safari cookie files found in %s", homeDir)
+		}
+	}
+
+	for _, file := range files {
+		fmt.Printf("\n[*] Attempting to read Safari cookies: \"%s\"\n", file)
+
+		cookies, err := safari.ReadCookies(file)
+		if err != nil {
+			log.Printf("[-] Error reading cookies: %v", err)
+			continue
+		}
+
+		formatter, err := decrypt.NewCookieFormatter(format, cookies)
+		if err != nil {
+			log.Fatalf("error creating cookie formatter: %v", err)
+		}
+		output, err := formatter.Format()
+		if err != nil {
+			log.Fatalf("error formatting cookies: %v", err)
+		}
+
+		fmt.Printf("[+] Read %d cookies:\n", len(cookies))
+		fmt.Println(output)
+	}
+}
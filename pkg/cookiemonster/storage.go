@@ -0,0 +1,50 @@
+package cookiemonster
+
+import (
+	"fmt"
+	"go-cookie-monster/pkg/webstorage"
+	"log"
+)
+
+func ProcessStorageMode(profileDir string) {
+	var err error
+
+	// If profile directory is not provided, build it
+	if profileDir == "" {
+		profileDir, err = BuildProfilePath()
+		if err != nil {
+			log.Fatalf("Error building profile path: %v", err)
+		}
+	}
+
+	fmt.Printf("\n[*] Attempting to read web storage from profile: \"%s\"\n", profileDir)
+
+	var records []webstorage.Record
+	readers := []struct {
+		name string
+		read func(string) ([]webstorage.Record, error)
+	}{
+		{"Local Storage", webstorage.ReadLocalStorage},
+		{"Session Storage", webstorage.ReadSessionStorage},
+		{"IndexedDB", webstorage.ReadIndexedDB},
+	}
+
+	for _, reader := range readers {
+		recs, err := reader.read(profileDir)
+		if err != nil {
+			log.Printf("[-] Error reading %s: %v", reader.name, err)
+			continue
+		}
+		fmt.Printf("[+] Read %d %s records\n", len(recs), reader.name)
+		records = append(records, recs...)
+	}
+
+	formatter := &webstorage.JSONFormatter{Records: records}
+	output, err := formatter.Format()
+	if err != nil {
+		log.Fatalf("error formatting storage records: %v", err)
+	}
+
+	fmt.Printf("[+] Extracted %d storage records:\n", len(records))
+	fmt.Println(output)
+}
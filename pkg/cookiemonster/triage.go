@@ -0,0 +1,248 @@
+package cookiemonster
+
+import (
+	"bufio"
+	"crypto/rsa"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go-cookie-monster/pkg/browsers"
+	"go-cookie-monster/pkg/dpapi"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func ProcessTriageMode(triageRoot, credsFile string, dpapiOpts DPAPIOptions, outputDir string) {
+	if triageRoot == "" {
+		log.Fatalf("collection path is required for triage mode")
+	}
+
+	creds := map[string]dpapi.Credentials{}
+	if credsFile != "" {
+		var err error
+		creds, err = parseCredsFile(credsFile)
+		if err != nil {
+			log.Fatalf("Error reading credentials file: %v", err)
+		}
+		fmt.Printf("[*] Loaded credentials for %d accounts\n", len(creds))
+	}
+
+	backupKey := loadBackupKey(dpapiOpts.BackupKey)
+
+	// Masterkeys supplied directly apply to every user
+	sharedKeys := dpapi.NewMasterKeyCache()
+	if dpapiOpts.MasterKeys != "" {
+		parsed, err := dpapi.ParseMasterKeys(dpapiOpts.MasterKeys)
+		if err != nil {
+			log.Fatalf("Error parsing DPAPI masterkeys: %v", err)
+		}
+		sharedKeys.Merge(parsed)
+	}
+
+	// The SYSTEM masterkey files come from the collection unless given explicitly
+	if dpapiOpts.SystemKey != "" && dpapiOpts.SystemProtectDir == "" {
+		dpapiOpts.SystemProtectDir = findTriagePath(triageRoot, "Windows", "System32", "Microsoft", "Protect", "S-1-5-18")
+		if dpapiOpts.SystemProtectDir == "" {
+			log.Printf("[-] SYSTEM Protect directory not found, SYSTEM masterkey files will not be decrypted")
+		}
+	}
+	systemKeys := loadSystemMasterKeys(dpapiOpts)
+
+	userDirs := findTriageUsers(triageRoot)
+	if len(userDirs) == 0 {
+		log.Fatalf("no user profiles (Users\\<user>\\AppData) found in %s", triageRoot)
+	}
+	fmt.Printf("[+] Found %d user profiles\n", len(userDirs))
+
+	for _, userDir := range userDirs {
+		result := processTriageUser(userDir, creds, backupKey, sharedKeys, systemKeys)
+		writeTriageResult(result, outputDir)
+	}
+}
+
+// processTriageUser resolves a user's masterkeys and decrypts every browser profile they have
+func processTriageUser(userDir string, creds map[string]dpapi.Credentials, backupKey *rsa.PrivateKey, sharedKeys, systemKeys *dpapi.MasterKeyCache) TriageResult {
+	result := TriageResult{User: filepath.Base(userDir), ProfilePath: userDir}
+	fmt.Printf("\n[*] Processing user \"%s\"\n", result.User)
+
+	masterKeys := dpapi.NewMasterKeyCache()
+	masterKeys.Merge(sharedKeys)
+
+	protectRoot := resolveFold(userDir, []string{"AppData", "Roaming", "Microsoft", "Protect"})
+	entries, _ := os.ReadDir(protectRoot)
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "S-1-") {
+			continue
+		}
+		sid := entry.Name()
+		result.SIDs = append(result.SIDs, sid)
+
+		userCreds, ok := creds[strings.ToUpper(sid)]
+		if !ok {
+			userCreds = creds[strings.ToLower(result.User)]
+		}
+		userCreds.SID = sid
+		userCreds.BackupKey = backupKey
+
+		recovered, errs := dpapi.LoadMasterKeys(filepath.Join(protectRoot, sid), userCreds)
+		for _, err := range errs {
+			result.Errors = append(result.Errors, err.Error())
+		}
+		fmt.Printf("[+] %s: recovered %d masterkeys\n", sid, recovered.Len())
+		masterKeys.Merge(recovered)
+	}
+	result.MasterKeys = masterKeys.Len()
+
+	if masterKeys.Len() == 0 {
+		result.Errors = append(result.Errors, "no masterkeys recovered, browser keys cannot be decrypted")
+		return result
+	}
+
+	userDataDirs, err := browsers.FindUserDataDirs(resolveFold(userDir, []string{"AppData"}))
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	}
+	for _, userDataDir := range userDataDirs {
+		result.Profiles = append(result.Profiles, DecryptUserDataDir(userDataDir, nil, masterKeys, systemKeys)...)
+	}
+
+	return result
+}
+
+// writeTriageResult prints a user's result, or writes it to <outputDir>/<user>.json
+func writeTriageResult(result TriageResult, outputDir string) {
+	jsonData, err := json.MarshalIndent(result, "", "    ")
+	if err != nil {
+		log.Printf("[-] Error formatting results for %s: %v", result.User, err)
+		return
+	}
+
+	if outputDir == "" {
+		fmt.Printf("[+] Results for %s:\n", result.User)
+		fmt.Println(string(jsonData))
+		return
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		log.Fatalf("error creating output directory: %v", err)
+	}
+	path := filepath.Join(outputDir, result.User+".json")
+	if err := os.WriteFile(path, jsonData, 0644); err != nil {
+		log.Printf("[-] Error writing %s: %v", path, err)
+		return
+	}
+	fmt.Printf("[+] Wrote %s\n", path)
+}
+
+// parseCredsFile reads a credentials map, one "<SID or username>:<type>:<value>" per line where
+// type is password, nthash or sha1. Blank lines and lines starting with # are ignored.
+func parseCredsFile(path string) (map[string]dpapi.Credentials, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	creds := make(map[string]dpapi.Credentials)
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("line %d: expected <SID or username>:<password|nthash|sha1>:<value>", lineNumber)
+		}
+		account := parts[0]
+		if strings.HasPrefix(strings.ToUpper(account), "S-1-") {
+			account = strings.ToUpper(account)
+		} else {
+			account = strings.ToLower(account)
+		}
+
+		entry := creds[account]
+		switch strings.ToLower(parts[1]) {
+		case "password":
+			entry.Password = parts[2]
+		case "nthash", "sha1":
+			decoded, err := hex.DecodeString(parts[2])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid hex: %v", lineNumber, err)
+			}
+			if strings.ToLower(parts[1]) == "nthash" {
+				entry.NTHash = decoded
+			} else {
+				entry.SHA1 = decoded
+			}
+		default:
+			return nil, fmt.Errorf("line %d: unknown credential type %q", lineNumber, parts[1])
+		}
+		creds[account] = entry
+	}
+
+	return creds, scanner.Err()
+}
+
+// findTriageUsers returns the user profile directories (those with an AppData directory, in any
+// case) in a Users directory at the collection root or one level below it (e.g. <root>\C\Users)
+func findTriageUsers(triageRoot string) []string {
+	usersDir := findTriagePath(triageRoot, "Users")
+	if usersDir == "" {
+		return nil
+	}
+
+	var userDirs []string
+	entries, _ := os.ReadDir(usersDir)
+	for _, entry := range entries {
+		userDir := filepath.Join(usersDir, entry.Name())
+		if entry.IsDir() && resolveFold(userDir, []string{"AppData"}) != "" {
+			userDirs = append(userDirs, userDir)
+		}
+	}
+	return userDirs
+}
+
+// findTriagePath finds a directory path in the collection root or one level below it,
+// matching each segment case-insensitively as Windows would
+func findTriagePath(triageRoot string, parts ...string) string {
+	candidates := []string{triageRoot}
+	entries, _ := os.ReadDir(triageRoot)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			candidates = append(candidates, filepath.Join(triageRoot, entry.Name()))
+		}
+	}
+
+	for _, candidate := range candidates {
+		if path := resolveFold(candidate, parts); path != "" {
+			return path
+		}
+	}
+	return ""
+}
+
+// resolveFold walks parts below dir, matching names case-insensitively
+func resolveFold(dir string, parts []string) string {
+	for _, part := range parts {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return ""
+		}
+		next := ""
+		for _, entry := range entries {
+			if entry.IsDir() && strings.EqualFold(entry.Name(), part) {
+				next = filepath.Join(dir, entry.Name())
+				break
+			}
+		}
+		if next == "" {
+			return ""
+		}
+		dir = next
+	}
+	return dir
+}
@@ -0,0 +1,138 @@
+package cookiemonster_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-cookie-monster/pkg/cookiemonster"
+)
+
+func TestProcessTriageModeMixedCookies(t *testing.T) {
+	root := t.TempDir()
+	f, _ := generateWindows(t, filepath.Join(root, "Users", "alice", "AppData", "Local", "Google", "Chrome"), 3)
+
+	outputDir := t.TempDir()
+	cookiemonster.ProcessTriageMode(root, "", cookiemonster.DPAPIOptions{
+		MasterKeys:       f.MasterKey,
+		SystemMasterKeys: f.SystemMasterKey,
+	}, outputDir)
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "alice.json"))
+	if err != nil {
+		t.Fatalf("reading triage result: %v", err)
+	}
+	var result cookiemonster.TriageResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("parsing triage result: %v", err)
+	}
+
+	profiles := make(map[string]cookiemonster.ProfileResult)
+	for _, p := range result.Profiles {
+		profiles[p.Profile] = p
+	}
+	for _, p := range f.Profiles {
+		got, ok := profiles[p.Name]
+		if !ok {
+			t.Errorf("%s: not triaged", p.Name)
+			continue
+		}
+		if len(got.Errors) > 0 {
+			t.Errorf("%s: %v", p.Name, got.Errors)
+		}
+		checkCookies(t, p.Name, got.Cookies, p.Cookies, "")
+	}
+}
+
+// TestProcessTriageModeMasterKeyFiles triages a collection whose masterkeys are only available as
+// masterkey files, resolved through the credentials file or the domain backup key
+func TestProcessTriageModeMasterKeyFiles(t *testing.T) {
+	const (
+		sid      = "S-1-5-21-1004336348-1177238915-682003330-1001"
+		password = "Summer2025!"
+	)
+
+	backupKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backupKeyFile := filepath.Join(t.TempDir(), "backup.pem")
+	backupKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(backupKey)})
+	if err := os.WriteFile(backupKeyFile, backupKeyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		password    string // the masterkey file is encrypted with
+		creds       string // credentials file, if any
+		backupKey   bool   // file has a domain key section and -backupkey is given
+		wantErrFrag string // triage error when no masterkey is recovered
+	}{
+		{"password by SID", password, strings.ToLower(sid) + ":password:" + password, false, ""},
+		{"password by username", password, "# workstation accounts\nAlice:password:" + password, false, ""},
+		{"domain backup key", "unknown domain password", "", true, ""},
+		{"wrong password", password, sid + ":password:Winter2025!", false, "no masterkeys recovered"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			userDir := filepath.Join(root, "C", "Users", "alice")
+			f, want := generateWindows(t, filepath.Join(userDir, "AppData", "Local", "Google", "Chrome"), 5)
+
+			// Collections do not always keep Windows' capitalization
+			var domainKey *rsa.PublicKey
+			opts := cookiemonster.DPAPIOptions{SystemMasterKeys: f.SystemMasterKey}
+			if tt.backupKey {
+				domainKey = &backupKey.PublicKey
+				opts.BackupKey = backupKeyFile
+			}
+			protectDir := filepath.Join(userDir, "AppData", "roaming", "MICROSOFT", "Protect", sid)
+			if _, err := f.WriteMasterKeyFile(protectDir, sid, tt.password, domainKey); err != nil {
+				t.Fatalf("WriteMasterKeyFile: %v", err)
+			}
+
+			credsFile := ""
+			if tt.creds != "" {
+				credsFile = filepath.Join(t.TempDir(), "creds.txt")
+				if err := os.WriteFile(credsFile, []byte(tt.creds+"\n"), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			outputDir := t.TempDir()
+			cookiemonster.ProcessTriageMode(root, credsFile, opts, outputDir)
+
+			data, err := os.ReadFile(filepath.Join(outputDir, "alice.json"))
+			if err != nil {
+				t.Fatalf("reading triage result: %v", err)
+			}
+			var result cookiemonster.TriageResult
+			if err := json.Unmarshal(data, &result); err != nil {
+				t.Fatalf("parsing triage result: %v", err)
+			}
+
+			if len(result.SIDs) != 1 || result.SIDs[0] != sid {
+				t.Errorf("got SIDs %v, want [%s]", result.SIDs, sid)
+			}
+			if tt.wantErrFrag != "" {
+				if result.MasterKeys != 0 || !strings.Contains(strings.Join(result.Errors, "\n"), tt.wantErrFrag) {
+					t.Errorf("got %d masterkeys and errors %v, want an error containing %q", result.MasterKeys, result.Errors, tt.wantErrFrag)
+				}
+				return
+			}
+			if result.MasterKeys != 1 || len(result.Errors) > 0 {
+				t.Fatalf("got %d masterkeys and errors %v, want 1 masterkey", result.MasterKeys, result.Errors)
+			}
+
+			got := findProfile(t, result.Profiles, "Default")
+			checkCookies(t, "Default", got.Cookies, want.Cookies, "")
+		})
+	}
+}
@@ -9,41 +9,181 @@ func NewDBReader(path string) (*DBReader, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &DBReader{db: db}, nil
+	return &DBReader{db: db, schemas: make(map[string]*SchemaInfo)}, nil
 }
 
 func (r *DBReader) Close() error {
 	return r.db.Close()
 }
 
+// DecryptValue decrypts a value encrypted with the browser's os_crypt outside the databases,
+// such as an Electron app's safeStorage-encrypted Local Storage entry
+func DecryptValue(key Key, encryptedData []byte) ([]byte, error) {
+	return decryptValue(key, encryptedData)
+}
+
+// QueryCookies queries the cookies table using the adapter matching the database's schema version.
+// The detected schema is available afterwards from Schema("cookies").
 func (r *DBReader) QueryCookies() (*sql.Rows, error) {
-	query := `SELECT name, encrypted_value, host_key, path, expires_utc, 
-              is_secure, is_httponly, samesite 
-              FROM cookies`
+	query, _, err := r.buildQuery(cookieAdapters)
+	if err != nil {
+		return nil, err
+	}
 	return r.db.Query(query)
 }
 
-func (e *CookieExtractor) ExtractCookie(keyBytes []byte) (*Cookie, error) {
+// QueryLogins queries the logins table of a Login Data database
+func (r *DBReader) QueryLogins() (*sql.Rows, error) {
+	query, _, err := r.buildQuery(loginAdapters)
+	if err != nil {
+		return nil, err
+	}
+	return r.db.Query(query)
+}
+
+// QueryAutofill queries the autofill table of a Web Data database
+func (r *DBReader) QueryAutofill() (*sql.Rows, error) {
+	query, _, err := r.buildQuery(autofillAdapters)
+	if err != nil {
+		return nil, err
+	}
+	return r.db.Query(query)
+}
+
+// QueryCreditCards queries the credit_cards table of a Web Data database
+func (r *DBReader) QueryCreditCards() (*sql.Rows, error) {
+	query, _, err := r.buildQuery(creditCardAdapters)
+	if err != nil {
+		return nil, err
+	}
+	return r.db.Query(query)
+}
+
+// QueryTokens queries the token_service table of a Web Data database
+func (r *DBReader) QueryTokens() (*sql.Rows, error) {
+	query, _, err := r.buildQuery(tokenAdapters)
+	if err != nil {
+		return nil, err
+	}
+	return r.db.Query(query)
+}
+
+func (e *CookieExtractor) ExtractCookie(key Key) (*Cookie, error) {
 	var c Cookie
 	var encryptedValue []byte
-	var sameSiteInt int
-	var expiresUtc int64
+	var plainValue string
+	var hasCrossSiteAncestor bool
+	var sameSiteInt, priorityInt, sourceSchemeInt int
+	var expiresUtc, creationUtc, lastAccessUtc, lastUpdateUtc int64
 
 	err := e.Rows.Scan(&c.Name, &encryptedValue, &c.Domain, &c.Path,
-		&expiresUtc, &c.Secure, &c.HTTPOnly, &sameSiteInt)
+		&expiresUtc, &c.Secure, &c.HTTPOnly, &sameSiteInt, &creationUtc,
+		&lastAccessUtc, &lastUpdateUtc, &c.HasExpires, &c.IsPersistent,
+		&priorityInt, &sourceSchemeInt, &c.SourcePort, &c.TopFrameSiteKey,
+		&hasCrossSiteAncestor, &plainValue)
 	if err != nil {
 		return nil, err
 	}
 
-	d, err := decryptAESGCM(keyBytes, encryptedValue)
-	if err != nil {
-		return nil, err
+	c.Value = plainValue
+	if len(encryptedValue) > 0 {
+		d, err := decryptValue(key, encryptedValue)
+		if err != nil {
+			return nil, err
+		}
+		if e.HostHash {
+			d, err = stripHostHash(d)
+			if err != nil {
+				return nil, err
+			}
+		}
+		c.Value = string(d)
 	}
 
-	c.Value = string(d)
 	c.ExpirationDate = convertTimestamp(expiresUtc)
+	c.Expires = formatTimestamp(c.ExpirationDate)
+	c.CreationDate = convertTimestamp(creationUtc)
+	c.LastAccessDate = convertTimestamp(lastAccessUtc)
+	c.LastUpdateDate = convertTimestamp(lastUpdateUtc)
 	c.SameSite = convertSameSite(sameSiteInt)
+	c.Priority = convertPriority(priorityInt)
+	c.SourceScheme = convertSourceScheme(sourceSchemeInt)
 	setDefaultValues(&c)
+	setPartitionKey(&c, hasCrossSiteAncestor)
 
 	return &c, nil
 }
+
+func (e *LoginExtractor) ExtractLogin(key Key) (*Login, error) {
+	var l Login
+	var encryptedPassword []byte
+	var dateCreated, dateLastUsed, datePasswordModified int64
+
+	err := e.Rows.Scan(&l.OriginURL, &l.ActionURL, &l.SignonRealm, &l.Username,
+		&encryptedPassword, &dateCreated, &dateLastUsed, &datePasswordModified,
+		&l.TimesUsed)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := decryptValue(key, encryptedPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	l.Password = string(d)
+	l.DateCreated = convertTimestamp(dateCreated)
+	l.DateLastUsed = convertTimestamp(dateLastUsed)
+	l.DatePasswordModified = convertTimestamp(datePasswordModified)
+
+	return &l, nil
+}
+
+func (e *WebDataExtractor) ExtractAutofill() (*Autofill, error) {
+	var a Autofill
+
+	// autofill dates are already Unix seconds
+	err := e.Rows.Scan(&a.Name, &a.Value, &a.DateCreated, &a.DateLastUsed, &a.Count)
+	if err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+func (e *WebDataExtractor) ExtractCreditCard(key Key) (*CreditCard, error) {
+	var c CreditCard
+	var encryptedNumber []byte
+
+	err := e.Rows.Scan(&c.NameOnCard, &c.ExpirationMonth, &c.ExpirationYear,
+		&encryptedNumber, &c.Nickname, &c.DateModified)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := decryptValue(key, encryptedNumber)
+	if err != nil {
+		return nil, err
+	}
+	c.CardNumber = string(d)
+
+	return &c, nil
+}
+
+func (e *WebDataExtractor) ExtractToken(key Key) (*Token, error) {
+	var t Token
+	var encryptedToken []byte
+
+	err := e.Rows.Scan(&t.Service, &encryptedToken)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := decryptValue(key, encryptedToken)
+	if err != nil {
+		return nil, err
+	}
+	t.Token = string(d)
+
+	return &t, nil
+}
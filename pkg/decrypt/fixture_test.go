@@ -0,0 +1,36 @@
+package decrypt_test
+
+import (
+	"testing"
+
+	"go-cookie-monster/pkg/fixture"
+)
+
+func TestFixtures(t *testing.T) {
+	tests := []struct {
+		platform string
+		seed     int64
+	}{
+		{fixture.PlatformWindows, 1},
+		{fixture.PlatformWindows, 42},
+		{fixture.PlatformLinux, 1},
+		{fixture.PlatformLinux, 42},
+		{fixture.PlatformMac, 1},
+		{fixture.PlatformMac, 42},
+	}
+	for _, tt := range tests {
+		t.Run(tt.platform, func(t *testing.T) {
+			f, err := fixture.Generate(t.TempDir(), fixture.Options{Platform: tt.platform, Seed: tt.seed})
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			checked, errs := fixture.Verify(f)
+			for _, err := range errs {
+				t.Error(err)
+			}
+			if checked == 0 {
+				t.Error("no values verified")
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package decrypt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// NewCookieFormatter returns the formatter for the named export format
+func NewCookieFormatter(format string, cookies []Cookie) (CookieFormatter, error) {
+	switch strings.ToLower(format) {
+	case "", FormatJSON:
+		return &JSONFormatter{Cookies: cookies}, nil
+	case FormatCDP:
+		return &CDPFormatter{Cookies: cookies}, nil
+	case FormatPlaywright:
+		return &PlaywrightFormatter{Cookies: cookies}, nil
+	case FormatNetscape:
+		return &NetscapeFormatter{Cookies: cookies}, nil
+	default:
+		return nil, fmt.Errorf("unknown cookie format: %s", format)
+	}
+}
+
+// Format renders the cookies as CDP CookieParam objects. Partitioned cookies carry
+// their partitionKey so Network.setCookies stores them in the original partition.
+func (f *CDPFormatter) Format() (string, error) {
+	params := make([]cdpCookie, 0, len(f.Cookies))
+	for _, c := range f.Cookies {
+		p := cdpCookie{
+			Name:         c.Name,
+			Value:        c.Value,
+			Domain:       c.Domain,
+			Path:         c.Path,
+			Secure:       c.Secure,
+			HTTPOnly:     c.HTTPOnly,
+			SameSite:     titleSameSite(c.SameSite),
+			Priority:     c.Priority,
+			SourceScheme: c.SourceScheme,
+			SourcePort:   c.SourcePort,
+			PartitionKey: cookiePartitionKey(c),
+		}
+		if !c.Session {
+			p.Expires = c.ExpirationDate
+		}
+		if p.SourcePort < 0 {
+			p.SourcePort = 0
+		}
+		params = append(params, p)
+	}
+
+	jsonData, err := json.MarshalIndent(params, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
+
+// Format renders the cookies as a Playwright storage state. Partitioned cookies carry
+// their top-level site as the partitionKey.
+func (f *PlaywrightFormatter) Format() (string, error) {
+	state := playwrightState{
+		Cookies: make([]playwrightCookie, 0, len(f.Cookies)),
+		Origins: []interface{}{},
+	}
+	for _, c := range f.Cookies {
+		p := playwrightCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Expires:  -1,
+			HTTPOnly: c.HTTPOnly,
+			Secure:   c.Secure,
+			SameSite: titleSameSite(c.SameSite),
+		}
+		if !c.Session {
+			p.Expires = c.ExpirationDate
+		}
+		if p.SameSite == "" {
+			p.SameSite = "None"
+		}
+		if key := cookiePartitionKey(c); key != nil {
+			p.PartitionKey = key.TopLevelSite
+		}
+		state.Cookies = append(state.Cookies, p)
+	}
+
+	jsonData, err := json.MarshalIndent(state, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
+
+// Format renders the cookies as a Netscape cookies.txt file. The format has no notion of
+// partitions, so partitioned cookies are written commented out with their top-level site
+// rather than being replayed as unpartitioned cookies.
+func (f *NetscapeFormatter) Format() (string, error) {
+	var b strings.Builder
+	b.WriteString("# Netscape HTTP Cookie File\n")
+
+	for _, c := range f.Cookies {
+		domain := c.Domain
+		if c.HTTPOnly {
+			domain = "#HttpOnly_" + domain
+		}
+
+		line := fmt.Sprintf("%s\t%s\t%s\t%s\t%d\t%s\t%s",
+			domain,
+			netscapeBool(!c.HostOnly),
+			c.Path,
+			netscapeBool(c.Secure),
+			c.ExpirationDate,
+			c.Name,
+			c.Value)
+
+		if key := cookiePartitionKey(c); key != nil {
+			fmt.Fprintf(&b, "# Partitioned (top-level site %s): %s\n", key.TopLevelSite, line)
+			continue
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	return b.String(), nil
+}
+
+// cookiePartitionKey returns the partition key of a partitioned cookie, or nil
+func cookiePartitionKey(c Cookie) *PartitionKey {
+	if key, ok := c.PartitionKey.(*PartitionKey); ok && key != nil {
+		return key
+	}
+	return nil
+}
+
+// titleSameSite converts our sameSite values to the capitalized form CDP and Playwright use
+func titleSameSite(sameSite string) string {
+	switch sameSite {
+	case "no_restriction":
+		return "None"
+	case "lax":
+		return "Lax"
+	case "strict":
+		return "Strict"
+	default:
+		return ""
+	}
+}
+
+func netscapeBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
@@ -1,12 +1,81 @@
 package decrypt
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// decryptValue dispatches an encrypted column value to the scheme named by its version prefix:
+// AES-128-CBC when the key has a CBC key for the prefix (Linux, macOS), AES-256-GCM otherwise
+// (Windows), and DPAPI for bare DPAPI blobs. Empty values decrypt to nothing.
+func decryptValue(key Key, encryptedData []byte) ([]byte, error) {
+	if len(encryptedData) == 0 {
+		return nil, nil
+	}
+
+	if len(key.anyOf) > 0 {
+		var lastErr error
+		for _, k := range key.anyOf {
+			plaintext, err := decryptValue(k, encryptedData)
+			if err == nil {
+				return plaintext, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+
+	if bytes.HasPrefix(encryptedData, prefixDPAPI) {
+		if key.DPAPI == nil {
+			return nil, errors.New("value is a DPAPI blob and no DPAPI decryptor is available")
+		}
+		return key.DPAPI(encryptedData)
+	}
+
+	prefix := encryptedData[:min(cbcPrefixLength, len(encryptedData))]
+	if cbcKey, ok := key.CBC[string(prefix)]; ok {
+		return decryptAESCBC(cbcKey, encryptedData)
+	}
+
+	gcmKey := key.GCM
+	if bytes.HasPrefix(encryptedData, prefixV20) && len(key.AppBound) > 0 {
+		gcmKey = key.AppBound
+	}
+
+	switch {
+	case bytes.HasPrefix(encryptedData, prefixV10),
+		bytes.HasPrefix(encryptedData, prefixV11),
+		bytes.HasPrefix(encryptedData, prefixV20):
+		if len(gcmKey) == 0 {
+			return nil, fmt.Errorf("no key for encrypted value prefix %q", prefix)
+		}
+		return decryptAESGCM(gcmKey, encryptedData)
+	default:
+		return nil, fmt.Errorf("unsupported encrypted value prefix: %x", prefix)
+	}
+}
+
+// stripHostHash removes the SHA256(host_key) prefix from a cookie plaintext
+func stripHostHash(plaintext []byte) ([]byte, error) {
+	if len(plaintext) < hostHashLength {
+		return nil, errors.New("decrypted value is shorter than the host hash")
+	}
+	return plaintext[hostHashLength:], nil
+}
+
 func decryptAESGCM(key []byte, encryptedData []byte) ([]byte, error) {
+	// 3 byte prefix, 12 byte nonce, 16 byte tag
+	if len(encryptedData) < 3+12+16 {
+		return nil, errors.New("encrypted value is too short")
+	}
+
 	nonce := encryptedData[3:15]
 	ciphertext := encryptedData[15 : len(encryptedData)-16]
 	tag := encryptedData[len(encryptedData)-16:]
@@ -26,13 +95,26 @@ func decryptAESGCM(key []byte, encryptedData []byte) ([]byte, error) {
 		return nil, err
 	}
 
-	return plaintext[32:], nil
+	return plaintext, nil
 }
 
+// convertTimestamp converts a Chromium timestamp (microseconds since 1601) to Unix seconds.
+// 0 is Chromium's "no value" sentinel and stays 0.
 func convertTimestamp(expiresUtc int64) int64 {
+	if expiresUtc == 0 {
+		return 0
+	}
 	return expiresUtc/1000000 - 11644473600
 }
 
+// formatTimestamp formats Unix seconds as RFC 3339, or returns "" for 0
+func formatTimestamp(unix int64) string {
+	if unix == 0 {
+		return ""
+	}
+	return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+}
+
 func convertSameSite(sameSiteInt int) string {
 	switch sameSiteInt {
 	case 0:
@@ -46,11 +128,82 @@ func convertSameSite(sameSiteInt int) string {
 	}
 }
 
+func convertPriority(priorityInt int) string {
+	switch priorityInt {
+	case 0:
+		return "Low"
+	case 2:
+		return "High"
+	default:
+		return "Medium"
+	}
+}
+
+func convertSourceScheme(sourceSchemeInt int) string {
+	switch sourceSchemeInt {
+	case 1:
+		return "NonSecure"
+	case 2:
+		return "Secure"
+	default:
+		return "Unset"
+	}
+}
+
+// setPartitionKey populates the partition key of CHIPS cookies from top_frame_site_key
+func setPartitionKey(c *Cookie, hasCrossSiteAncestor bool) {
+	if c.TopFrameSiteKey == "" {
+		c.PartitionKey = nil
+		return
+	}
+	c.PartitionKey = &PartitionKey{
+		TopLevelSite:         c.TopFrameSiteKey,
+		HasCrossSiteAncestor: hasCrossSiteAncestor,
+	}
+}
+
+// DedupCookies removes duplicate cookies, keeping the most recently updated copy.
+// Cookies are identified by the columns of Chromium's unique index: name, domain, path,
+// partition (top frame site and cross-site ancestor), source scheme and source port. A
+// partitioned cookie never replaces an unpartitioned one (or one from another partition), and
+// an http cookie never replaces an https one, with the same name.
+func DedupCookies(cookies []Cookie) []Cookie {
+	index := make(map[string]int)
+	var out []Cookie
+
+	for _, c := range cookies {
+		hasCrossSiteAncestor := false
+		if pk, ok := c.PartitionKey.(*PartitionKey); ok && pk != nil {
+			hasCrossSiteAncestor = pk.HasCrossSiteAncestor
+		}
+		id := strings.Join([]string{c.Name, c.Domain, c.Path, c.TopFrameSiteKey,
+			strconv.FormatBool(hasCrossSiteAncestor), c.SourceScheme, strconv.Itoa(c.SourcePort)}, "\x00")
+		if i, ok := index[id]; ok {
+			if cookieUpdated(c) > cookieUpdated(out[i]) {
+				out[i] = c
+			}
+			continue
+		}
+		index[id] = len(out)
+		out = append(out, c)
+	}
+
+	return out
+}
+
+// cookieUpdated returns the last time a cookie was written
+func cookieUpdated(c Cookie) int64 {
+	if c.LastUpdateDate != 0 {
+		return c.LastUpdateDate
+	}
+	return c.CreationDate
+}
+
 func setDefaultValues(c *Cookie) {
-	c.HostOnly = false
-	c.Session = false
+	// Domain cookies are stored with a leading dot, host-only cookies without one
+	c.HostOnly = !strings.HasPrefix(c.Domain, ".")
+	c.Session = !c.IsPersistent
 	c.FirstPartyDomain = ""
-	c.PartitionKey = nil
 	c.StoreID = nil
 }
 
@@ -61,3 +214,27 @@ func (f *JSONFormatter) Format() (string, error) {
 	}
 	return string(jsonData), nil
 }
+
+func (f *LoginJSONFormatter) Format() (string, error) {
+	jsonData, err := json.MarshalIndent(f.Logins, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
+
+func (f *WebDataJSONFormatter) Format() (string, error) {
+	jsonData, err := json.MarshalIndent(f, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
+
+func (f *HistoryJSONFormatter) Format() (string, error) {
+	jsonData, err := json.MarshalIndent(f, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
@@ -0,0 +1,53 @@
+package decrypt
+
+import "testing"
+
+func TestDedupCookies(t *testing.T) {
+	base := Cookie{Name: "sid", Domain: ".a.com", Path: "/", SourceScheme: "Secure", SourcePort: 443, LastUpdateDate: 1}
+	newer := base
+	newer.Value = "newer"
+	newer.LastUpdateDate = 2
+
+	variant := func(change func(*Cookie)) Cookie {
+		c := base
+		change(&c)
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		cookies []Cookie
+		want    int
+	}{
+		{"same cookie", []Cookie{base, newer}, 1},
+		{"other path", []Cookie{base, variant(func(c *Cookie) { c.Path = "/x" })}, 2},
+		{"partitioned", []Cookie{base, variant(func(c *Cookie) {
+			c.TopFrameSiteKey = "https://b.com"
+			setPartitionKey(c, false)
+		})}, 2},
+		{"cross-site ancestor", []Cookie{
+			variant(func(c *Cookie) {
+				c.TopFrameSiteKey = "https://b.com"
+				setPartitionKey(c, false)
+			}),
+			variant(func(c *Cookie) {
+				c.TopFrameSiteKey = "https://b.com"
+				setPartitionKey(c, true)
+			}),
+		}, 2},
+		{"source scheme", []Cookie{base, variant(func(c *Cookie) { c.SourceScheme = "NonSecure" })}, 2},
+		{"source port", []Cookie{base, variant(func(c *Cookie) { c.SourcePort = 8443 })}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DedupCookies(tt.cookies); len(got) != tt.want {
+				t.Fatalf("got %d cookies, want %d", len(got), tt.want)
+			}
+		})
+	}
+
+	if got := DedupCookies([]Cookie{base, newer}); got[0].Value != "newer" {
+		t.Fatalf("kept %q, want the most recently updated copy", got[0].Value)
+	}
+}
@@ -15,17 +15,252 @@ type Cookie struct {
 	FirstPartyDomain string      `json:"firstPartyDomain"`
 	PartitionKey     interface{} `json:"partitionKey"`
 	ExpirationDate   int64       `json:"expirationDate"`
+	Expires          string      `json:"expires,omitempty"`
 	StoreID          interface{} `json:"storeId"`
+	CreationDate     int64       `json:"creationDate"`
+	LastAccessDate   int64       `json:"lastAccessDate"`
+	LastUpdateDate   int64       `json:"lastUpdateDate"`
+	HasExpires       bool        `json:"hasExpires"`
+	IsPersistent     bool        `json:"isPersistent"`
+	Priority         string      `json:"priority"`
+	SourceScheme     string      `json:"sourceScheme"`
+	SourcePort       int         `json:"sourcePort"`
+	TopFrameSiteKey  string      `json:"topFrameSiteKey"`
+}
+
+// PartitionKey identifies the partition of a CHIPS (partitioned) cookie
+type PartitionKey struct {
+	TopLevelSite         string `json:"topLevelSite"`
+	HasCrossSiteAncestor bool   `json:"hasCrossSiteAncestor"`
+}
+
+type Login struct {
+	OriginURL            string `json:"originUrl"`
+	ActionURL            string `json:"actionUrl"`
+	SignonRealm          string `json:"signonRealm"`
+	Username             string `json:"username"`
+	Password             string `json:"password"`
+	DateCreated          int64  `json:"dateCreated"`
+	DateLastUsed         int64  `json:"dateLastUsed"`
+	DatePasswordModified int64  `json:"datePasswordModified"`
+	TimesUsed            int    `json:"timesUsed"`
+}
+
+type Autofill struct {
+	Name         string `json:"name"`
+	Value        string `json:"value"`
+	DateCreated  int64  `json:"dateCreated"`
+	DateLastUsed int64  `json:"dateLastUsed"`
+	Count        int    `json:"count"`
+}
+
+type CreditCard struct {
+	NameOnCard      string `json:"nameOnCard"`
+	ExpirationMonth int    `json:"expirationMonth"`
+	ExpirationYear  int    `json:"expirationYear"`
+	CardNumber      string `json:"cardNumber"`
+	Nickname        string `json:"nickname"`
+	DateModified    int64  `json:"dateModified"`
+}
+
+type Token struct {
+	Service string `json:"service"`
+	Token   string `json:"token"`
+}
+
+// HistoryURL is a visited URL with its visit totals
+type HistoryURL struct {
+	URL        string `json:"url"`
+	Title      string `json:"title"`
+	VisitCount int    `json:"visitCount"`
+	LastVisit  int64  `json:"lastVisit"`
+}
+
+// HistoryVisit is a single visit to a URL
+type HistoryVisit struct {
+	URL        string `json:"url"`
+	Title      string `json:"title"`
+	VisitTime  int64  `json:"visitTime"`
+	Transition string `json:"transition"`
+}
+
+// Bookmark is a bookmarked URL and the folder path it is filed under
+type Bookmark struct {
+	Title        string `json:"title"`
+	URL          string `json:"url"`
+	Folder       string `json:"folder"`
+	DateAdded    int64  `json:"dateAdded"`
+	DateModified int64  `json:"dateModified"`
+}
+
+// SchemaInfo describes the schema a table was read with
+type SchemaInfo struct {
+	Table             string   `json:"table"`
+	Version           int      `json:"version"`
+	CompatibleVersion int      `json:"compatibleVersion"`
+	Adapter           string   `json:"adapter"`
+	HostHash          bool     `json:"hostHash"`
+	BestEffort        bool     `json:"bestEffort"`
+	MissingColumns    []string `json:"missingColumns,omitempty"`
 }
 
 type DBReader struct {
-	db *sql.DB
+	db      *sql.DB
+	schemas map[string]*SchemaInfo
 }
 
 type CookieExtractor struct {
+	Rows     *sql.Rows
+	HostHash bool // plaintexts are prefixed with SHA256(host_key)
+}
+
+type LoginExtractor struct {
+	Rows *sql.Rows
+}
+
+type WebDataExtractor struct {
 	Rows *sql.Rows
 }
 
+// CookieFormatter renders cookies in one of the supported export formats
+type CookieFormatter interface {
+	Format() (string, error)
+}
+
 type JSONFormatter struct {
 	Cookies []Cookie
 }
+
+// CDPFormatter formats cookies as Network.setCookies parameters for the Chrome DevTools Protocol
+type CDPFormatter struct {
+	Cookies []Cookie
+}
+
+// PlaywrightFormatter formats cookies as a Playwright storage state file
+type PlaywrightFormatter struct {
+	Cookies []Cookie
+}
+
+// NetscapeFormatter formats cookies as a Netscape cookies.txt file
+type NetscapeFormatter struct {
+	Cookies []Cookie
+}
+
+type cdpCookie struct {
+	Name         string        `json:"name"`
+	Value        string        `json:"value"`
+	Domain       string        `json:"domain"`
+	Path         string        `json:"path"`
+	Secure       bool          `json:"secure"`
+	HTTPOnly     bool          `json:"httpOnly"`
+	SameSite     string        `json:"sameSite,omitempty"`
+	Expires      int64         `json:"expires,omitempty"`
+	Priority     string        `json:"priority,omitempty"`
+	SourceScheme string        `json:"sourceScheme,omitempty"`
+	SourcePort   int           `json:"sourcePort,omitempty"`
+	PartitionKey *PartitionKey `json:"partitionKey,omitempty"`
+}
+
+type playwrightState struct {
+	Cookies []playwrightCookie `json:"cookies"`
+	Origins []interface{}      `json:"origins"`
+}
+
+type playwrightCookie struct {
+	Name         string `json:"name"`
+	Value        string `json:"value"`
+	Domain       string `json:"domain"`
+	Path         string `json:"path"`
+	Expires      int64  `json:"expires"`
+	HTTPOnly     bool   `json:"httpOnly"`
+	Secure       bool   `json:"secure"`
+	SameSite     string `json:"sameSite"`
+	PartitionKey string `json:"partitionKey,omitempty"`
+}
+
+type LoginJSONFormatter struct {
+	Logins []Login
+}
+
+type HistoryJSONFormatter struct {
+	URLs      []HistoryURL   `json:"urls"`
+	Visits    []HistoryVisit `json:"visits"`
+	Bookmarks []Bookmark     `json:"bookmarks"`
+}
+
+type WebDataJSONFormatter struct {
+	Autofill    []Autofill   `json:"autofill"`
+	CreditCards []CreditCard `json:"creditCards"`
+	Tokens      []Token      `json:"tokens"`
+}
+
+// Key holds what a profile's encrypted values are decrypted with. Windows profiles use
+// AES-256-GCM: the os_crypt key for v10 values and, since Chrome 127, the app-bound key for v20
+// values, often side by side in one database. Linux and macOS derive an AES-128-CBC key per
+// prefix. Values that are bare DPAPI blobs are passed to DPAPI, when set.
+type Key struct {
+	GCM      []byte                            // os_crypt key, or any single key given by the user (Windows)
+	AppBound []byte                            // app-bound key for v20 values; GCM is used when unset (Windows)
+	CBC      map[string][]byte                 // keys by value prefix, "v10" or "v11" (Linux, macOS)
+	DPAPI    func(blob []byte) ([]byte, error) // CryptUnprotectData, live or with offline masterkeys
+
+	anyOf []Key // keys tried in turn for each value, see AnyOf
+}
+
+// queryAdapter maps a range of schema versions of a table to the columns we select
+type queryAdapter struct {
+	name       string
+	table      string
+	minVersion int
+	maxVersion int
+	hostHash   bool
+	columns    []column
+}
+
+// column is an output column, the name it has in the adapter's schema, and a SQL default
+type column struct {
+	name     string
+	source   string
+	fallback string
+}
+
+const (
+	// Newest schema versions the adapters were written against
+	maxKnownCookiesVersion   = 24
+	maxKnownLoginDataVersion = 43
+	maxKnownWebDataVersion   = 135
+
+	// SHA256 host hash prefixed to cookie plaintexts from cookies version 24
+	hostHashLength = 32
+)
+
+const (
+	// Cookie export formats
+	FormatJSON       = "json"
+	FormatCDP        = "cdp"
+	FormatPlaywright = "playwright"
+	FormatNetscape   = "netscape"
+)
+
+const (
+	// AES-128-CBC key derivation used by Chromium on Linux and macOS
+	cbcSalt          = "saltysalt"
+	cbcKeyLength     = 16
+	linuxIterations  = 1
+	linuxV10Password = "peanuts"
+	linuxV11Password = "" // used by Chromium when no keyring password is available
+	macIterations    = 1003
+	cbcIVByte        = ' '
+	cbcPrefixLength  = 3
+)
+
+var (
+	// Encrypted value version prefixes
+	prefixV10 = []byte("v10")
+	prefixV11 = []byte("v11")
+	prefixV20 = []byte("v20")
+
+	// DPAPI blob header (version 1 and the provider GUID), which starts values encrypted
+	// directly with CryptProtectData by Chromium before v80 and Electron apps without Local State
+	prefixDPAPI = []byte{0x01, 0x00, 0x00, 0x00, 0xd0, 0x8c, 0x9d, 0xdf, 0x01, 0x15, 0xd1, 0x11, 0x8c, 0x7a, 0x00, 0xc0, 0x4f, 0xc2, 0x97, 0xeb}
+)
@@ -0,0 +1,94 @@
+package decrypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// WindowsKey wraps a decrypted os_crypt or app-bound key
+func WindowsKey(key []byte) Key {
+	return Key{GCM: key}
+}
+
+// LinuxKey derives the keys Chromium uses on Linux: v10 values are encrypted with a key derived
+// from "peanuts", v11 values with one derived from the Secret Service or KWallet password. An
+// empty keyringPassword derives the v11 key Chromium falls back to when no keyring is available.
+func LinuxKey(keyringPassword string) Key {
+	if keyringPassword == "" {
+		keyringPassword = linuxV11Password
+	}
+	return Key{CBC: map[string][]byte{
+		string(prefixV10): deriveCBCKey(linuxV10Password, linuxIterations),
+		string(prefixV11): deriveCBCKey(keyringPassword, linuxIterations),
+	}}
+}
+
+// MacKey derives the key Chromium uses on macOS from the "<Browser> Safe Storage" keychain
+// password. Every value is v10, encrypted with a PBKDF2-SHA1 key of 1003 iterations.
+func MacKey(safeStoragePassword string) Key {
+	return Key{CBC: map[string][]byte{
+		string(prefixV10): deriveCBCKey(safeStoragePassword, macIterations),
+	}}
+}
+
+// AnyOf returns a key that decrypts each value with the first of keys that can, so a database
+// whose values were written under different keys decrypts in one pass. Keys that are zero are
+// skipped; a single remaining key is returned as is.
+func AnyOf(keys ...Key) Key {
+	var nonZero []Key
+	for _, k := range keys {
+		if !k.IsZero() {
+			nonZero = append(nonZero, k)
+		}
+	}
+	if len(nonZero) == 1 {
+		return nonZero[0]
+	}
+	return Key{anyOf: nonZero}
+}
+
+// IsZero reports whether the key has nothing to decrypt with
+func (k Key) IsZero() bool {
+	return len(k.GCM) == 0 && len(k.AppBound) == 0 && len(k.CBC) == 0 && k.DPAPI == nil && len(k.anyOf) == 0
+}
+
+// deriveCBCKey derives an AES-128 key with PBKDF2-SHA1 over the fixed "saltysalt" salt
+func deriveCBCKey(password string, iterations int) []byte {
+	return pbkdf2.Key([]byte(password), []byte(cbcSalt), iterations, cbcKeyLength, sha1.New)
+}
+
+// decryptAESCBC decrypts a prefixed AES-128-CBC value. The IV is 16 spaces.
+func decryptAESCBC(key []byte, encryptedData []byte) ([]byte, error) {
+	ciphertext := encryptedData[cbcPrefixLength:]
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, errors.New("encrypted value is not a whole number of blocks")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+	iv := bytes.Repeat([]byte{cbcIVByte}, aes.BlockSize)
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+
+	// PKCS#7 padding; a bad pad almost always means the wrong password
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return nil, fmt.Errorf("invalid padding (wrong key?)")
+	}
+	for _, b := range plaintext[len(plaintext)-padding:] {
+		if int(b) != padding {
+			return nil, fmt.Errorf("invalid padding (wrong key?)")
+		}
+	}
+
+	return plaintext[:len(plaintext)-padding], nil
+}
@@ -0,0 +1,127 @@
+package decrypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"testing"
+)
+
+// gcmValue encrypts plaintext as Chromium does on Windows: prefix || nonce || ciphertext || tag
+func gcmValue(prefix string, key, plaintext []byte) []byte {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	nonce := bytes.Repeat([]byte{7}, aead.NonceSize())
+	return aead.Seal(append([]byte(prefix), nonce...), nonce, plaintext, nil)
+}
+
+// cbcValue encrypts plaintext as Chromium does on Linux and macOS: prefix || AES-128-CBC with
+// an IV of 16 spaces
+func cbcValue(prefix string, key, plaintext []byte) []byte {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	n := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := append(append([]byte(nil), plaintext...), bytes.Repeat([]byte{byte(n)}, n)...)
+	out := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, bytes.Repeat([]byte{cbcIVByte}, aes.BlockSize)).CryptBlocks(out, padded)
+	return append([]byte(prefix), out...)
+}
+
+func TestDecryptValue(t *testing.T) {
+	osCrypt := bytes.Repeat([]byte{0x10}, 32)
+	appBound := bytes.Repeat([]byte{0x20}, 32)
+	plaintext := []byte("session=secret")
+
+	v10 := gcmValue("v10", osCrypt, plaintext)
+	v20 := gcmValue("v20", appBound, plaintext)
+	tampered := append([]byte(nil), v10...)
+	tampered[len(tampered)-1] ^= 1
+	dpapiBlob := append(append([]byte(nil), prefixDPAPI...), "blob"...)
+	unprotect := func(data []byte) ([]byte, error) { return plaintext, nil }
+
+	windows := Key{GCM: osCrypt, AppBound: appBound}
+	linux := LinuxKey("keyring")
+
+	tests := []struct {
+		name    string
+		key     Key
+		value   []byte
+		want    []byte
+		wantErr bool
+	}{
+		{"v10 with the os_crypt key", WindowsKey(osCrypt), v10, plaintext, false},
+		{"v10 alongside an app-bound key", windows, v10, plaintext, false},
+		{"v20 with the app-bound key", windows, v20, plaintext, false},
+		{"v20 with the app-bound key given as -key", WindowsKey(appBound), v20, plaintext, false},
+		{"v20 without the app-bound key", WindowsKey(osCrypt), v20, nil, true},
+		{"v10 and v20 through AnyOf", AnyOf(WindowsKey(appBound), Key{}, WindowsKey(osCrypt)), v10, plaintext, false},
+		{"v20 through AnyOf", AnyOf(WindowsKey(osCrypt), WindowsKey(appBound)), v20, plaintext, false},
+		{"no AnyOf key decrypts", AnyOf(WindowsKey(appBound), linux), v10, nil, true},
+		{"tampered value", WindowsKey(osCrypt), tampered, nil, true},
+		{"Linux v10", linux, cbcValue("v10", deriveCBCKey(linuxV10Password, linuxIterations), plaintext), plaintext, false},
+		{"Linux v11", linux, cbcValue("v11", deriveCBCKey("keyring", linuxIterations), plaintext), plaintext, false},
+		{"Linux v11 without a keyring", LinuxKey(""), cbcValue("v11", deriveCBCKey(linuxV11Password, linuxIterations), plaintext), plaintext, false},
+		{"macOS v10", MacKey("safe storage"), cbcValue("v10", deriveCBCKey("safe storage", macIterations), plaintext), plaintext, false},
+		{"DPAPI blob", Key{GCM: osCrypt, DPAPI: unprotect}, dpapiBlob, plaintext, false},
+		{"DPAPI blob without a decryptor", WindowsKey(osCrypt), dpapiBlob, nil, true},
+		{"empty value", WindowsKey(osCrypt), nil, nil, false},
+		{"unknown prefix", WindowsKey(osCrypt), []byte("v99abcdefghijklmnopqrstuvwxyz0123456789"), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decryptValue(tt.key, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnyOf(t *testing.T) {
+	single := WindowsKey(bytes.Repeat([]byte{1}, 32))
+
+	tests := []struct {
+		name     string
+		keys     []Key
+		wantZero bool
+		wantKeys int
+	}{
+		{"no keys", nil, true, 0},
+		{"only zero keys", []Key{{}, {}}, true, 0},
+		{"one key is returned as is", []Key{{}, single}, false, 0},
+		{"several keys", []Key{single, LinuxKey("")}, false, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := AnyOf(tt.keys...)
+			if key.IsZero() != tt.wantZero {
+				t.Errorf("IsZero = %v, want %v", key.IsZero(), tt.wantZero)
+			}
+			if len(key.anyOf) != tt.wantKeys {
+				t.Errorf("got %d alternatives, want %d", len(key.anyOf), tt.wantKeys)
+			}
+		})
+	}
+}
+
+func TestDecryptValueReportsLastError(t *testing.T) {
+	failing := Key{DPAPI: func([]byte) ([]byte, error) { return nil, errors.New("unprotect failed") }}
+	blob := append(append([]byte(nil), prefixDPAPI...), "blob"...)
+
+	_, err := decryptValue(AnyOf(WindowsKey(bytes.Repeat([]byte{1}, 32)), failing), blob)
+	if err == nil || err.Error() != "unprotect failed" {
+		t.Errorf("got %v, want the last key's error", err)
+	}
+}
@@ -0,0 +1,262 @@
+package decrypt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Cookies adapters, newest first. Chromium 130 (cookies version 24) started prefixing every
+// cookie plaintext with SHA256(host_key). Version 10 renamed the secure/httponly/persistent
+// columns and older builds have none of the source or partition columns.
+var cookieAdapters = []queryAdapter{
+	{
+		name:       "cookies-v24",
+		table:      "cookies",
+		minVersion: 24,
+		maxVersion: maxKnownCookiesVersion,
+		hostHash:   true,
+		columns:    cookieColumns,
+	},
+	{
+		name:       "cookies-v10",
+		table:      "cookies",
+		minVersion: 10,
+		maxVersion: 23,
+		columns:    cookieColumns,
+	},
+	{
+		name:       "cookies-legacy",
+		table:      "cookies",
+		minVersion: 0,
+		maxVersion: 9,
+		columns: []column{
+			{"name", "name", "''"},
+			{"encrypted_value", "encrypted_value", "X''"},
+			{"host_key", "host_key", "''"},
+			{"path", "path", "'/'"},
+			{"expires_utc", "expires_utc", "0"},
+			{"is_secure", "secure", "0"},
+			{"is_httponly", "httponly", "0"},
+			{"samesite", "firstpartyonly", "0"},
+			{"creation_utc", "creation_utc", "0"},
+			{"last_access_utc", "last_access_utc", "0"},
+			{"last_update_utc", "last_update_utc", "0"},
+			{"has_expires", "has_expires", "1"},
+			{"is_persistent", "persistent", "1"},
+			{"priority", "priority", "1"},
+			{"source_scheme", "source_scheme", "0"},
+			{"source_port", "source_port", "-1"},
+			{"top_frame_site_key", "top_frame_site_key", "''"},
+			{"has_cross_site_ancestor", "has_cross_site_ancestor", "0"},
+			{"value", "value", "''"},
+		},
+	},
+}
+
+var cookieColumns = []column{
+	{"name", "name", "''"},
+	{"encrypted_value", "encrypted_value", "X''"},
+	{"host_key", "host_key", "''"},
+	{"path", "path", "'/'"},
+	{"expires_utc", "expires_utc", "0"},
+	{"is_secure", "is_secure", "0"},
+	{"is_httponly", "is_httponly", "0"},
+	{"samesite", "samesite", "0"},
+	{"creation_utc", "creation_utc", "0"},
+	{"last_access_utc", "last_access_utc", "0"},
+	{"last_update_utc", "last_update_utc", "0"},
+	{"has_expires", "has_expires", "1"},
+	{"is_persistent", "is_persistent", "1"},
+	{"priority", "priority", "1"},
+	{"source_scheme", "source_scheme", "0"},
+	{"source_port", "source_port", "-1"},
+	{"top_frame_site_key", "top_frame_site_key", "''"},
+	{"has_cross_site_ancestor", "has_cross_site_ancestor", "0"},
+	{"value", "value", "''"},
+}
+
+// Login Data adapters. The date_last_used and date_password_modified columns only exist in newer builds.
+var loginAdapters = []queryAdapter{
+	{
+		name:       "logins",
+		table:      "logins",
+		minVersion: 0,
+		maxVersion: maxKnownLoginDataVersion,
+		columns: []column{
+			{"origin_url", "origin_url", "''"},
+			{"action_url", "action_url", "''"},
+			{"signon_realm", "signon_realm", "''"},
+			{"username_value", "username_value", "''"},
+			{"password_value", "password_value", "X''"},
+			{"date_created", "date_created", "0"},
+			{"date_last_used", "date_last_used", "0"},
+			{"date_password_modified", "date_password_modified", "0"},
+			{"times_used", "times_used", "0"},
+		},
+	},
+}
+
+// Web Data adapters, one per table we read
+var autofillAdapters = []queryAdapter{
+	{
+		name:       "autofill",
+		table:      "autofill",
+		minVersion: 0,
+		maxVersion: maxKnownWebDataVersion,
+		columns: []column{
+			{"name", "name", "''"},
+			{"value", "value", "''"},
+			{"date_created", "date_created", "0"},
+			{"date_last_used", "date_last_used", "0"},
+			{"count", "count", "0"},
+		},
+	},
+}
+
+var creditCardAdapters = []queryAdapter{
+	{
+		name:       "credit_cards",
+		table:      "credit_cards",
+		minVersion: 0,
+		maxVersion: maxKnownWebDataVersion,
+		columns: []column{
+			{"name_on_card", "name_on_card", "''"},
+			{"expiration_month", "expiration_month", "0"},
+			{"expiration_year", "expiration_year", "0"},
+			{"card_number_encrypted", "card_number_encrypted", "X''"},
+			{"nickname", "nickname", "''"},
+			{"date_modified", "date_modified", "0"},
+		},
+	},
+}
+
+var tokenAdapters = []queryAdapter{
+	{
+		name:       "token_service",
+		table:      "token_service",
+		minVersion: 0,
+		maxVersion: maxKnownWebDataVersion,
+		columns: []column{
+			{"service", "service", "''"},
+			{"encrypted_token", "encrypted_token", "X''"},
+		},
+	},
+}
+
+// Schema returns the schema information recorded by the last query of the given table, or nil
+func (r *DBReader) Schema(table string) *SchemaInfo {
+	return r.schemas[table]
+}
+
+// buildQuery detects the schema version, picks the matching adapter and returns a SELECT
+// that yields the adapter's columns in order, substituting defaults for missing columns
+func (r *DBReader) buildQuery(adapters []queryAdapter) (string, *SchemaInfo, error) {
+	version, compatible := r.metaVersion()
+	table := adapters[0].table
+
+	columns, err := r.tableColumns(table)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(columns) == 0 {
+		return "", nil, fmt.Errorf("table %s not found", table)
+	}
+
+	// Pick the adapter for this version. Versions newer than any adapter fall back to
+	// the newest one and are read on a best-effort basis.
+	adapter := adapters[0]
+	bestEffort := version > adapters[0].maxVersion
+	if !bestEffort {
+		for _, a := range adapters {
+			if version >= a.minVersion && version <= a.maxVersion {
+				adapter = a
+				break
+			}
+		}
+	}
+
+	info := &SchemaInfo{
+		Table:             table,
+		Version:           version,
+		CompatibleVersion: compatible,
+		Adapter:           adapter.name,
+		HostHash:          adapter.hostHash,
+		BestEffort:        bestEffort,
+	}
+
+	selects := make([]string, 0, len(adapter.columns))
+	for _, col := range adapter.columns {
+		switch {
+		case columns[col.source]:
+			selects = append(selects, col.source)
+		case columns[col.name]:
+			selects = append(selects, col.name)
+		default:
+			selects = append(selects, fmt.Sprintf("%s AS %s", col.fallback, col.name))
+			info.MissingColumns = append(info.MissingColumns, col.name)
+		}
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(selects, ", "), table)
+	r.schemas[table] = info
+	return query, info, nil
+}
+
+// metaVersion reads the version and last_compatible_version keys from the meta table.
+// Databases without a meta table report version 0.
+func (r *DBReader) metaVersion() (int, int) {
+	var version, compatible int
+
+	rows, err := r.db.Query(`SELECT key, value FROM meta WHERE key IN ('version', 'last_compatible_version')`)
+	if err != nil {
+		return 0, 0
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			continue
+		}
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "version":
+			version = v
+		case "last_compatible_version":
+			compatible = v
+		}
+	}
+
+	return version, compatible
+}
+
+// tableColumns returns the set of column names in table using PRAGMA table_info
+func (r *DBReader) tableColumns(table string) (map[string]bool, error) {
+	rows, err := r.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			colType    string
+			notNull    int
+			defaultVal interface{}
+			pk         int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultVal, &pk); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+
+	return columns, rows.Err()
+}
@@ -0,0 +1,150 @@
+package dpapi
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// ParseBackupKey parses a domain DPAPI backup private key. PVK files (as exported by
+// mimikatz or impacket), bare PRIVATEKEYBLOBs and PEM/DER PKCS#1 or PKCS#8 keys are accepted.
+func ParseBackupKey(data []byte) (*rsa.PrivateKey, error) {
+	if len(data) >= 4 && binary.LittleEndian.Uint32(data) == pvkMagic {
+		return parsePVK(data)
+	}
+	if len(data) >= 8 && data[0] == privateKeyBlobType && data[1] == privateKeyBlobVersion {
+		return parsePrivateKeyBlob(data)
+	}
+
+	der := data
+	if block, _ := pem.Decode(data); block != nil {
+		der = block.Bytes
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, errors.New("unrecognized backup key format (expected PVK, PRIVATEKEYBLOB, PEM or DER)")
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("backup key is not an RSA key")
+	}
+	return key, nil
+}
+
+// parsePVK parses an unencrypted PVK file: a fixed header followed by a PRIVATEKEYBLOB
+func parsePVK(data []byte) (*rsa.PrivateKey, error) {
+	r := &reader{data: data}
+	r.uint32() // magic
+	r.uint32() // reserved
+	r.uint32() // key type
+	encrypted := r.uint32()
+	saltLen := r.uint32()
+	keyLen := r.uint32()
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to parse PVK header: %v", r.err)
+	}
+	if encrypted != 0 {
+		return nil, errors.New("encrypted PVK files are not supported")
+	}
+
+	r.bytes(int(saltLen))
+	blob := r.bytes(int(keyLen))
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to read PVK key blob: %v", r.err)
+	}
+	return parsePrivateKeyBlob(blob)
+}
+
+// parsePrivateKeyBlob parses a CryptoAPI PRIVATEKEYBLOB (PUBLICKEYSTRUC, RSAPUBKEY "RSA2",
+// then the little-endian key components)
+func parsePrivateKeyBlob(data []byte) (*rsa.PrivateKey, error) {
+	r := &reader{data: data}
+	r.bytes(8) // PUBLICKEYSTRUC
+	magic := r.bytes(4)
+	bitLen := r.uint32()
+	pubExp := r.uint32()
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to parse PRIVATEKEYBLOB header: %v", r.err)
+	}
+	if !bytes.Equal(magic, []byte("RSA2")) {
+		return nil, errors.New("PRIVATEKEYBLOB is not an RSA private key")
+	}
+
+	n := int(bitLen / 8)
+	half := int(bitLen / 16)
+	readInt := func(size int) *big.Int {
+		return new(big.Int).SetBytes(reverseBytes(r.bytes(size)))
+	}
+	modulus := readInt(n)
+	p := readInt(half)
+	q := readInt(half)
+	readInt(half) // exponent1
+	readInt(half) // exponent2
+	readInt(half) // coefficient
+	d := readInt(n)
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to read RSA key components: %v", r.err)
+	}
+
+	key := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{N: modulus, E: int(pubExp)},
+		D:         d,
+		Primes:    []*big.Int{p, q},
+	}
+	if err := key.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid RSA key: %v", err)
+	}
+	key.Precompute()
+	return key, nil
+}
+
+// DecryptWithBackupKey recovers the masterkey from the file's domain key section with the
+// domain DPAPI backup private key
+func (f *MasterKeyFile) DecryptWithBackupKey(key *rsa.PrivateKey) ([]byte, error) {
+	if len(f.DomainKey) == 0 {
+		return nil, errors.New("masterkey file has no domain key section")
+	}
+
+	r := &reader{data: f.DomainKey}
+	r.uint32() // version
+	secretLen := r.uint32()
+	r.uint32() // access check length
+	r.guid()   // backup key GUID
+	secret := r.bytes(int(secretLen))
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to parse domain key section: %v", r.err)
+	}
+
+	// The secret is stored little-endian; RSA expects big-endian
+	cleartext, err := rsa.DecryptPKCS1v15(nil, key, reverseBytes(secret))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt domain key (wrong backup key?): %v", err)
+	}
+
+	// cleartext is cbMasterKey (4) || cbSuppKey (4) || masterkey || supplemental key
+	cr := &reader{data: cleartext}
+	masterKeyLen := cr.uint32()
+	cr.uint32() // supplemental key length
+	masterKey := cr.bytes(int(masterKeyLen))
+	if cr.err != nil {
+		return nil, fmt.Errorf("malformed domain key cleartext: %v", cr.err)
+	}
+	return append([]byte(nil), masterKey...), nil
+}
+
+// reverseBytes returns a reversed copy of b
+func reverseBytes(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[len(b)-1-i] = b[i]
+	}
+	return out
+}
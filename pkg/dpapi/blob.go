@@ -0,0 +1,156 @@
+package dpapi
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+)
+
+// reader is a small little-endian cursor over DPAPI structures
+type reader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *reader) uint32() uint32 {
+	if r.err != nil || r.pos+4 > len(r.data) {
+		r.err = errors.New("unexpected end of data")
+		return 0
+	}
+	v := binary.LittleEndian.Uint32(r.data[r.pos:])
+	r.pos += 4
+	return v
+}
+
+func (r *reader) uint64() uint64 {
+	if r.err != nil || r.pos+8 > len(r.data) {
+		r.err = errors.New("unexpected end of data")
+		return 0
+	}
+	v := binary.LittleEndian.Uint64(r.data[r.pos:])
+	r.pos += 8
+	return v
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || r.pos+n > len(r.data) {
+		r.err = errors.New("unexpected end of data")
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *reader) guid() GUID {
+	var g GUID
+	copy(g[:], r.bytes(16))
+	return g
+}
+
+// lengthPrefixed reads a uint32 length followed by that many bytes
+func (r *reader) lengthPrefixed() []byte {
+	return r.bytes(int(r.uint32()))
+}
+
+// ParseBlob parses a DPAPI_BLOB
+func ParseBlob(data []byte) (*Blob, error) {
+	if !bytes.HasPrefix(data, BlobPrefix) {
+		return nil, errors.New("data is not a DPAPI blob")
+	}
+
+	r := &reader{data: data}
+	b := &Blob{}
+	b.Version = r.uint32()
+	b.Provider = r.guid()
+	signStart := r.pos
+	b.MasterKeyVersion = r.uint32()
+	b.MasterKey = r.guid()
+	b.Flags = r.uint32()
+	b.Description = decodeUTF16(r.lengthPrefixed())
+	b.CryptAlg = r.uint32()
+	b.CryptAlgLen = r.uint32()
+	b.Salt = r.lengthPrefixed()
+	b.HMACKey = r.lengthPrefixed()
+	b.HashAlg = r.uint32()
+	b.HashAlgLen = r.uint32()
+	b.HMAC2Key = r.lengthPrefixed()
+	b.Data = r.lengthPrefixed()
+	signEnd := r.pos
+	b.Sign = r.lengthPrefixed()
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to parse DPAPI blob: %v", r.err)
+	}
+
+	b.signed = data[signStart:signEnd]
+	return b, nil
+}
+
+// Decrypt decrypts the blob with a decrypted 64 byte masterkey
+func (b *Blob) Decrypt(masterKey, entropy []byte) ([]byte, error) {
+	keyHash := sha1.Sum(masterKey)
+	return b.DecryptWithKeyHash(keyHash[:], entropy)
+}
+
+// DecryptWithKeyHash decrypts the blob with the SHA1 of the masterkey and verifies its signature.
+// The session key and signature are keyed with the SHA1 of the masterkey using one of two
+// constructions: Windows XP's, which mimikatz still uses for SHA1 blobs, or the real HMAC of
+// Vista and later. Both are tried, the likelier one for the blob's hash algorithm first.
+func (b *Blob) DecryptWithKeyHash(keyHash, entropy []byte) ([]byte, error) {
+	h, err := newHash(b.HashAlg)
+	if err != nil {
+		return nil, fmt.Errorf("hash algorithm 0x%x: %v", b.HashAlg, err)
+	}
+	cryptInfo, ok := cryptAlgs[b.CryptAlg]
+	if !ok {
+		return nil, fmt.Errorf("cipher algorithm 0x%x: %v", b.CryptAlg, ErrUnsupportedAlg)
+	}
+
+	macs := []macFunc{hmacSum, hmacSumXP}
+	if b.HashAlg == CALG_SHA1 {
+		macs = []macFunc{hmacSumXP, hmacSum}
+	}
+
+	for _, mac := range macs {
+		// signature = MAC(SHA1(masterkey), HMAC2 key || entropy || everything from the masterkey version to the data)
+		if !hmac.Equal(mac(h, keyHash, b.HMAC2Key, entropy, b.signed), b.Sign) {
+			continue
+		}
+
+		// session key = MAC(SHA1(masterkey), salt || entropy)
+		sessionKey := mac(h, keyHash, b.Salt, entropy)
+		derived, err := deriveSessionKey(sessionKey, b.HashAlg, b.CryptAlg)
+		if err != nil {
+			return nil, err
+		}
+
+		plaintext, err := decryptCBC(b.CryptAlg, derived, nil, b.Data)
+		if err != nil {
+			return nil, err
+		}
+		return unpad(plaintext, cryptInfo.blockSize)
+	}
+
+	return nil, ErrBadSignature
+}
+
+func utf16Encode(s string) []uint16 {
+	return utf16.Encode([]rune(s))
+}
+
+func decodeUTF16(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[i*2:])
+	}
+	// strip the terminating NUL
+	for len(u) > 0 && u[len(u)-1] == 0 {
+		u = u[:len(u)-1]
+	}
+	return string(utf16.Decode(u))
+}
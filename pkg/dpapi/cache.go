@@ -0,0 +1,103 @@
+package dpapi
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// NewMasterKeyCache returns an empty masterkey cache
+func NewMasterKeyCache() *MasterKeyCache {
+	return &MasterKeyCache{keys: make(map[GUID][]byte)}
+}
+
+// Add stores a masterkey for guid. key is either the decrypted masterkey or its 20 byte SHA1,
+// which is what tools such as mimikatz print as "sha1".
+func (c *MasterKeyCache) Add(guid GUID, key []byte) {
+	if len(key) == sha1.Size {
+		c.keys[guid] = append([]byte(nil), key...)
+		return
+	}
+	sum := sha1.Sum(key)
+	c.keys[guid] = sum[:]
+}
+
+// KeyHash returns the SHA1 of the masterkey for guid
+func (c *MasterKeyCache) KeyHash(guid GUID) ([]byte, bool) {
+	key, ok := c.keys[guid]
+	return key, ok
+}
+
+// Len returns the number of cached masterkeys
+func (c *MasterKeyCache) Len() int {
+	return len(c.keys)
+}
+
+// GUIDs returns the cached masterkey GUIDs in sorted order
+func (c *MasterKeyCache) GUIDs() []GUID {
+	guids := make([]GUID, 0, len(c.keys))
+	for g := range c.keys {
+		guids = append(guids, g)
+	}
+	sort.Slice(guids, func(i, j int) bool {
+		return guids[i].String() < guids[j].String()
+	})
+	return guids
+}
+
+// Merge copies every key of other into the cache
+func (c *MasterKeyCache) Merge(other *MasterKeyCache) {
+	for g, k := range other.keys {
+		c.keys[g] = k
+	}
+}
+
+// Unprotect is an offline CryptUnprotectData: it parses a DPAPI blob and decrypts it with
+// the cached masterkey it names
+func (c *MasterKeyCache) Unprotect(data []byte) ([]byte, error) {
+	return c.UnprotectWithEntropy(data, nil)
+}
+
+// UnprotectWithEntropy is Unprotect with optional entropy
+func (c *MasterKeyCache) UnprotectWithEntropy(data, entropy []byte) ([]byte, error) {
+	blob, err := ParseBlob(data)
+	if err != nil {
+		return nil, err
+	}
+
+	keyHash, ok := c.keys[blob.MasterKey]
+	if !ok {
+		return nil, fmt.Errorf("%w: {%s}", ErrNoMasterKey, blob.MasterKey)
+	}
+
+	return blob.DecryptWithKeyHash(keyHash, entropy)
+}
+
+// ParseMasterKeys parses a comma-separated list of "{GUID}:hexkey" pairs into a cache
+func ParseMasterKeys(s string) (*MasterKeyCache, error) {
+	cache := NewMasterKeyCache()
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		guidStr, keyStr, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, fmt.Errorf("expected {GUID}:key, got %q", pair)
+		}
+		guid, err := ParseGUID(guidStr)
+		if err != nil {
+			return nil, err
+		}
+		key, err := hex.DecodeString(strings.ReplaceAll(strings.TrimSpace(keyStr), "\\x", ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid masterkey for {%s}: %v", guid, err)
+		}
+
+		cache.Add(guid, key)
+	}
+	return cache, nil
+}
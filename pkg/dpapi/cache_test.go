@@ -0,0 +1,216 @@
+package dpapi
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"strings"
+	"testing"
+)
+
+func appendLengthPrefixed(b, data []byte) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(data)))
+	return append(b, data...)
+}
+
+func pad(data []byte, blockSize int) []byte {
+	n := blockSize - len(data)%blockSize
+	return append(append([]byte(nil), data...), bytes.Repeat([]byte{byte(n)}, n)...)
+}
+
+// blobAlgs are the algorithms and signing construction a blob is built with
+type blobAlgs struct {
+	hashAlg, hashLen   uint32
+	hash               func() hash.Hash
+	cryptAlg, cryptLen uint32
+	keyLen             int
+	newCipher          func(key []byte) (cipher.Block, error)
+	mac                func(h func() hash.Hash, key []byte, data ...[]byte) []byte
+}
+
+var (
+	// Windows 10 and 11 protect with AES-256 and SHA-512 and sign with a real HMAC
+	algsWindows10 = blobAlgs{CALG_SHA_512, 512, sha512.New, CALG_AES_256, 256, 32, aes.NewCipher, realHMAC}
+	// Windows XP protected with 3DES and SHA1 and its own keyed hash
+	algsWindowsXP = blobAlgs{CALG_SHA1, 160, sha1.New, CALG_3DES, 192, 24, des.NewTripleDESCipher, xpHMAC}
+)
+
+func realHMAC(h func() hash.Hash, key []byte, data ...[]byte) []byte {
+	mac := hmac.New(h, key)
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
+
+// xpHMAC is the keyed hash of Windows XP, whose inner hash only covers the first part
+func xpHMAC(h func() hash.Hash, key []byte, data ...[]byte) []byte {
+	keyBlock := make([]byte, h().BlockSize())
+	copy(keyBlock, key)
+	inner, outer := h(), h()
+	for _, b := range keyBlock {
+		inner.Write([]byte{b ^ 0x36})
+		outer.Write([]byte{b ^ 0x5c})
+	}
+	inner.Write(data[0])
+	outer.Write(inner.Sum(nil))
+	for _, d := range data[1:] {
+		outer.Write(d)
+	}
+	return outer.Sum(nil)
+}
+
+// protect builds a blob as CryptProtectData does
+func protect(algs blobAlgs, masterKey []byte, guid GUID, plaintext, entropy []byte) []byte {
+	keyHash := sha1.Sum(masterKey)
+	salt := bytes.Repeat([]byte{0x5a}, 32)
+	hmac2Key := bytes.Repeat([]byte{0xa5}, 64)
+
+	// CryptDeriveKey stretches a session key shorter than the cipher key through ipad and opad
+	key := algs.mac(algs.hash, keyHash[:], salt, entropy)
+	if len(key) < algs.keyLen {
+		keyBlock := make([]byte, algs.hash().BlockSize())
+		copy(keyBlock, key)
+		ipad, opad := algs.hash(), algs.hash()
+		for _, b := range keyBlock {
+			ipad.Write([]byte{b ^ 0x36})
+			opad.Write([]byte{b ^ 0x5c})
+		}
+		key = append(ipad.Sum(nil), opad.Sum(nil)...)
+	}
+	block, err := algs.newCipher(key[:algs.keyLen])
+	if err != nil {
+		panic(err)
+	}
+	padded := pad(plaintext, block.BlockSize())
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, make([]byte, block.BlockSize())).CryptBlocks(ciphertext, padded)
+
+	var body []byte
+	body = binary.LittleEndian.AppendUint32(body, 1)
+	body = append(body, guid[:]...)
+	body = binary.LittleEndian.AppendUint32(body, 0)
+	body = appendLengthPrefixed(body, []byte{0, 0})
+	body = binary.LittleEndian.AppendUint32(body, algs.cryptAlg)
+	body = binary.LittleEndian.AppendUint32(body, algs.cryptLen)
+	body = appendLengthPrefixed(body, salt)
+	body = appendLengthPrefixed(body, nil)
+	body = binary.LittleEndian.AppendUint32(body, algs.hashAlg)
+	body = binary.LittleEndian.AppendUint32(body, algs.hashLen)
+	body = appendLengthPrefixed(body, hmac2Key)
+	body = appendLengthPrefixed(body, ciphertext)
+
+	blob := append(append([]byte(nil), BlobPrefix...), body...)
+	return appendLengthPrefixed(blob, algs.mac(algs.hash, keyHash[:], hmac2Key, entropy, body))
+}
+
+func TestParseMasterKeys(t *testing.T) {
+	key := strings.Repeat("ab", masterKeyLength)
+	sum := sha1.Sum(bytes.Repeat([]byte{0xab}, masterKeyLength))
+	keyHash := hex.EncodeToString(sum[:])
+	guid1 := "12345678-1234-5678-9abc-def012345678"
+	guid2 := "87654321-4321-8765-cba9-876543210fed"
+
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{"masterkey", "{" + guid1 + "}:" + key, 1, false},
+		{"masterkey SHA1", guid1 + ":" + keyHash, 1, false},
+		{"escaped hex", guid1 + ":" + `\x` + strings.Join(strings.SplitAfter(key, "ab")[:masterKeyLength], `\x`), 1, false},
+		{"list with blanks", " {" + guid1 + "}:" + key + ", ,{" + guid2 + "}:" + keyHash + " ", 2, false},
+		{"empty", "", 0, false},
+		{"missing key", guid1, 0, true},
+		{"bad GUID", "{1234}:" + key, 0, true},
+		{"bad hex", guid1 + ":zz", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache, err := ParseMasterKeys(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if cache.Len() != tt.want {
+				t.Fatalf("got %d keys, want %d", cache.Len(), tt.want)
+			}
+			guid, _ := ParseGUID(guid1)
+			if got, ok := cache.KeyHash(guid); tt.want > 0 && (!ok || !bytes.Equal(got, sum[:])) {
+				t.Errorf("key hash for {%s}: got %x, want %x", guid1, got, sum)
+			}
+		})
+	}
+}
+
+func TestUnprotect(t *testing.T) {
+	masterKey := bytes.Repeat([]byte{0x42}, masterKeyLength)
+	guid, _ := ParseGUID("12345678-1234-5678-9abc-def012345678")
+	other, _ := ParseGUID("87654321-4321-8765-cba9-876543210fed")
+	plaintext := []byte("os_crypt key material")
+	entropy := []byte("entropy")
+
+	blob := protect(algsWindows10, masterKey, guid, plaintext, nil)
+	tampered := append([]byte(nil), blob...)
+	tampered[len(tampered)-100] ^= 1
+
+	// A real HMAC over a SHA1 blob, and the XP construction over a SHA-512 one
+	sha1HMAC := algsWindowsXP
+	sha1HMAC.mac = realHMAC
+	sha512XP := algsWindows10
+	sha512XP.mac = xpHMAC
+
+	cache := NewMasterKeyCache()
+	cache.Add(guid, masterKey)
+	wrongKey := NewMasterKeyCache()
+	wrongKey.Add(guid, bytes.Repeat([]byte{0x43}, masterKeyLength))
+	otherGUID := NewMasterKeyCache()
+	otherGUID.Add(other, masterKey)
+
+	tests := []struct {
+		name    string
+		cache   *MasterKeyCache
+		blob    []byte
+		entropy []byte
+		wantErr error
+	}{
+		{"Windows 10 blob", cache, blob, nil, nil},
+		{"Windows 10 blob with entropy", cache, protect(algsWindows10, masterKey, guid, plaintext, entropy), entropy, nil},
+		{"Windows XP blob", cache, protect(algsWindowsXP, masterKey, guid, plaintext, nil), nil, nil},
+		{"Windows XP blob with entropy", cache, protect(algsWindowsXP, masterKey, guid, plaintext, entropy), entropy, nil},
+		{"SHA1 blob with a real HMAC", cache, protect(sha1HMAC, masterKey, guid, plaintext, entropy), entropy, nil},
+		{"SHA-512 blob with the XP construction", cache, protect(sha512XP, masterKey, guid, plaintext, entropy), entropy, nil},
+		{"missing entropy", cache, protect(algsWindows10, masterKey, guid, plaintext, entropy), nil, ErrBadSignature},
+		{"wrong masterkey", wrongKey, blob, nil, ErrBadSignature},
+		{"tampered blob", cache, tampered, nil, ErrBadSignature},
+		{"masterkey not cached", otherGUID, blob, nil, ErrNoMasterKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cache.UnprotectWithEntropy(tt.blob, tt.entropy)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UnprotectWithEntropy: %v", err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Errorf("got %q, want %q", got, plaintext)
+			}
+		})
+	}
+}
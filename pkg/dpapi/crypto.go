@@ -0,0 +1,203 @@
+package dpapi
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"hash"
+)
+
+// newHash returns the hash constructor for a DPAPI hash ALG_ID
+func newHash(alg uint32) (func() hash.Hash, error) {
+	switch alg {
+	case CALG_SHA1, CALG_HMAC:
+		return sha1.New, nil
+	case CALG_SHA_256:
+		return sha256.New, nil
+	case CALG_SHA_384:
+		return sha512.New384, nil
+	case CALG_SHA_512:
+		return sha512.New, nil
+	default:
+		return nil, ErrUnsupportedAlg
+	}
+}
+
+// newBlockCipher returns the block cipher for a DPAPI cipher ALG_ID
+func newBlockCipher(alg uint32, key []byte) (cipher.Block, error) {
+	info, ok := cryptAlgs[alg]
+	if !ok {
+		return nil, ErrUnsupportedAlg
+	}
+	if len(key) < info.keyLen {
+		return nil, errors.New("derived key is too short")
+	}
+	key = key[:info.keyLen]
+
+	if alg == CALG_3DES {
+		return des.NewTripleDESCipher(key)
+	}
+	return aes.NewCipher(key)
+}
+
+// decryptCBC decrypts data with a zero IV, as DPAPI does, without removing padding
+func decryptCBC(alg uint32, key, iv, data []byte) ([]byte, error) {
+	block, err := newBlockCipher(alg, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 || len(data)%block.BlockSize() != 0 {
+		return nil, errors.New("ciphertext is not a multiple of the block size")
+	}
+	if iv == nil {
+		iv = make([]byte, block.BlockSize())
+	}
+
+	out := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv[:block.BlockSize()]).CryptBlocks(out, data)
+	return out, nil
+}
+
+// unpad removes PKCS#7 style padding
+func unpad(data []byte, blockSize int) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty plaintext")
+	}
+	n := int(data[len(data)-1])
+	if n == 0 || n > blockSize || n > len(data) {
+		return nil, errors.New("bad padding")
+	}
+	return data[:len(data)-n], nil
+}
+
+// macFunc keys a hash over the concatenation of data
+type macFunc func(h func() hash.Hash, key []byte, data ...[]byte) []byte
+
+// hmacSum computes HMAC(key, data...) with the given hash
+func hmacSum(h func() hash.Hash, key []byte, data ...[]byte) []byte {
+	mac := hmac.New(h, key)
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
+
+// hmacSumXP computes the keyed hash of Windows 2000 and XP, which only puts the first data
+// part in the inner hash: H(key ^ opad || H(key ^ ipad || data[0]) || data[1:]...)
+func hmacSumXP(h func() hash.Hash, key []byte, data ...[]byte) []byte {
+	blockSize := h().BlockSize()
+	padded := make([]byte, blockSize)
+	copy(padded, key)
+	ipad := make([]byte, blockSize)
+	opad := make([]byte, blockSize)
+	for i := range padded {
+		ipad[i] = padded[i] ^ 0x36
+		opad[i] = padded[i] ^ 0x5c
+	}
+
+	inner := h()
+	inner.Write(ipad)
+	if len(data) > 0 {
+		inner.Write(data[0])
+		data = data[1:]
+	}
+
+	outer := h()
+	outer.Write(opad)
+	outer.Write(inner.Sum(nil))
+	for _, d := range data {
+		outer.Write(d)
+	}
+	return outer.Sum(nil)
+}
+
+// deriveSessionKey expands a session key to the cipher key length, mirroring CryptDeriveKey
+func deriveSessionKey(sessionKey []byte, hashAlg, cryptAlg uint32) ([]byte, error) {
+	h, err := newHash(hashAlg)
+	if err != nil {
+		return nil, err
+	}
+	hashInfo := hashAlgs[hashAlg]
+	cryptInfo, ok := cryptAlgs[cryptAlg]
+	if !ok {
+		return nil, ErrUnsupportedAlg
+	}
+
+	derived := sessionKey
+	if len(derived) > hashInfo.blockSize {
+		derived = hmacSum(h, derived)
+	}
+	if len(derived) >= cryptInfo.keyLen {
+		return derived, nil
+	}
+
+	// Too short for the cipher: hash the key XOR'ed with ipad and opad and concatenate
+	padded := make([]byte, hashInfo.blockSize)
+	copy(padded, derived)
+	ipad := make([]byte, hashInfo.blockSize)
+	opad := make([]byte, hashInfo.blockSize)
+	for i := range padded {
+		ipad[i] = padded[i] ^ 0x36
+		opad[i] = padded[i] ^ 0x5c
+	}
+
+	hi := h()
+	hi.Write(ipad)
+	ho := h()
+	ho.Write(opad)
+	return append(hi.Sum(nil), ho.Sum(nil)...), nil
+}
+
+// pbkdf2DPAPI is the PBKDF2 variant masterkeys are encrypted with: each iteration's HMAC is
+// taken over the running XOR of the previous ones rather than over the previous HMAC
+func pbkdf2DPAPI(h func() hash.Hash, password, salt []byte, iterations, keyLen int) []byte {
+	mac := hmac.New(h, password)
+	var out []byte
+	for block := uint32(1); len(out) < keyLen; block++ {
+		mac.Reset()
+		mac.Write(salt)
+		mac.Write(binary.BigEndian.AppendUint32(nil, block))
+		t := mac.Sum(nil)
+
+		for i := 1; i < iterations; i++ {
+			mac.Reset()
+			mac.Write(t)
+			u := mac.Sum(nil)
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		out = append(out, t...)
+	}
+	return out[:keyLen]
+}
+
+// pbkdf2 implements PBKDF2 (RFC 8018) with an arbitrary HMAC hash
+func pbkdf2(h func() hash.Hash, password, salt []byte, iterations, keyLen int) []byte {
+	mac := hmac.New(h, password)
+	var out []byte
+	for block := uint32(1); len(out) < keyLen; block++ {
+		mac.Reset()
+		mac.Write(salt)
+		mac.Write(binary.BigEndian.AppendUint32(nil, block))
+		u := mac.Sum(nil)
+
+		t := append([]byte(nil), u...)
+		for i := 1; i < iterations; i++ {
+			mac.Reset()
+			mac.Write(u)
+			u = mac.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		out = append(out, t...)
+	}
+	return out[:keyLen]
+}
@@ -0,0 +1,255 @@
+package dpapi
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ParseMasterKeyFile parses a masterkey file
+func ParseMasterKeyFile(data []byte) (*MasterKeyFile, error) {
+	if len(data) < masterKeyFileHeaderSize {
+		return nil, errors.New("masterkey file is too short")
+	}
+
+	r := &reader{data: data}
+	f := &MasterKeyFile{}
+	f.Version = r.uint32()
+	r.uint32() // reserved
+	r.uint32() // reserved
+	guid, err := ParseGUID(decodeUTF16(r.bytes(72)))
+	if err != nil {
+		return nil, fmt.Errorf("bad masterkey GUID: %v", err)
+	}
+	f.GUID = guid
+	r.uint32() // unused
+	r.uint32() // unused
+	f.Policy = r.uint32()
+	masterKeyLen := r.uint64()
+	backupKeyLen := r.uint64()
+	credHistLen := r.uint64()
+	domainKeyLen := r.uint64()
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to parse masterkey file header: %v", r.err)
+	}
+
+	sections := make([][]byte, 4)
+	for i, n := range []uint64{masterKeyLen, backupKeyLen, credHistLen, domainKeyLen} {
+		if n > uint64(len(data)) {
+			return nil, errors.New("masterkey section length out of range")
+		}
+		sections[i] = r.bytes(int(n))
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to read masterkey sections: %v", r.err)
+	}
+
+	if len(sections[0]) > 0 {
+		if f.MasterKey, err = parseMasterKey(sections[0]); err != nil {
+			return nil, fmt.Errorf("bad masterkey section: %v", err)
+		}
+	}
+	if len(sections[1]) > 0 {
+		if f.BackupKey, err = parseMasterKey(sections[1]); err != nil {
+			return nil, fmt.Errorf("bad backup key section: %v", err)
+		}
+	}
+	f.CredHist = sections[2]
+	f.DomainKey = sections[3]
+
+	return f, nil
+}
+
+func parseMasterKey(data []byte) (*MasterKey, error) {
+	r := &reader{data: data}
+	mk := &MasterKey{}
+	mk.Version = r.uint32()
+	mk.Salt = r.bytes(16)
+	mk.Iterations = r.uint32()
+	mk.HashAlg = r.uint32()
+	mk.CryptAlg = r.uint32()
+	if r.err != nil {
+		return nil, r.err
+	}
+	mk.Data = data[r.pos:]
+	return mk, nil
+}
+
+// Decrypt decrypts the masterkey section with a pre-key and verifies its HMAC.
+// The cipher key and IV come from DPAPI's PBKDF2 variant over the pre-key and salt, using the
+// section's hash algorithm.
+func (mk *MasterKey) Decrypt(preKey []byte) ([]byte, error) {
+	h, err := newHash(mk.HashAlg)
+	if err != nil {
+		return nil, fmt.Errorf("hash algorithm 0x%x: %v", mk.HashAlg, err)
+	}
+	cryptInfo, ok := cryptAlgs[mk.CryptAlg]
+	if !ok {
+		return nil, fmt.Errorf("cipher algorithm 0x%x: %v", mk.CryptAlg, ErrUnsupportedAlg)
+	}
+
+	derived := pbkdf2DPAPI(h, preKey, mk.Salt, int(mk.Iterations), cryptInfo.keyLen+cryptInfo.blockSize)
+	cleartext, err := decryptCBC(mk.CryptAlg, derived[:cryptInfo.keyLen], derived[cryptInfo.keyLen:], mk.Data)
+	if err != nil {
+		return nil, err
+	}
+	if len(cleartext) < 16+masterKeyHMACCheckLength+masterKeyLength {
+		return nil, errors.New("decrypted masterkey is too short")
+	}
+
+	// cleartext is HMAC salt (16) || HMAC || ... || key (64)
+	key := cleartext[len(cleartext)-masterKeyLength:]
+	hmacSalt := cleartext[:16]
+	storedHMAC := cleartext[16 : 16+masterKeyHMACCheckLength]
+
+	hmacKey := hmacSum(h, preKey, hmacSalt)
+	calculated := hmacSum(h, hmacKey, key)
+	if !hmac.Equal(calculated[:masterKeyHMACCheckLength], storedHMAC) {
+		return nil, ErrBadMasterKey
+	}
+
+	return append([]byte(nil), key...), nil
+}
+
+// Decrypt tries each pre-key against the file's masterkey section
+func (f *MasterKeyFile) Decrypt(preKeys [][]byte) ([]byte, error) {
+	if f.MasterKey == nil {
+		return nil, errors.New("masterkey file has no masterkey section")
+	}
+
+	lastErr := ErrBadMasterKey
+	for _, preKey := range preKeys {
+		key, err := f.MasterKey.Decrypt(preKey)
+		if err == nil {
+			return key, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// PreKeys derives every masterkey pre-key the credentials allow:
+// HMAC-SHA1(SHA1(password), SID) for local accounts, HMAC-SHA1(NT hash, SID) for domain
+// accounts, and the PBKDF2-SHA256 variant used for domain accounts on Windows 10 and later.
+// The DPAPI_SYSTEM keys are used as they are.
+func (c Credentials) PreKeys() [][]byte {
+	sid := utf16LE(c.SID + "\x00")
+
+	var sha1Hashes, ntHashes [][]byte
+	if c.Password != "" {
+		pw := utf16LE(c.Password)
+		sum := sha1.Sum(pw)
+		nt := md4Sum(pw)
+		sha1Hashes = append(sha1Hashes, sum[:])
+		ntHashes = append(ntHashes, nt[:])
+	}
+	if len(c.SHA1) > 0 {
+		sha1Hashes = append(sha1Hashes, c.SHA1)
+	}
+	if len(c.NTHash) > 0 {
+		ntHashes = append(ntHashes, c.NTHash)
+	}
+
+	var preKeys [][]byte
+	if c.SystemKey != nil {
+		preKeys = append(preKeys, c.SystemKey.PreKeys()...)
+	}
+	for _, h := range sha1Hashes {
+		preKeys = append(preKeys, hmacSum(sha1.New, h, sid))
+	}
+	for _, h := range ntHashes {
+		preKeys = append(preKeys, hmacSum(sha1.New, h, sid))
+
+		// Protected domain accounts
+		sidNoNull := utf16LE(c.SID)
+		tmp := pbkdf2(sha256.New, h, sidNoNull, 10000, 32)
+		tmp = pbkdf2(sha256.New, tmp, sidNoNull, 1, 32)[:16]
+		preKeys = append(preKeys, hmacSum(sha1.New, tmp, sid))
+	}
+
+	return preKeys
+}
+
+// LoadMasterKeys decrypts every masterkey file in a Protect\<SID> directory with the given
+// credentials. If the credentials have no SID, it is taken from the directory name. A Protect
+// directory holding several SID directories is walked as a whole, which is how a domain
+// backup key is typically used, and so is the User directory of Protect\S-1-5-18.
+// Files that cannot be decrypted are reported in the returned error slice.
+func LoadMasterKeys(dir string, creds Credentials) (*MasterKeyCache, []error) {
+	cache := NewMasterKeyCache()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return cache, []error{fmt.Errorf("failed to read masterkey directory %s: %v", dir, err)}
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() && (strings.HasPrefix(entry.Name(), "S-1-") || entry.Name() == "User") {
+			sub, subErrs := LoadMasterKeys(filepath.Join(dir, entry.Name()), creds)
+			cache.Merge(sub)
+			errs = append(errs, subErrs...)
+		}
+	}
+
+	if creds.SID == "" {
+		creds.SID = filepath.Base(dir)
+	}
+	preKeys := creds.PreKeys()
+
+	for _, entry := range entries {
+		if entry.IsDir() || !isGUIDName(entry.Name()) {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", path, err))
+			continue
+		}
+
+		mkf, err := ParseMasterKeyFile(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", path, err))
+			continue
+		}
+
+		key, err := mkf.Decrypt(preKeys)
+		if err != nil && creds.BackupKey != nil && len(mkf.DomainKey) > 0 {
+			key, err = mkf.DecryptWithBackupKey(creds.BackupKey)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", path, err))
+			continue
+		}
+
+		cache.Add(mkf.GUID, key)
+	}
+
+	return cache, errs
+}
+
+// isGUIDName reports whether a file name is a bare GUID, as masterkey files are named
+func isGUIDName(name string) bool {
+	if len(name) != 36 || strings.Count(name, "-") != 4 {
+		return false
+	}
+	_, err := ParseGUID(name)
+	return err == nil
+}
+
+func utf16LE(s string) []byte {
+	var b bytes.Buffer
+	for _, u := range utf16Encode(s) {
+		b.WriteByte(byte(u))
+		b.WriteByte(byte(u >> 8))
+	}
+	return b.Bytes()
+}
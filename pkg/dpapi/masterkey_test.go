@@ -0,0 +1,179 @@
+package dpapi
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"os"
+	"path/filepath"
+	"testing"
+
+	xpbkdf2 "golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	testSID      = "S-1-5-21-1-2-3-1001"
+	testPassword = "Passw0rd!"
+	testGUID     = "12345678-1234-5678-9abc-def012345678"
+)
+
+// deriveKeyDPAPI is the PBKDF2 variant Windows encrypts masterkeys with, where each HMAC
+// covers the XOR of all previous ones
+func deriveKeyDPAPI(h func() hash.Hash, password, salt []byte, iterations, keyLen int) []byte {
+	var out []byte
+	for i := uint32(1); len(out) < keyLen; i++ {
+		derived := realHMAC(h, password, salt, binary.BigEndian.AppendUint32(nil, i))
+		for r := 1; r < iterations; r++ {
+			actual := realHMAC(h, password, derived)
+			for j := range derived {
+				derived[j] ^= actual[j]
+			}
+		}
+		out = append(out, derived...)
+	}
+	return out[:keyLen]
+}
+
+// writeMasterKeyFile writes a masterkey file holding key encrypted with preKey as Windows does
+func writeMasterKeyFile(t *testing.T, dir, guid string, algs blobAlgs, preKey, key []byte) {
+	t.Helper()
+
+	salt := bytes.Repeat([]byte{3}, 16)
+	hmacSalt := bytes.Repeat([]byte{5}, 16)
+	mac := realHMAC(algs.hash, realHMAC(algs.hash, preKey, hmacSalt), key)
+	cleartext := append(append([]byte(nil), hmacSalt...), mac...)
+
+	block, err := algs.newCipher(make([]byte, algs.keyLen))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blockSize := block.BlockSize()
+	// The key sits at the end, after any filler that aligns the cleartext to the block size
+	cleartext = append(cleartext, make([]byte, (blockSize-len(cleartext)%blockSize)%blockSize)...)
+	cleartext = append(cleartext, key...)
+
+	derived := deriveKeyDPAPI(algs.hash, preKey, salt, 8000, algs.keyLen+blockSize)
+	if block, err = algs.newCipher(derived[:algs.keyLen]); err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := make([]byte, len(cleartext))
+	cipher.NewCBCEncrypter(block, derived[algs.keyLen:]).CryptBlocks(ciphertext, cleartext)
+
+	var section []byte
+	section = binary.LittleEndian.AppendUint32(section, 2)
+	section = append(section, salt...)
+	section = binary.LittleEndian.AppendUint32(section, 8000)
+	section = binary.LittleEndian.AppendUint32(section, algs.hashAlg)
+	section = binary.LittleEndian.AppendUint32(section, algs.cryptAlg)
+	section = append(section, ciphertext...)
+
+	var file []byte
+	file = binary.LittleEndian.AppendUint32(file, 2)
+	file = append(file, make([]byte, 8)...)
+	file = append(file, utf16LE(guid)...)
+	file = append(file, make([]byte, 8)...)
+	file = binary.LittleEndian.AppendUint32(file, 6)
+	file = binary.LittleEndian.AppendUint64(file, uint64(len(section)))
+	file = append(file, make([]byte, 24)...)
+	file = append(file, section...)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, guid), file, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Preferred"), make([]byte, 24), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPBKDF2(t *testing.T) {
+	password, salt := []byte("password"), []byte("salt")
+
+	// RFC 6070 PBKDF2-HMAC-SHA1 vectors. The DPAPI variant only departs from PBKDF2 from the
+	// third iteration on.
+	tests := []struct {
+		name       string
+		derive     func(h func() hash.Hash, password, salt []byte, iterations, keyLen int) []byte
+		iterations int
+		want       string
+	}{
+		{"PBKDF2 1 iteration", pbkdf2, 1, "0c60c80f961f0e71f3a9b524af6012062fe037a6"},
+		{"PBKDF2 2 iterations", pbkdf2, 2, "ea6c014dc72d6f8ccd1ed92ace1d41f0d8de8957"},
+		{"PBKDF2 4096 iterations", pbkdf2, 4096, "4b007901b765489abead49d926f721d065a429c1"},
+		{"DPAPI 1 iteration", pbkdf2DPAPI, 1, "0c60c80f961f0e71f3a9b524af6012062fe037a6"},
+		{"DPAPI 2 iterations", pbkdf2DPAPI, 2, "ea6c014dc72d6f8ccd1ed92ace1d41f0d8de8957"},
+		{"DPAPI 4096 iterations", pbkdf2DPAPI, 4096, hex.EncodeToString(deriveKeyDPAPI(sha1.New, password, salt, 4096, 20))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hex.EncodeToString(tt.derive(sha1.New, password, salt, tt.iterations, 20)); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if bytes.Equal(pbkdf2DPAPI(sha1.New, password, salt, 3, 20), pbkdf2(sha1.New, password, salt, 3, 20)) {
+		t.Error("DPAPI variant matches PBKDF2 at 3 iterations")
+	}
+}
+
+func TestLoadMasterKeys(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, masterKeyLength)
+	sid := utf16LE(testSID + "\x00")
+	passwordSHA1 := sha1.Sum(utf16LE(testPassword))
+	ntHash := md4Sum(utf16LE(testPassword))
+	systemKey := &SystemKey{Machine: bytes.Repeat([]byte{0x11}, systemKeyLength), User: bytes.Repeat([]byte{0x22}, systemKeyLength)}
+
+	// Protected Users pre-key: standard PBKDF2-SHA256 over the NT hash, then once more
+	protected := xpbkdf2.Key(ntHash[:], utf16LE(testSID), 10000, 32, sha256.New)
+	protected = xpbkdf2.Key(protected, utf16LE(testSID), 1, 32, sha256.New)[:16]
+
+	tests := []struct {
+		name    string
+		dir     string // where the masterkey file is written
+		load    string // directory passed to LoadMasterKeys
+		algs    blobAlgs
+		preKey  []byte
+		creds   Credentials
+		wantKey bool
+	}{
+		{"password", testSID, testSID, algsWindows10, realHMAC(sha1.New, passwordSHA1[:], sid), Credentials{Password: testPassword}, true},
+		{"password SHA1", testSID, testSID, algsWindows10, realHMAC(sha1.New, passwordSHA1[:], sid), Credentials{SHA1: passwordSHA1[:]}, true},
+		{"NT hash", testSID, testSID, algsWindows10, realHMAC(sha1.New, ntHash[:], sid), Credentials{NTHash: ntHash[:]}, true},
+		{"domain account by password", testSID, testSID, algsWindows10, realHMAC(sha1.New, ntHash[:], sid), Credentials{Password: testPassword}, true},
+		{"protected user", testSID, testSID, algsWindows10, realHMAC(sha1.New, protected, sid), Credentials{NTHash: ntHash[:]}, true},
+		{"Windows XP masterkey", testSID, testSID, algsWindowsXP, realHMAC(sha1.New, passwordSHA1[:], sid), Credentials{Password: testPassword}, true},
+		{"Protect directory", filepath.Join("Protect", testSID), "Protect", algsWindows10, realHMAC(sha1.New, passwordSHA1[:], sid), Credentials{Password: testPassword}, true},
+		{"SYSTEM user key", filepath.Join("S-1-5-18", "User"), "S-1-5-18", algsWindows10, systemKey.User, Credentials{SystemKey: systemKey}, true},
+		{"wrong password", testSID, testSID, algsWindows10, realHMAC(sha1.New, passwordSHA1[:], sid), Credentials{Password: "wrong"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeMasterKeyFile(t, filepath.Join(root, tt.dir), testGUID, tt.algs, tt.preKey, key)
+
+			// The SID is taken from the directory name
+			cache, errs := LoadMasterKeys(filepath.Join(root, tt.load), tt.creds)
+			if !tt.wantKey {
+				if cache.Len() != 0 || len(errs) != 1 {
+					t.Fatalf("got %d keys and errors %v, want one error", cache.Len(), errs)
+				}
+				return
+			}
+			if len(errs) != 0 {
+				t.Fatalf("LoadMasterKeys: %v", errs)
+			}
+			guid, _ := ParseGUID(testGUID)
+			want := sha1.Sum(key)
+			if got, ok := cache.KeyHash(guid); !ok || !bytes.Equal(got, want[:]) {
+				t.Errorf("got key hash %x, want %x", got, want)
+			}
+		})
+	}
+}
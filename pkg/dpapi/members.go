@@ -0,0 +1,141 @@
+package dpapi
+
+import (
+	"crypto/rsa"
+	"errors"
+)
+
+const (
+	// Windows ALG_IDs used by DPAPI
+	CALG_3DES    = 0x6603
+	CALG_AES_128 = 0x660e
+	CALG_AES_192 = 0x660f
+	CALG_AES_256 = 0x6610
+	CALG_SHA1    = 0x8004
+	CALG_HMAC    = 0x8009
+	CALG_SHA_256 = 0x800c
+	CALG_SHA_384 = 0x800d
+	CALG_SHA_512 = 0x800e
+)
+
+var (
+	// DPAPI provider GUID found in every blob
+	providerGUID = GUID{0xd0, 0x8c, 0x9d, 0xdf, 0x01, 0x15, 0xd1, 0x11, 0x8c, 0x7a, 0x00, 0xc0, 0x4f, 0xc2, 0x97, 0xeb}
+
+	// Blob prefix: version 1 followed by the provider GUID
+	BlobPrefix = append([]byte{0x01, 0x00, 0x00, 0x00}, providerGUID[:]...)
+
+	ErrNoMasterKey    = errors.New("masterkey not found in cache")
+	ErrBadSignature   = errors.New("DPAPI blob signature mismatch (wrong key or entropy)")
+	ErrUnsupportedAlg = errors.New("unsupported DPAPI algorithm")
+	ErrBadMasterKey   = errors.New("masterkey HMAC mismatch (wrong credentials)")
+)
+
+const (
+	// Fixed size of the masterkey file header before the key sections
+	masterKeyFileHeaderSize = 128
+
+	// Decrypted masterkeys are always 64 bytes
+	masterKeyLength = 64
+
+	// Leading part of the masterkey HMAC that is compared on decryption
+	masterKeyHMACCheckLength = 16
+
+	// PVK file magic and PRIVATEKEYBLOB header values for the domain backup key
+	pvkMagic              = 0xb0b5f11e
+	privateKeyBlobType    = 0x07
+	privateKeyBlobVersion = 0x02
+
+	// Length of each half of the DPAPI_SYSTEM LSA secret
+	systemKeyLength = 20
+)
+
+// GUID is a Windows GUID in its on-disk (mixed-endian) byte order
+type GUID [16]byte
+
+// Blob is a parsed DPAPI_BLOB
+type Blob struct {
+	Version          uint32
+	Provider         GUID
+	MasterKeyVersion uint32
+	MasterKey        GUID
+	Flags            uint32
+	Description      string
+	CryptAlg         uint32
+	CryptAlgLen      uint32
+	Salt             []byte
+	HMACKey          []byte
+	HashAlg          uint32
+	HashAlgLen       uint32
+	HMAC2Key         []byte
+	Data             []byte
+	Sign             []byte
+
+	signed []byte // bytes covered by Sign
+}
+
+// MasterKeyFile is a parsed masterkey file from AppData\Roaming\Microsoft\Protect\<SID>\<GUID>
+type MasterKeyFile struct {
+	Version   uint32
+	GUID      GUID
+	Policy    uint32
+	MasterKey *MasterKey
+	BackupKey *MasterKey
+	CredHist  []byte
+	DomainKey []byte
+}
+
+// MasterKey is an encrypted masterkey section of a masterkey file
+type MasterKey struct {
+	Version    uint32
+	Salt       []byte
+	Iterations uint32
+	HashAlg    uint32
+	CryptAlg   uint32
+	Data       []byte
+}
+
+// Credentials are the secrets a masterkey can be decrypted with. Any combination may
+// be set; every pre-key they produce is tried, then the domain backup key.
+type Credentials struct {
+	SID       string
+	Password  string
+	NTHash    []byte          // MD4 of the UTF-16LE password
+	SHA1      []byte          // SHA1 of the UTF-16LE password (the DPAPI SHA1 prekey)
+	BackupKey *rsa.PrivateKey // domain DPAPI backup key, decrypts the domain key section
+	SystemKey *SystemKey      // DPAPI_SYSTEM secret, decrypts the SYSTEM (S-1-5-18) masterkeys
+}
+
+// SystemKey is the decrypted DPAPI_SYSTEM LSA secret: the machine key protects the machine
+// masterkeys in Protect\S-1-5-18 and the user key those in Protect\S-1-5-18\User
+type SystemKey struct {
+	Machine []byte
+	User    []byte
+}
+
+// MasterKeyCache maps masterkey GUIDs to the SHA1 of the decrypted masterkey,
+// which is all that is needed to decrypt blobs
+type MasterKeyCache struct {
+	keys map[GUID][]byte
+}
+
+// algInfo describes the DPAPI cipher and hash algorithms
+type algInfo struct {
+	keyLen    int
+	blockSize int
+}
+
+var cryptAlgs = map[uint32]algInfo{
+	CALG_3DES:    {keyLen: 24, blockSize: 8},
+	CALG_AES_128: {keyLen: 16, blockSize: 16},
+	CALG_AES_192: {keyLen: 24, blockSize: 16},
+	CALG_AES_256: {keyLen: 32, blockSize: 16},
+}
+
+var hashAlgs = map[uint32]algInfo{
+	CALG_SHA1:    {keyLen: 20, blockSize: 64},
+	CALG_HMAC:    {keyLen: 20, blockSize: 64},
+	CALG_SHA_256: {keyLen: 32, blockSize: 64},
+	CALG_SHA_384: {keyLen: 48, blockSize: 128},
+	CALG_SHA_512: {keyLen: 64, blockSize: 128},
+}
@@ -0,0 +1,66 @@
+package dpapi
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ParseSystemKey parses the DPAPI_SYSTEM LSA secret. It accepts the raw secret as hex (44 bytes
+// with its version header, or the 40 bytes of machine key followed by user key), or the
+// "dpapi_machinekey:0x...,dpapi_userkey:0x..." form printed by secretsdump.
+func ParseSystemKey(s string) (*SystemKey, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, ":") {
+		key := &SystemKey{}
+		for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == '\n' }) {
+			name, value, ok := strings.Cut(strings.TrimSpace(part), ":")
+			if !ok {
+				continue
+			}
+			decoded, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(value), "0x"))
+			if err != nil || len(decoded) != systemKeyLength {
+				return nil, fmt.Errorf("invalid %s value", name)
+			}
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case "dpapi_machinekey":
+				key.Machine = decoded
+			case "dpapi_userkey":
+				key.User = decoded
+			}
+		}
+		if key.Machine == nil && key.User == nil {
+			return nil, errors.New("no dpapi_machinekey or dpapi_userkey found")
+		}
+		return key, nil
+	}
+
+	decoded, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DPAPI_SYSTEM hex: %v", err)
+	}
+	switch len(decoded) {
+	case 4 + 2*systemKeyLength:
+		decoded = decoded[4:]
+	case 2 * systemKeyLength:
+	default:
+		return nil, fmt.Errorf("DPAPI_SYSTEM secret has unexpected length %d", len(decoded))
+	}
+	return &SystemKey{
+		Machine: decoded[:systemKeyLength],
+		User:    decoded[systemKeyLength:],
+	}, nil
+}
+
+// PreKeys returns the machine and user keys, which SYSTEM masterkeys use directly as pre-keys
+func (k *SystemKey) PreKeys() [][]byte {
+	var preKeys [][]byte
+	if k.Machine != nil {
+		preKeys = append(preKeys, k.Machine)
+	}
+	if k.User != nil {
+		preKeys = append(preKeys, k.User)
+	}
+	return preKeys
+}
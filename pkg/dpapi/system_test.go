@@ -0,0 +1,56 @@
+package dpapi
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseSystemKey(t *testing.T) {
+	machine := strings.Repeat("11", systemKeyLength)
+	user := strings.Repeat("22", systemKeyLength)
+
+	tests := []struct {
+		name        string
+		input       string
+		wantMachine bool
+		wantUser    bool
+		wantErr     bool
+	}{
+		{"raw secret with version header", "01000000" + machine + user, true, true, false},
+		{"machine and user keys", "0x" + machine + user, true, true, false},
+		{"secretsdump form", "dpapi_machinekey:0x" + machine + ",dpapi_userkey:0x" + user, true, true, false},
+		{"secretsdump machine key only", "dpapi_machinekey:0x" + machine, true, false, false},
+		{"wrong length", machine, false, false, true},
+		{"bad hex", "zz", false, false, true},
+		{"bad secretsdump value", "dpapi_machinekey:0x1122", false, false, true},
+		{"no known names", "other:0x" + machine, false, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParseSystemKey(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := bytes.Equal(key.Machine, bytes.Repeat([]byte{0x11}, systemKeyLength)); got != tt.wantMachine {
+				t.Errorf("machine key: got %x", key.Machine)
+			}
+			if got := bytes.Equal(key.User, bytes.Repeat([]byte{0x22}, systemKeyLength)); got != tt.wantUser {
+				t.Errorf("user key: got %x", key.User)
+			}
+			if got, want := len(key.PreKeys()), btoi(tt.wantMachine)+btoi(tt.wantUser); got != want {
+				t.Errorf("got %d pre-keys, want %d", got, want)
+			}
+		})
+	}
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,231 @@
+package extensions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ListExtensions returns the extensions installed in a Chromium profile directory.
+// Extensions are gathered from Preferences, Secure Preferences and the Extensions directory,
+// and names are resolved from each extension's manifest.json.
+func ListExtensions(profileDir string) ([]Extension, error) {
+	found := make(map[string]*Extension)
+
+	// Preferences based discovery
+	prefsRead := false
+	for _, name := range []string{preferencesFile, securePreferencesFile} {
+		settings, err := readPreferences(filepath.Join(profileDir, name))
+		if err != nil {
+			continue
+		}
+		prefsRead = true
+
+		for id, s := range settings {
+			ext := found[id]
+			if ext == nil {
+				ext = &Extension{ID: id}
+				found[id] = ext
+			}
+			if s.State != nil {
+				ext.Enabled = *s.State == stateEnabled
+			}
+			ext.FromWebStore = ext.FromWebStore || s.FromWebStore
+			if s.Path != "" && ext.Path == "" {
+				ext.Path = s.Path
+				// Web store extensions are stored relative to the Extensions directory,
+				// unpacked extensions keep an absolute path outside the profile
+				if !isAbsPath(s.Path) {
+					ext.Path = filepath.Join(profileDir, extensionsDir, s.Path)
+				}
+			}
+			if s.Manifest != nil {
+				if ext.Name == "" {
+					ext.Name = s.Manifest.Name
+				}
+				if ext.Version == "" {
+					ext.Version = s.Manifest.Version
+				}
+			}
+		}
+	}
+
+	// Directory based discovery, which also gives us the manifest on disk
+	entries, dirErr := os.ReadDir(filepath.Join(profileDir, extensionsDir))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		id := entry.Name()
+		ext := found[id]
+		if ext == nil {
+			ext = &Extension{ID: id}
+			found[id] = ext
+		}
+
+		versionDir, version := latestVersionDir(filepath.Join(profileDir, extensionsDir, id))
+		if versionDir == "" {
+			continue
+		}
+		ext.Path = versionDir
+		if ext.Version == "" {
+			ext.Version = version
+		}
+
+		m, err := readManifest(versionDir)
+		if err != nil {
+			continue
+		}
+		ext.Name = m.Name
+		if m.Version != "" {
+			ext.Version = m.Version
+		}
+	}
+
+	if !prefsRead && dirErr != nil {
+		return nil, fmt.Errorf("no Preferences or Extensions directory found in %s", profileDir)
+	}
+
+	// Resolve names for extensions only known from Preferences
+	for _, ext := range found {
+		if (ext.Name == "" || strings.HasPrefix(ext.Name, "__MSG_")) && ext.Path != "" {
+			if m, err := readManifest(ext.Path); err == nil {
+				ext.Name = m.Name
+			}
+		}
+	}
+
+	extensions := make([]Extension, 0, len(found))
+	for _, ext := range found {
+		extensions = append(extensions, *ext)
+	}
+	sort.Slice(extensions, func(i, j int) bool {
+		return extensions[i].ID < extensions[j].ID
+	})
+
+	return extensions, nil
+}
+
+// readPreferences returns the extensions.settings map of a Preferences file
+func readPreferences(path string) (map[string]extensionSettings, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var prefs preferences
+	if err := json.Unmarshal(content, &prefs); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	return prefs.Extensions.Settings, nil
+}
+
+// latestVersionDir returns the highest versioned subdirectory of an extension directory
+func latestVersionDir(dir string) (string, string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", ""
+	}
+
+	var versions []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			versions = append(versions, entry.Name())
+		}
+	}
+	if len(versions) == 0 {
+		return "", ""
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) < 0
+	})
+	latest := versions[len(versions)-1]
+	return filepath.Join(dir, latest), strings.SplitN(latest, "_", 2)[0]
+}
+
+// readManifest reads manifest.json and resolves a localized name
+func readManifest(dir string) (*manifest, error) {
+	content, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var m manifest
+	if err := json.Unmarshal(content, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest in %s: %v", dir, err)
+	}
+
+	m.Name = localize(dir, m.DefaultLocale, m.Name)
+	return &m, nil
+}
+
+// localize resolves "__MSG_name__" placeholders from the extension's _locales messages
+func localize(dir, defaultLocale, value string) string {
+	if !strings.HasPrefix(value, "__MSG_") || !strings.HasSuffix(value, "__") {
+		return value
+	}
+	key := strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(value, "__MSG_"), "__"))
+
+	locales := []string{defaultLocale, "en", "en_US"}
+	for _, locale := range locales {
+		if locale == "" {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, localesDir, locale, messagesFile))
+		if err != nil {
+			continue
+		}
+
+		var messages map[string]struct {
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(content, &messages); err != nil {
+			continue
+		}
+
+		// Message names are case-insensitive
+		for name, msg := range messages {
+			if strings.ToLower(name) == key {
+				return msg.Message
+			}
+		}
+	}
+
+	return value
+}
+
+// isAbsPath reports whether path is absolute on either Windows or the local OS,
+// since Preferences copied off Windows hosts are often read elsewhere
+func isAbsPath(path string) bool {
+	if filepath.IsAbs(path) || strings.HasPrefix(path, `\\`) {
+		return true
+	}
+	return len(path) >= 3 && path[1] == ':' && (path[2] == '\\' || path[2] == '/')
+}
+
+// compareVersions compares dotted version strings numerically
+func compareVersions(a, b string) int {
+	pa := strings.Split(strings.SplitN(a, "_", 2)[0], ".")
+	pb := strings.Split(strings.SplitN(b, "_", 2)[0], ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			fmt.Sscanf(pa[i], "%d", &x)
+		}
+		if i < len(pb) {
+			fmt.Sscanf(pb[i], "%d", &y)
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,65 @@
+package extensions
+
+const (
+	// Directory and file names inside a Chromium profile
+	extensionsDir         = "Extensions"
+	localSettingsDir      = "Local Extension Settings"
+	syncSettingsDir       = "Sync Extension Settings"
+	preferencesFile       = "Preferences"
+	securePreferencesFile = "Secure Preferences"
+	manifestFile          = "manifest.json"
+	localesDir            = "_locales"
+	messagesFile          = "messages.json"
+
+	// Storage areas reported in StorageRecord.Area
+	LocalArea = "local"
+	SyncArea  = "sync"
+
+	// Extension state value used by Preferences for enabled extensions
+	stateEnabled = 1
+)
+
+// Extension describes an installed extension
+type Extension struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Version      string `json:"version,omitempty"`
+	Path         string `json:"path,omitempty"`
+	Enabled      bool   `json:"enabled"`
+	FromWebStore bool   `json:"fromWebStore"`
+}
+
+// StorageRecord is a single key/value pair from an extension's storage area
+type StorageRecord struct {
+	ExtensionID   string      `json:"extensionId"`
+	ExtensionName string      `json:"extensionName,omitempty"`
+	Area          string      `json:"area"`
+	Key           string      `json:"key"`
+	Value         interface{} `json:"value"`
+}
+
+// JSONFormatter formats extensions and their storage as indented JSON
+type JSONFormatter struct {
+	Extensions []Extension     `json:"extensions"`
+	Storage    []StorageRecord `json:"storage"`
+}
+
+// preferences holds the parts of the Preferences files we care about
+type preferences struct {
+	Extensions struct {
+		Settings map[string]extensionSettings `json:"settings"`
+	} `json:"extensions"`
+}
+
+type extensionSettings struct {
+	Path         string    `json:"path"`
+	State        *int      `json:"state"`
+	FromWebStore bool      `json:"from_webstore"`
+	Manifest     *manifest `json:"manifest"`
+}
+
+type manifest struct {
+	Name          string `json:"name"`
+	Version       string `json:"version"`
+	DefaultLocale string `json:"default_locale"`
+}
@@ -0,0 +1,98 @@
+package extensions
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"go-cookie-monster/pkg/leveldb"
+)
+
+// ReadStorage dumps the local and sync storage areas of the given extensions.
+// If allowlist is non-empty, only extensions whose IDs appear in it are read.
+func ReadStorage(profileDir string, extensions []Extension, allowlist []string) ([]StorageRecord, error) {
+	names := make(map[string]string)
+	for _, ext := range extensions {
+		names[ext.ID] = ext.Name
+	}
+
+	allowed := make(map[string]bool)
+	for _, id := range allowlist {
+		allowed[id] = true
+	}
+
+	var out []StorageRecord
+	for _, area := range []struct {
+		name string
+		dir  string
+	}{
+		{LocalArea, localSettingsDir},
+		{SyncArea, syncSettingsDir},
+	} {
+		entries, err := os.ReadDir(filepath.Join(profileDir, area.dir))
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			id := entry.Name()
+			if !entry.IsDir() || (len(allowed) > 0 && !allowed[id]) {
+				continue
+			}
+
+			records, err := leveldb.ReadRecords(filepath.Join(profileDir, area.dir, id))
+			if err != nil {
+				continue
+			}
+
+			for _, rec := range leveldb.LiveRecords(records) {
+				out = append(out, StorageRecord{
+					ExtensionID:   id,
+					ExtensionName: names[id],
+					Area:          area.name,
+					Key:           string(rec.Key),
+					Value:         decodeValue(rec.Value),
+				})
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// decodeValue returns extension storage values, which are JSON, as raw JSON when valid
+func decodeValue(value []byte) interface{} {
+	if json.Valid(value) {
+		return json.RawMessage(value)
+	}
+	return string(value)
+}
+
+// FilterExtensions returns only the extensions whose IDs are in the allowlist.
+// An empty allowlist returns every extension.
+func FilterExtensions(extensions []Extension, allowlist []string) []Extension {
+	if len(allowlist) == 0 {
+		return extensions
+	}
+
+	allowed := make(map[string]bool)
+	for _, id := range allowlist {
+		allowed[id] = true
+	}
+
+	var out []Extension
+	for _, ext := range extensions {
+		if allowed[ext.ID] {
+			out = append(out, ext)
+		}
+	}
+	return out
+}
+
+func (f *JSONFormatter) Format() (string, error) {
+	jsonData, err := json.MarshalIndent(f, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
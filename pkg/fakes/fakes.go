@@ -0,0 +1,76 @@
+package fakes
+
+import (
+	"fmt"
+	"io/fs"
+
+	"go-cookie-monster/pkg/browsers"
+	"go-cookie-monster/pkg/chromefile"
+	"go-cookie-monster/pkg/processes"
+)
+
+func (k *Keys) MasterKey(encryptedKey string) (string, error) {
+	return lookup(k.MasterKeys, encryptedKey, k.DefaultMasterKey)
+}
+
+func (k *Keys) AppBoundKey(encryptedKey string, browser browsers.Browser) (string, error) {
+	return lookup(k.AppBound, encryptedKey, k.DefaultAppBoundKey)
+}
+
+func (k *Keys) CanDecryptAppBound() bool {
+	return k.DefaultAppBoundKey != "" || len(k.AppBound) > 0
+}
+
+func (k *Keys) Unprotect(data []byte) ([]byte, error) {
+	if plaintext, ok := k.Blobs[string(data)]; ok {
+		return plaintext, nil
+	}
+	return nil, ErrNoKey
+}
+
+func lookup(keys map[string]string, encryptedKey, fallback string) (string, error) {
+	if key, ok := keys[encryptedKey]; ok {
+		return key, nil
+	}
+	if fallback != "" {
+		return fallback, nil
+	}
+	return "", ErrNoKey
+}
+
+func (f *Files) ReadLocalState(path string) ([]byte, error) {
+	if content, ok := f.LocalStates[path]; ok {
+		return content, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+}
+
+func (f *Files) BrowserFiles(pids []uint32) (*chromefile.ChromeFiles, error) {
+	f.PIDs = append(f.PIDs, pids...)
+	return &chromefile.ChromeFiles{
+		Cookies:   f.fileData(f.Cookies, "Cookies"),
+		LoginData: f.fileData(f.LoginData, "Login Data"),
+	}, nil
+}
+
+func (f *Files) fileData(data []byte, name string) chromefile.FileData {
+	if data == nil {
+		return chromefile.FileData{Error: fmt.Sprintf("fake file source has no %s", name)}
+	}
+	return chromefile.FileData{
+		Data:       data,
+		FromHandle: f.FromHandle,
+		Size:       int64(len(data)),
+		Path:       name,
+	}
+}
+
+func (p *Processes) FindProcess(name string) ([]processes.Process, error) {
+	var found []processes.Process
+	for _, proc := range p.Running {
+		if proc.Name == name {
+			found = append(found, proc)
+		}
+	}
+	return found, nil
+}
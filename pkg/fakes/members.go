@@ -0,0 +1,36 @@
+package fakes
+
+import (
+	"errors"
+
+	"go-cookie-monster/pkg/processes"
+)
+
+// ErrNoKey is returned by Keys for keys it was not given
+var ErrNoKey = errors.New("fake key provider has no key for this input")
+
+// Keys is an in-memory KeyProvider. Keys are returned in the "\xHH" form the live providers
+// use; encrypted keys missing from the maps fall back to the defaults.
+type Keys struct {
+	DefaultMasterKey   string            // returned for any encrypted os_crypt key without an entry
+	DefaultAppBoundKey string            // returned for any app-bound key without an entry
+	MasterKeys         map[string]string // base64 encrypted_key -> key
+	AppBound           map[string]string // base64 app_bound_encrypted_key -> key
+	Blobs              map[string][]byte // DPAPI blob (as a string) -> plaintext
+}
+
+// Files is an in-memory FileSource holding Local State files by path and the databases that
+// BrowserFiles returns. Requested PIDs are recorded in PIDs.
+type Files struct {
+	LocalStates map[string][]byte
+	Cookies     []byte
+	LoginData   []byte
+	FromHandle  bool // report the databases as read through a process handle
+
+	PIDs []uint32
+}
+
+// Processes is an in-memory ProcessLister over a fixed process list
+type Processes struct {
+	Running []processes.Process
+}
@@ -0,0 +1,181 @@
+package firefox
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-cookie-monster/pkg/decrypt"
+)
+
+// ReadCookies reads a profile's cookies.sqlite into the same Cookie model used for Chromium,
+// so the results go through every cookie exporter. Firefox stores cookies in the clear.
+func ReadCookies(profileDir string) ([]decrypt.Cookie, error) {
+	db, cleanup, err := openDatabase(filepath.Join(profileDir, cookiesFile))
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	columns, err := tableColumns(db, "moz_cookies")
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("%s has no moz_cookies table", cookiesFile)
+	}
+
+	// sameSite and schemeMap are missing from older profiles
+	query := fmt.Sprintf(`SELECT name, value, host, path, expiry, creationTime, lastAccessed,
+		isSecure, isHttpOnly, %s, %s, %s FROM moz_cookies`,
+		columnOr(columns, "originAttributes", "''"),
+		columnOr(columns, "sameSite", "0"),
+		columnOr(columns, "schemeMap", "0"))
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cookies []decrypt.Cookie
+	for rows.Next() {
+		var (
+			c                      decrypt.Cookie
+			expiry                 int64
+			creationTime, accessed int64
+			isSecure, isHTTPOnly   int
+			originAttributes       string
+			sameSite, schemeMap    int
+		)
+		if err := rows.Scan(&c.Name, &c.Value, &c.Domain, &c.Path, &expiry, &creationTime, &accessed,
+			&isSecure, &isHTTPOnly, &originAttributes, &sameSite, &schemeMap); err != nil {
+			return nil, err
+		}
+
+		if expiry > maxExpirySeconds {
+			expiry /= 1000
+		}
+		c.ExpirationDate = expiry
+		c.Expires = formatUnix(expiry)
+		c.CreationDate = creationTime / 1000000
+		c.LastAccessDate = accessed / 1000000
+		c.Secure = isSecure != 0
+		c.HTTPOnly = isHTTPOnly != 0
+		c.SameSite = convertSameSite(sameSite)
+		c.SourceScheme = convertSchemeMap(schemeMap)
+		c.SourcePort = -1
+		c.Priority = "Medium"
+
+		// cookies.sqlite only holds persistent cookies; session cookies live in the session store
+		c.HasExpires = true
+		c.IsPersistent = true
+		c.HostOnly = !strings.HasPrefix(c.Domain, ".")
+
+		parseOriginAttributes(originAttributes).apply(&c)
+		cookies = append(cookies, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// moz_cookies is unique per name, host, path and originAttributes, so there is nothing
+	// to deduplicate (and DedupCookies would merge containers)
+	return cookies, nil
+}
+
+// parseOriginAttributes parses an originAttributes suffix, as stored in moz_cookies, e.g.
+// "^userContextId=2&partitionKey=%28https%2Cexample.com%29"
+func parseOriginAttributes(suffix string) originAttributes {
+	attrs, err := url.ParseQuery(strings.TrimPrefix(suffix, "^"))
+	if err != nil {
+		return originAttributes{}
+	}
+	userContextID, _ := strconv.Atoi(attrs.Get("userContextId"))
+	privateBrowsingID, _ := strconv.Atoi(attrs.Get("privateBrowsingId"))
+	return originAttributes{
+		FirstPartyDomain:  attrs.Get("firstPartyDomain"),
+		PartitionKey:      attrs.Get("partitionKey"),
+		UserContextID:     userContextID,
+		PrivateBrowsingID: privateBrowsingID,
+	}
+}
+
+// apply sets the container, first-party domain and partition of a cookie
+func (o originAttributes) apply(c *decrypt.Cookie) {
+	c.FirstPartyDomain = o.FirstPartyDomain
+	c.StoreID = storeID(o.UserContextID, o.PrivateBrowsingID)
+
+	if site, crossSite, ok := parsePartitionKey(o.PartitionKey); ok {
+		c.TopFrameSiteKey = site
+		c.PartitionKey = &decrypt.PartitionKey{
+			TopLevelSite:         site,
+			HasCrossSiteAncestor: crossSite,
+		}
+	}
+}
+
+// storeID returns the WebExtensions cookie store of a container or private browsing context
+func storeID(userContextID, privateBrowsingID int) string {
+	switch {
+	case privateBrowsingID != 0:
+		return privateStoreID
+	case userContextID != 0:
+		return containerStoreID + strconv.Itoa(userContextID)
+	default:
+		return defaultStoreID
+	}
+}
+
+// parsePartitionKey parses a partitionKey origin attribute, "(scheme,baseDomain[,port][,f])",
+// into its top-level site. The trailing "f" marks a foreign (cross-site) ancestor.
+func parsePartitionKey(key string) (site string, crossSite bool, ok bool) {
+	if !strings.HasPrefix(key, "(") || !strings.HasSuffix(key, ")") {
+		return "", false, false
+	}
+	fields := strings.Split(key[1:len(key)-1], ",")
+	if len(fields) < 2 || fields[0] == "" || fields[1] == "" {
+		return "", false, false
+	}
+	for _, field := range fields[2:] {
+		if field == "f" {
+			crossSite = true
+		}
+	}
+	return fields[0] + "://" + fields[1], crossSite, true
+}
+
+// convertSameSite maps nsICookie's SAMESITE_* values to the WebExtensions names
+func convertSameSite(sameSite int) string {
+	switch sameSite {
+	case 1:
+		return "lax"
+	case 2:
+		return "strict"
+	default:
+		return "no_restriction"
+	}
+}
+
+// convertSchemeMap maps the schemes a cookie was set from to its source scheme. A cookie
+// seen over https is Secure even if it was also set over http.
+func convertSchemeMap(schemeMap int) string {
+	switch {
+	case schemeMap&schemeHTTPS != 0:
+		return "Secure"
+	case schemeMap&(schemeHTTP|schemeFile) != 0:
+		return "NonSecure"
+	default:
+		return "Unset"
+	}
+}
+
+// formatUnix formats Unix seconds as RFC 3339, or returns "" for 0
+func formatUnix(unix int64) string {
+	if unix == 0 {
+		return ""
+	}
+	return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+}
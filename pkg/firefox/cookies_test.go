@@ -0,0 +1,202 @@
+package firefox
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-cookie-monster/pkg/decrypt"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// writeDatabase creates an SQLite database by running statements in order
+func writeDatabase(t *testing.T, path string, statements ...string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, s := range statements {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindProfiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, profilesFile), "\xef\xbb\xbf"+`[General]
+StartWithLastProfile=1
+
+[Profile1]
+Name=work
+IsRelative=1
+Path=Profiles/abcd1234.work
+
+[Profile0]
+Name=default
+IsRelative=1
+Path=Profiles/wxyz5678.default
+Default=1
+
+[Profile2]
+Name=deleted
+IsRelative=1
+Path=Profiles/gone.deleted
+`)
+	writeFile(t, filepath.Join(dir, installsFile), "[308046B0AF4A39CB]\nDefault=Profiles/abcd1234.work\nLocked=1\n")
+	for _, name := range []string{"abcd1234.work", "wxyz5678.default"} {
+		if err := os.MkdirAll(filepath.Join(dir, "Profiles", name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !IsFirefoxDir(dir) {
+		t.Error("IsFirefoxDir = false")
+	}
+	profiles, err := FindProfiles(dir)
+	if err != nil {
+		t.Fatalf("FindProfiles: %v", err)
+	}
+
+	// installs.ini takes precedence over Default=1, and the deleted profile is skipped
+	want := []Profile{
+		{Name: "work", Path: filepath.Join(dir, "Profiles", "abcd1234.work"), Default: true},
+		{Name: "default", Path: filepath.Join(dir, "Profiles", "wxyz5678.default")},
+	}
+	if len(profiles) != len(want) {
+		t.Fatalf("got %+v, want %+v", profiles, want)
+	}
+	for i := range want {
+		if profiles[i] != want[i] {
+			t.Errorf("profile %d: got %+v, want %+v", i, profiles[i], want[i])
+		}
+	}
+
+	if _, err := FindProfiles(t.TempDir()); err == nil {
+		t.Error("FindProfiles without profiles.ini: got no error")
+	}
+}
+
+func TestReadCookies(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		insert string
+	}{
+		{"current schema",
+			`CREATE TABLE moz_cookies (id INTEGER PRIMARY KEY, originAttributes TEXT NOT NULL DEFAULT '',
+				name TEXT, value TEXT, host TEXT, path TEXT, expiry INTEGER, lastAccessed INTEGER,
+				creationTime INTEGER, isSecure INTEGER, isHttpOnly INTEGER, inBrowserElement INTEGER DEFAULT 0,
+				sameSite INTEGER DEFAULT 0, rawSameSite INTEGER DEFAULT 0, schemeMap INTEGER DEFAULT 0,
+				isPartitionedAttributeSet INTEGER DEFAULT 0)`,
+			`INSERT INTO moz_cookies (originAttributes, name, value, host, path, expiry, lastAccessed,
+				creationTime, isSecure, isHttpOnly, sameSite, schemeMap) VALUES
+				('', 'sid', 'abc', '.example.com', '/', 1800000000000, 1700000100000000, 1700000000000000, 1, 1, 1, 2),
+				('^userContextId=2', 'pref', 'dark', 'www.example.com', '/app', 1800000000, 1700000100000000, 1700000000000000, 0, 0, 2, 1),
+				('^partitionKey=%28https%2Cembedder.com%2Cf%29', 'chip', 'p', 'widget.example', '/', 1800000000, 0, 0, 1, 0, 0, 2)`},
+		// Before sameSite (Firefox 63) and schemeMap (Firefox 81)
+		{"old schema",
+			`CREATE TABLE moz_cookies (id INTEGER PRIMARY KEY, baseDomain TEXT, originAttributes TEXT NOT NULL DEFAULT '',
+				name TEXT, value TEXT, host TEXT, path TEXT, expiry INTEGER, lastAccessed INTEGER,
+				creationTime INTEGER, isSecure INTEGER, isHttpOnly INTEGER, inBrowserElement INTEGER DEFAULT 0)`,
+			`INSERT INTO moz_cookies (baseDomain, originAttributes, name, value, host, path, expiry, lastAccessed,
+				creationTime, isSecure, isHttpOnly) VALUES
+				('example.com', '', 'sid', 'abc', '.example.com', '/', 1800000000, 1700000100000000, 1700000000000000, 1, 1)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeDatabase(t, filepath.Join(dir, cookiesFile), tt.schema, tt.insert)
+
+			cookies, err := ReadCookies(dir)
+			if err != nil {
+				t.Fatalf("ReadCookies: %v", err)
+			}
+			if len(cookies) == 0 {
+				t.Fatal("no cookies")
+			}
+
+			sid := cookies[0]
+			if sid.Name != "sid" || sid.Value != "abc" || sid.Domain != ".example.com" || sid.HostOnly {
+				t.Errorf("got %+v", sid)
+			}
+			// Millisecond expiries are converted to seconds
+			if sid.ExpirationDate != 1800000000 || sid.Expires != "2027-01-15T08:00:00Z" {
+				t.Errorf("expiry: got %d (%s)", sid.ExpirationDate, sid.Expires)
+			}
+			if sid.CreationDate != 1700000000 || sid.LastAccessDate != 1700000100 {
+				t.Errorf("times: got created %d, accessed %d", sid.CreationDate, sid.LastAccessDate)
+			}
+			if !sid.Secure || !sid.HTTPOnly || !sid.IsPersistent || sid.StoreID != defaultStoreID {
+				t.Errorf("flags: got %+v", sid)
+			}
+			if tt.name == "old schema" {
+				if sid.SameSite != "no_restriction" || sid.SourceScheme != "Unset" {
+					t.Errorf("got SameSite %q, SourceScheme %q", sid.SameSite, sid.SourceScheme)
+				}
+				return
+			}
+			if sid.SameSite != "lax" || sid.SourceScheme != "Secure" {
+				t.Errorf("got SameSite %q, SourceScheme %q", sid.SameSite, sid.SourceScheme)
+			}
+
+			if len(cookies) != 3 {
+				t.Fatalf("got %d cookies, want 3", len(cookies))
+			}
+			pref := cookies[1]
+			if !pref.HostOnly || pref.StoreID != containerStoreID+"2" || pref.SameSite != "strict" ||
+				pref.SourceScheme != "NonSecure" || pref.ExpirationDate != 1800000000 {
+				t.Errorf("container cookie: got %+v", pref)
+			}
+			chip := cookies[2]
+			key, _ := chip.PartitionKey.(*decrypt.PartitionKey)
+			if key == nil || key.TopLevelSite != "https://embedder.com" || !key.HasCrossSiteAncestor ||
+				chip.TopFrameSiteKey != "https://embedder.com" {
+				t.Errorf("partitioned cookie: got %+v", chip)
+			}
+		})
+	}
+}
+
+func TestParsePartitionKey(t *testing.T) {
+	tests := []struct {
+		key       string
+		site      string
+		crossSite bool
+		ok        bool
+	}{
+		{"(https,example.com)", "https://example.com", false, true},
+		{"(http,localhost,8080)", "http://localhost", false, true},
+		{"(https,example.com,f)", "https://example.com", true, true},
+		{"", "", false, false},
+		{"(https)", "", false, false},
+		{"https,example.com", "", false, false},
+	}
+	for _, tt := range tests {
+		site, crossSite, ok := parsePartitionKey(tt.key)
+		if site != tt.site || crossSite != tt.crossSite || ok != tt.ok {
+			t.Errorf("parsePartitionKey(%q) = %q, %v, %v, want %q, %v, %v",
+				tt.key, site, crossSite, ok, tt.site, tt.crossSite, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package firefox
+
+import (
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// openDatabase opens a copy of a profile's SQLite database. Firefox keeps its databases
+// locked and recent writes in the -wal file, so both are copied to a temporary directory
+// first; the returned cleanup closes the copy and removes it.
+func openDatabase(path string) (*sql.DB, func(), error) {
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		return nil, nil, err
+	}
+	removeTmp := func() { os.RemoveAll(tmpDir) }
+
+	copyPath := filepath.Join(tmpDir, filepath.Base(path))
+	if err := copyFile(path, copyPath); err != nil {
+		removeTmp()
+		return nil, nil, err
+	}
+	if _, err := os.Stat(path + "-wal"); err == nil {
+		if err := copyFile(path+"-wal", copyPath+"-wal"); err != nil {
+			removeTmp()
+			return nil, nil, err
+		}
+	}
+
+	db, err := sql.Open("sqlite3", copyPath)
+	if err != nil {
+		removeTmp()
+		return nil, nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		removeTmp()
+		return nil, nil, err
+	}
+
+	return db, func() {
+		db.Close()
+		removeTmp()
+	}, nil
+}
+
+// tableColumns returns the set of column names in a table, so readers can cope with older
+// schemas that lack newer columns
+func tableColumns(db *sql.DB, table string) (map[string]bool, error) {
+	rows, err := db.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
+
+// columnOr selects column if the table has it, else the fallback expression
+func columnOr(columns map[string]bool, column, fallback string) string {
+	if columns[column] {
+		return column
+	}
+	return fallback
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,36 @@
+package firefox
+
+import (
+	"path/filepath"
+
+	"go-cookie-monster/pkg/decrypt"
+)
+
+// ReadFormHistory reads the values typed into form fields from a profile's formhistory.sqlite,
+// into the same Autofill model used for Chromium's Web Data
+func ReadFormHistory(profileDir string) ([]decrypt.Autofill, error) {
+	db, cleanup, err := openDatabase(filepath.Join(profileDir, formHistoryFile))
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	rows, err := db.Query(`SELECT fieldname, value, COALESCE(firstUsed, 0), COALESCE(lastUsed, 0), timesUsed
+		FROM moz_formhistory ORDER BY lastUsed DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []decrypt.Autofill
+	for rows.Next() {
+		var a decrypt.Autofill
+		if err := rows.Scan(&a.Name, &a.Value, &a.DateCreated, &a.DateLastUsed, &a.Count); err != nil {
+			return nil, err
+		}
+		a.DateCreated /= 1000000
+		a.DateLastUsed /= 1000000
+		entries = append(entries, a)
+	}
+	return entries, rows.Err()
+}
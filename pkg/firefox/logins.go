@@ -0,0 +1,75 @@
+package firefox
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go-cookie-monster/pkg/decrypt"
+)
+
+// ReadLogins decrypts a profile's saved passwords from logins.json with the keys in key4.db,
+// into the same Login model used for Chromium's Login Data. Logins that fail to decrypt are
+// reported in the returned error slice; the final error is fatal to the whole profile.
+func ReadLogins(profileDir, primaryPassword string) ([]decrypt.Login, []error, error) {
+	content, err := os.ReadFile(filepath.Join(profileDir, loginsFile))
+	if err != nil {
+		return nil, nil, err
+	}
+	var stored loginsJSON
+	if err := json.Unmarshal(content, &stored); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %v", loginsFile, err)
+	}
+
+	ks, err := OpenKeyStore(profileDir, primaryPassword)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		logins []decrypt.Login
+		errs   []error
+	)
+	for _, entry := range stored.Logins {
+		username, err := ks.decryptBase64(entry.EncryptedUsername)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: failed to decrypt username: %v", entry.Hostname, err))
+			continue
+		}
+		password, err := ks.decryptBase64(entry.EncryptedPassword)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: failed to decrypt password: %v", entry.Hostname, err))
+			continue
+		}
+
+		// Chromium's signon_realm is the origin with a trailing slash, followed by the realm
+		// for HTTP authentication
+		logins = append(logins, decrypt.Login{
+			OriginURL:            entry.Hostname,
+			ActionURL:            entry.FormSubmitURL,
+			SignonRealm:          entry.Hostname + "/" + entry.HTTPRealm,
+			Username:             string(username),
+			Password:             string(password),
+			DateCreated:          entry.TimeCreated / 1000,
+			DateLastUsed:         entry.TimeLastUsed / 1000,
+			DatePasswordModified: entry.TimePasswordChanged / 1000,
+			TimesUsed:            entry.TimesUsed,
+		})
+	}
+
+	return logins, errs, nil
+}
+
+// decryptBase64 decrypts a base64-encoded logins.json value
+func (ks *KeyStore) decryptBase64(value string) ([]byte, error) {
+	if value == "" {
+		return nil, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return ks.Decrypt(data)
+}
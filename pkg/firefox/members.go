@@ -0,0 +1,239 @@
+package firefox
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+
+	"go-cookie-monster/pkg/decrypt"
+)
+
+const (
+	// Files in the Firefox root directory
+	profilesFile = "profiles.ini"
+	installsFile = "installs.ini"
+
+	// Files inside a Firefox profile
+	cookiesFile     = "cookies.sqlite"
+	sessionFile     = "sessionstore.jsonlz4"
+	placesFile      = "places.sqlite"
+	formHistoryFile = "formhistory.sqlite"
+	keyDBFile       = "key4.db"
+	loginsFile      = "logins.json"
+
+	// Per-origin localStorage databases, below the profile directory
+	storageDefaultDir = "storage/default"
+	localStorageFile  = "ls/data.sqlite"
+
+	// LSValue compression and conversion types in ls/data.sqlite
+	lsCompressionSnappy = 1
+	lsConversionNone    = 0 // raw UTF-16
+	lsConversionUTF8    = 1
+
+	// moz_bookmarks.type of bookmarked URLs, and the GUID of the root holding tags
+	bookmarkTypeURL = 1
+	tagsRootGUID    = "tagsfolder_____"
+
+	// moz_cookies.schemeMap bits
+	schemeHTTP  = 1 << 0
+	schemeHTTPS = 1 << 1
+	schemeFile  = 1 << 2
+
+	// Cookie expiry moved from seconds to milliseconds in schema 14; any expiry past this
+	// (the year 5138 in seconds) is in milliseconds
+	maxExpirySeconds = 1e11
+
+	// storeId values used by the WebExtensions cookies API
+	defaultStoreID   = "firefox-default"
+	privateStoreID   = "firefox-private"
+	containerStoreID = "firefox-container-"
+
+	// passwordCheck is the plaintext of key4.db's password check entry
+	passwordCheck = "password-check"
+)
+
+var (
+	// OIDs used by NSS's encrypted key4.db entries and logins.json values
+	oidPBEWithSHA1And3DES = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 5, 1, 3}
+	oidPBES2              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1       = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256     = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAES256CBC          = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDESEDE3CBC         = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+)
+
+var (
+	// ErrNoProfiles is returned when a Firefox directory lists no profiles
+	ErrNoProfiles = errors.New("no Firefox profiles found")
+	// ErrPrimaryPassword is returned when key4.db is protected by a different primary password
+	ErrPrimaryPassword = errors.New("wrong Firefox primary password")
+)
+
+// KeyStore holds the keys decrypted from a profile's key4.db, indexed by their CKA_ID
+type KeyStore struct {
+	keys map[string][]byte
+}
+
+// Profile is a profile listed in profiles.ini
+type Profile struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Default bool   `json:"default"`
+}
+
+// originAttributes are the parts of a cookie's origin attributes that affect where it belongs
+type originAttributes struct {
+	FirstPartyDomain  string `json:"firstPartyDomain"`
+	PartitionKey      string `json:"partitionKey"`
+	UserContextID     int    `json:"userContextId"`
+	PrivateBrowsingID int    `json:"privateBrowsingId"`
+}
+
+// iniSection is one [section] of an INI file
+type iniSection struct {
+	Name   string
+	Values map[string]string
+}
+
+// logins.json, as written by Firefox's LoginManagerStorage
+type loginsJSON struct {
+	Logins []struct {
+		Hostname            string `json:"hostname"`
+		HTTPRealm           string `json:"httpRealm"`
+		FormSubmitURL       string `json:"formSubmitURL"`
+		EncryptedUsername   string `json:"encryptedUsername"`
+		EncryptedPassword   string `json:"encryptedPassword"`
+		TimeCreated         int64  `json:"timeCreated"`
+		TimeLastUsed        int64  `json:"timeLastUsed"`
+		TimePasswordChanged int64  `json:"timePasswordChanged"`
+		TimesUsed           int    `json:"timesUsed"`
+	} `json:"logins"`
+}
+
+// encryptedEntry is a password-based encrypted key4.db entry
+type encryptedEntry struct {
+	Algorithm  pkix.AlgorithmIdentifier
+	Ciphertext []byte
+}
+
+// pbeParams are the parameters of pbeWithSHA1AndTripleDES-CBC
+type pbeParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+// pbes2Params are the parameters of PBES2: a key derivation function and a cipher
+type pbes2Params struct {
+	KeyDerivation pkix.AlgorithmIdentifier
+	Encryption    pkix.AlgorithmIdentifier
+}
+
+// pbkdf2Params are the PBKDF2 parameters; PRF defaults to HMAC-SHA1
+type pbkdf2Params struct {
+	Salt       []byte
+	Iterations int
+	KeyLength  int                      `asn1:"optional"`
+	PRF        pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// sdrEntry is a value encrypted with NSS's secret decoder ring (logins.json fields)
+type sdrEntry struct {
+	KeyID      []byte
+	Algorithm  pkix.AlgorithmIdentifier
+	Ciphertext []byte
+}
+
+// SessionJSONFormatter holds the windows, tabs and session cookies restored from a session store
+type SessionJSONFormatter struct {
+	Source        string           `json:"source"`
+	Windows       []SessionWindow  `json:"windows"`
+	ClosedWindows []SessionWindow  `json:"closedWindows"`
+	Cookies       []decrypt.Cookie `json:"cookies"`
+}
+
+// SessionWindow is an open or recently closed browser window
+type SessionWindow struct {
+	Tabs       []SessionTab `json:"tabs"`
+	ClosedTabs []SessionTab `json:"closedTabs"`
+	ClosedAt   int64        `json:"closedAt,omitempty"`
+}
+
+// SessionTab is a tab with its back/forward history and any form data typed into its pages
+type SessionTab struct {
+	URL          string         `json:"url"`
+	Title        string         `json:"title"`
+	StoreID      string         `json:"storeId"`
+	Pinned       bool           `json:"pinned"`
+	Hidden       bool           `json:"hidden"`
+	LastAccessed int64          `json:"lastAccessed"`
+	ClosedAt     int64          `json:"closedAt,omitempty"`
+	History      []SessionEntry `json:"history"`
+	FormData     []FormField    `json:"formData,omitempty"`
+}
+
+// SessionEntry is a page in a tab's history
+type SessionEntry struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+}
+
+// FormField is a form field value saved for session restore, identified by element ID or XPath
+type FormField struct {
+	URL   string      `json:"url"`
+	ID    string      `json:"id,omitempty"`
+	XPath string      `json:"xpath,omitempty"`
+	Value interface{} `json:"value"`
+}
+
+// sessionStore is the subset of sessionstore.jsonlz4 that is extracted
+type sessionStore struct {
+	Windows       []sessionWindow `json:"windows"`
+	ClosedWindows []sessionWindow `json:"_closedWindows"`
+	Cookies       []sessionCookie `json:"cookies"`
+}
+
+type sessionWindow struct {
+	Tabs       []sessionTab `json:"tabs"`
+	ClosedTabs []struct {
+		State    sessionTab `json:"state"`
+		ClosedAt int64      `json:"closedAt"`
+	} `json:"_closedTabs"`
+	ClosedAt int64           `json:"closedAt"`
+	Cookies  []sessionCookie `json:"cookies"`
+}
+
+type sessionTab struct {
+	Entries       []sessionEntry `json:"entries"`
+	Index         int            `json:"index"`
+	LastAccessed  int64          `json:"lastAccessed"`
+	Pinned        bool           `json:"pinned"`
+	Hidden        bool           `json:"hidden"`
+	UserContextID int            `json:"userContextId"`
+	FormData      *sessionForm   `json:"formdata"`
+}
+
+type sessionEntry struct {
+	URL      string         `json:"url"`
+	Title    string         `json:"title"`
+	FormData *sessionForm   `json:"formdata"`
+	Children []sessionEntry `json:"children"`
+}
+
+type sessionForm struct {
+	URL   string                 `json:"url"`
+	ID    map[string]interface{} `json:"id"`
+	XPath map[string]interface{} `json:"xpath"`
+}
+
+type sessionCookie struct {
+	Host             string           `json:"host"`
+	Name             string           `json:"name"`
+	Value            string           `json:"value"`
+	Path             string           `json:"path"`
+	Secure           bool             `json:"secure"`
+	HTTPOnly         bool             `json:"httponly"`
+	SameSite         int              `json:"sameSite"`
+	SchemeMap        int              `json:"schemeMap"`
+	OriginAttributes originAttributes `json:"originAttributes"`
+}
@@ -0,0 +1,235 @@
+package firefox
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// OpenKeyStore decrypts the keys in a profile's key4.db with the primary password ("" when
+// none is set). The password is checked against the database's password-check entry first.
+func OpenKeyStore(profileDir, primaryPassword string) (*KeyStore, error) {
+	keyDBPath := filepath.Join(profileDir, keyDBFile)
+	if _, err := os.Stat(keyDBPath); err != nil {
+		if _, legacyErr := os.Stat(filepath.Join(profileDir, "key3.db")); legacyErr == nil {
+			return nil, errors.New("key3.db (Firefox 57 and older) is not supported")
+		}
+		return nil, err
+	}
+
+	db, cleanup, err := openDatabase(keyDBPath)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	var globalSalt, check []byte
+	err = db.QueryRow("SELECT item1, item2 FROM metaData WHERE id = 'password'").Scan(&globalSalt, &check)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read password check from %s: %v", keyDBFile, err)
+	}
+	plaintext, err := decryptEntry(globalSalt, []byte(primaryPassword), check)
+	if err != nil || string(plaintext) != passwordCheck {
+		return nil, ErrPrimaryPassword
+	}
+
+	rows, err := db.Query("SELECT a11, a102 FROM nssPrivate")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keys from %s: %v", keyDBFile, err)
+	}
+	defer rows.Close()
+
+	ks := &KeyStore{keys: make(map[string][]byte)}
+	for rows.Next() {
+		var encryptedKey, keyID []byte
+		if err := rows.Scan(&encryptedKey, &keyID); err != nil {
+			return nil, err
+		}
+		key, err := decryptEntry(globalSalt, []byte(primaryPassword), encryptedKey)
+		if err != nil {
+			continue
+		}
+		ks.keys[string(keyID)] = key
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ks.keys) == 0 {
+		return nil, fmt.Errorf("no keys could be decrypted from %s", keyDBFile)
+	}
+
+	return ks, nil
+}
+
+// Decrypt decrypts a value encrypted with NSS's secret decoder ring, such as the
+// encryptedUsername and encryptedPassword fields of logins.json (after base64 decoding)
+func (ks *KeyStore) Decrypt(data []byte) ([]byte, error) {
+	var entry sdrEntry
+	if _, err := asn1.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse encrypted value: %v", err)
+	}
+
+	key, ok := ks.keys[string(entry.KeyID)]
+	if !ok {
+		return nil, fmt.Errorf("no key with ID %x", entry.KeyID)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(entry.Algorithm.Parameters.FullBytes, &iv); err != nil {
+		return nil, fmt.Errorf("failed to parse encrypted value IV: %v", err)
+	}
+
+	switch {
+	case entry.Algorithm.Algorithm.Equal(oidDESEDE3CBC):
+		if len(key) < 24 {
+			return nil, errors.New("key is too short for 3DES")
+		}
+		return decrypt3DES(key[:24], iv, entry.Ciphertext)
+	case entry.Algorithm.Algorithm.Equal(oidAES256CBC):
+		if len(key) < 32 {
+			return nil, errors.New("key is too short for AES-256")
+		}
+		return decryptAES(key[:32], iv, entry.Ciphertext)
+	default:
+		return nil, fmt.Errorf("unsupported encryption algorithm %v", entry.Algorithm.Algorithm)
+	}
+}
+
+// decryptEntry decrypts a password-based encrypted key4.db entry: either legacy
+// pbeWithSHA1AndTripleDES-CBC, or PBES2 with PBKDF2 and AES-256-CBC (Firefox 75+)
+func decryptEntry(globalSalt, password, data []byte) ([]byte, error) {
+	var entry encryptedEntry
+	if _, err := asn1.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse encrypted entry: %v", err)
+	}
+	params := entry.Algorithm.Parameters.FullBytes
+
+	switch {
+	case entry.Algorithm.Algorithm.Equal(oidPBEWithSHA1And3DES):
+		var pbe pbeParams
+		if _, err := asn1.Unmarshal(params, &pbe); err != nil {
+			return nil, fmt.Errorf("failed to parse PBE parameters: %v", err)
+		}
+		key, iv := deriveLegacy3DESKey(globalSalt, password, pbe.Salt)
+		return decrypt3DES(key, iv, entry.Ciphertext)
+
+	case entry.Algorithm.Algorithm.Equal(oidPBES2):
+		var pbes2 pbes2Params
+		if _, err := asn1.Unmarshal(params, &pbes2); err != nil {
+			return nil, fmt.Errorf("failed to parse PBES2 parameters: %v", err)
+		}
+		if !pbes2.KeyDerivation.Algorithm.Equal(oidPBKDF2) {
+			return nil, fmt.Errorf("unsupported key derivation %v", pbes2.KeyDerivation.Algorithm)
+		}
+		if !pbes2.Encryption.Algorithm.Equal(oidAES256CBC) {
+			return nil, fmt.Errorf("unsupported PBES2 cipher %v", pbes2.Encryption.Algorithm)
+		}
+
+		var kdf pbkdf2Params
+		if _, err := asn1.Unmarshal(pbes2.KeyDerivation.Parameters.FullBytes, &kdf); err != nil {
+			return nil, fmt.Errorf("failed to parse PBKDF2 parameters: %v", err)
+		}
+		var prf func() hash.Hash
+		switch {
+		case len(kdf.PRF.Algorithm) == 0, kdf.PRF.Algorithm.Equal(oidHMACWithSHA1):
+			prf = sha1.New
+		case kdf.PRF.Algorithm.Equal(oidHMACWithSHA256):
+			prf = sha256.New
+		default:
+			return nil, fmt.Errorf("unsupported PBKDF2 PRF %v", kdf.PRF.Algorithm)
+		}
+		keyLength := kdf.KeyLength
+		if keyLength == 0 {
+			keyLength = 32
+		}
+
+		var iv []byte
+		if _, err := asn1.Unmarshal(pbes2.Encryption.Parameters.FullBytes, &iv); err != nil {
+			return nil, fmt.Errorf("failed to parse PBES2 IV: %v", err)
+		}
+		// NSS stores a 14-byte IV; the real one is prefixed with its DER OCTET STRING header
+		if len(iv) == aes.BlockSize-2 {
+			iv = append([]byte{0x04, 0x0e}, iv...)
+		}
+
+		// The PBKDF2 password is the SHA1 of the global salt and the primary password
+		hashed := sha1.Sum(append(append([]byte(nil), globalSalt...), password...))
+		key := pbkdf2.Key(hashed[:], kdf.Salt, kdf.Iterations, keyLength, prf)
+		return decryptAES(key, iv, entry.Ciphertext)
+
+	default:
+		return nil, fmt.Errorf("unsupported encryption algorithm %v", entry.Algorithm.Algorithm)
+	}
+}
+
+// deriveLegacy3DESKey derives the 3DES key and IV of NSS's pbeWithSHA1AndTripleDES-CBC
+func deriveLegacy3DESKey(globalSalt, password, entrySalt []byte) (key, iv []byte) {
+	hp := sha1.Sum(append(append([]byte(nil), globalSalt...), password...))
+	chp := sha1.Sum(append(hp[:], entrySalt...))
+
+	// The entry salt is zero-padded to 20 bytes
+	pes := make([]byte, max(sha1.Size, len(entrySalt)))
+	copy(pes, entrySalt)
+
+	mac := func(data ...[]byte) []byte {
+		h := hmac.New(sha1.New, chp[:])
+		for _, d := range data {
+			h.Write(d)
+		}
+		return h.Sum(nil)
+	}
+	k1 := mac(pes, entrySalt)
+	tk := mac(pes)
+	k2 := mac(tk, entrySalt)
+
+	k := append(k1, k2...)
+	return k[:24], k[len(k)-8:]
+}
+
+func decrypt3DES(key, iv, data []byte) ([]byte, error) {
+	block, err := des.NewTripleDESCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return decryptCBC(block, iv, data)
+}
+
+func decryptAES(key, iv, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return decryptCBC(block, iv, data)
+}
+
+// decryptCBC decrypts CBC data and removes its PKCS#7 padding
+func decryptCBC(block cipher.Block, iv, data []byte) ([]byte, error) {
+	blockSize := block.BlockSize()
+	if len(iv) != blockSize {
+		return nil, fmt.Errorf("IV must be %d bytes", blockSize)
+	}
+	if len(data) == 0 || len(data)%blockSize != 0 {
+		return nil, errors.New("ciphertext is not a whole number of blocks")
+	}
+
+	plaintext := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, data)
+
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > blockSize ||
+		!bytes.Equal(plaintext[len(plaintext)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, errors.New("invalid padding")
+	}
+	return plaintext[:len(plaintext)-padding], nil
+}
@@ -0,0 +1,283 @@
+package firefox
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// The vectors below were generated outside Go, with Python's hashlib and the openssl
+// command line, following NSS's key4.db and secret decoder ring formats
+const (
+	// CKA_ID NSS gives its secret decoder ring key
+	testKeyID = "f8000000000000000000000000000001"
+
+	// A Firefox 75+ profile without a primary password: PBES2 entries with
+	// PBKDF2-HMAC-SHA256 and AES-256-CBC, and a 3DES login key
+	modernGlobalSalt = "5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a"
+	modernCheck      = "308181306d06092a864886f70d01050d3060304106092a864886f70d01050c30340420" +
+		"1111111111111111111111111111111111111111111111111111111111111111020101020120300a06082a86" +
+		"4886f70d0209301b060960864801650304012a040e22222222222222222222222222220410721e8774d91ed7" +
+		"90627d7e348ec33dcd"
+	modernKey = "308191306d06092a864886f70d01050d3060304106092a864886f70d01050c30340420" +
+		"333333333333333333333333333333333333333333333333333333333333333302010a020120300a06082a86" +
+		"4886f70d0209301b060960864801650304012a040e444444444444444444444444444404201d67d1bab3c214" +
+		"96bb64183db31c5bac7ef32b15b13f9e095a74b89aaead2310"
+	modern3DESKey  = "101112131415161718191a1b1c1d1e1f2021222324252627"
+	modernUsername = "MDIEEPgAAAAAAAAAAAAAAAAAAAEwFAYIKoZIhvcNAwcECAECAwQFBgcIBAjNtHVw68hE/w=="
+	modernPassword = "MDoEEPgAAAAAAAAAAAAAAAAAAAEwFAYIKoZIhvcNAwcECBESExQVFhcYBBBqtPqUx7gK54ImSNie/dKE"
+
+	// A Firefox 58-74 profile with the primary password "hunter2": pbeWithSHA1AndTripleDES-CBC
+	// entries, and an AES-256 login key
+	legacyGlobalSalt = "c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3"
+	legacyCheck      = "303c3028060b2a864886f70d010c050103301904146666666666666666666666666666" +
+		"666666666666020101041067d1ab714c2259c726c8f1105a339245"
+	legacyKey = "30543028060b2a864886f70d010c05010330190414777777777777777777777777777777" +
+		"777777777702010104285a815ba69f9cd8f85d1d84703fc4c00fe663281c68ff518f6bbf7967728ed095636e40" +
+		"0ff142f31e"
+	legacyAESKey      = "404142434445464748494a4b4c4d4e4f505152535455565758595a5b5c5d5e5f"
+	legacyUsername    = "MEMEEPgAAAAAAAAAAAAAAAAAAAEwHQYJYIZIAWUDBAEqBBCIiIiIiIiIiIiIiIiIiIiIBBBAiAV2LmMSnkX5IQ7vi/3q"
+	legacyPassword    = "MEMEEPgAAAAAAAAAAAAAAAAAAAEwHQYJYIZIAWUDBAEqBBCZmZmZmZmZmZmZmZmZmZmZBBBfRZ/jd0YshZ536PgxmsE5"
+	legacyPrimaryPass = "hunter2"
+
+	// A PBES2 password check with PBKDF2-HMAC-SHA1 defaults and a full 16-byte IV
+	fullIVCheck = "3064305006092a864886f70d01050d3043302206092a864886f70d01050c301504105555" +
+		"5555555555555555555555555555020102301d060960864801650304012a0410abababababababababababab" +
+		"abababab04101a1c73f4798c36c521da402010517780"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// writeKeyDB writes a key4.db holding a password check entry and one encrypted key
+func writeKeyDB(t *testing.T, dir, globalSalt, check, key string) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, keyDBFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	statements := []struct {
+		query string
+		args  []any
+	}{
+		{"CREATE TABLE metaData (id PRIMARY KEY UNIQUE ON CONFLICT REPLACE, item1, item2)", nil},
+		{"CREATE TABLE nssPrivate (id PRIMARY KEY UNIQUE ON CONFLICT ABORT, a11, a102)", nil},
+		{"INSERT INTO metaData VALUES ('password', ?, ?)", []any{unhex(t, globalSalt), unhex(t, check)}},
+		{"INSERT INTO nssPrivate VALUES (1, ?, ?)", []any{unhex(t, key), unhex(t, testKeyID)}},
+	}
+	for _, s := range statements {
+		if _, err := db.Exec(s.query, s.args...); err != nil {
+			t.Fatalf("%s: %v", s.query, err)
+		}
+	}
+}
+
+// writeLogins writes a logins.json with a single saved login
+func writeLogins(t *testing.T, dir, hostname, username, password string) {
+	t.Helper()
+
+	content, err := json.Marshal(map[string]any{
+		"nextId": 2,
+		"logins": []map[string]any{{
+			"id":                  1,
+			"hostname":            hostname,
+			"httpRealm":           nil,
+			"formSubmitURL":       hostname + "/login",
+			"encryptedUsername":   username,
+			"encryptedPassword":   password,
+			"timeCreated":         1700000000000,
+			"timeLastUsed":        1700000100000,
+			"timePasswordChanged": 1700000000000,
+			"timesUsed":           3,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, loginsFile), content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeriveLegacy3DESKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		password  string
+		entrySalt string
+		key, iv   string
+	}{
+		{"no primary password", "", "000102030405060708090a0b0c0d0e0f10111213",
+			"344c4227eac11e01912da9270bab0ab53d9dd5557fe14f3d", "aaa0735a54136d4d"},
+		{"short entry salt", "hunter2", "000102030405060708090a0b0c0d0e0f",
+			"1c7858f85b7aed7a17085e03675181c995905f99d9762a96", "6a975ec3058e219b"},
+	}
+	globalSalt := unhex(t, strings.Repeat("a1", 20))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, iv := deriveLegacy3DESKey(globalSalt, []byte(tt.password), unhex(t, tt.entrySalt))
+			if got := hex.EncodeToString(key); got != tt.key {
+				t.Errorf("key = %s, want %s", got, tt.key)
+			}
+			if got := hex.EncodeToString(iv); got != tt.iv {
+				t.Errorf("iv = %s, want %s", got, tt.iv)
+			}
+		})
+	}
+}
+
+func TestDecryptEntry(t *testing.T) {
+	tests := []struct {
+		name       string
+		globalSalt string
+		password   string
+		entry      string
+		want       string
+		wantErr    bool
+	}{
+		// NSS's AES IV is 14 bytes; the real IV starts with its DER header, 04 0e
+		{"PBES2 with a 14-byte IV", modernGlobalSalt, "", modernCheck, hex.EncodeToString([]byte(passwordCheck)), false},
+		{"PBES2 with a 16-byte IV", modernGlobalSalt, "", fullIVCheck, hex.EncodeToString([]byte(passwordCheck)), false},
+		{"PBES2 key", modernGlobalSalt, "", modernKey, modern3DESKey, false},
+		{"legacy 3DES", legacyGlobalSalt, legacyPrimaryPass, legacyCheck, hex.EncodeToString([]byte(passwordCheck)), false},
+		{"legacy 3DES key", legacyGlobalSalt, legacyPrimaryPass, legacyKey, legacyAESKey, false},
+		{"PBES2 with the wrong password", modernGlobalSalt, "hunter2", modernCheck, "", true},
+		{"legacy 3DES without the password", legacyGlobalSalt, "", legacyCheck, "", true},
+		{"not an entry", modernGlobalSalt, "", "0102", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decryptEntry(unhex(t, tt.globalSalt), []byte(tt.password), unhex(t, tt.entry))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %x, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decryptEntry: %v", err)
+			}
+			if hex.EncodeToString(got) != tt.want {
+				t.Errorf("got %x, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyStoreDecrypt(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"3DES", modern3DESKey, modernUsername, "alice", false},
+		{"AES-256", legacyAESKey, legacyPassword, "Tr0ub4dor&3", false},
+		{"key too short for AES-256", modern3DESKey, legacyUsername, "", true},
+		{"wrong key", legacyAESKey[:48], modernPassword, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ks := &KeyStore{keys: map[string][]byte{string(unhex(t, testKeyID)): unhex(t, tt.key)}}
+			got, err := ks.decryptBase64(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decrypt: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("unknown key ID", func(t *testing.T) {
+		ks := &KeyStore{keys: map[string][]byte{"other": unhex(t, modern3DESKey)}}
+		if _, err := ks.decryptBase64(modernUsername); err == nil {
+			t.Error("got no error")
+		}
+	})
+}
+
+func TestReadLogins(t *testing.T) {
+	tests := []struct {
+		name               string
+		globalSalt         string
+		check, key         string
+		username, password string
+		primaryPassword    string
+		wantUser, wantPass string
+		wantErr            error
+	}{
+		{"no primary password", modernGlobalSalt, modernCheck, modernKey, modernUsername, modernPassword,
+			"", "alice", "correct horse", nil},
+		{"primary password", legacyGlobalSalt, legacyCheck, legacyKey, legacyUsername, legacyPassword,
+			legacyPrimaryPass, "bob", "Tr0ub4dor&3", nil},
+		{"missing primary password", legacyGlobalSalt, legacyCheck, legacyKey, legacyUsername, legacyPassword,
+			"", "", "", ErrPrimaryPassword},
+		{"wrong primary password", modernGlobalSalt, modernCheck, modernKey, modernUsername, modernPassword,
+			legacyPrimaryPass, "", "", ErrPrimaryPassword},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeKeyDB(t, dir, tt.globalSalt, tt.check, tt.key)
+			writeLogins(t, dir, "https://example.com", tt.username, tt.password)
+
+			logins, errs, err := ReadLogins(dir, tt.primaryPassword)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(errs) > 0 {
+				t.Errorf("login errors: %v", errs)
+			}
+			if len(logins) != 1 {
+				t.Fatalf("got %d logins, want 1", len(logins))
+			}
+			l := logins[0]
+			if l.Username != tt.wantUser || l.Password != tt.wantPass {
+				t.Errorf("got %q/%q, want %q/%q", l.Username, l.Password, tt.wantUser, tt.wantPass)
+			}
+			if l.SignonRealm != "https://example.com/" || l.ActionURL != "https://example.com/login" {
+				t.Errorf("got realm %q, action %q", l.SignonRealm, l.ActionURL)
+			}
+			if l.DateCreated != 1700000000 || l.DateLastUsed != 1700000100 || l.TimesUsed != 3 {
+				t.Errorf("got created %d, last used %d, used %d times", l.DateCreated, l.DateLastUsed, l.TimesUsed)
+			}
+		})
+	}
+
+	t.Run("key3.db", func(t *testing.T) {
+		dir := t.TempDir()
+		writeLogins(t, dir, "https://example.com", modernUsername, modernPassword)
+		if err := os.WriteFile(filepath.Join(dir, "key3.db"), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := ReadLogins(dir, ""); err == nil || !strings.Contains(err.Error(), "key3.db") {
+			t.Errorf("got error %v, want key3.db unsupported", err)
+		}
+	})
+}
@@ -0,0 +1,174 @@
+package firefox
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+
+	"go-cookie-monster/pkg/decrypt"
+)
+
+// ReadHistory reads the visited URLs, individual visits and bookmarks of a profile's
+// places.sqlite. Times are converted from PRTime (microseconds) to Unix seconds.
+func ReadHistory(profileDir string) (*decrypt.HistoryJSONFormatter, error) {
+	db, cleanup, err := openDatabase(filepath.Join(profileDir, placesFile))
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	history := &decrypt.HistoryJSONFormatter{}
+	if history.URLs, err = readPlaces(db); err != nil {
+		return nil, err
+	}
+	if history.Visits, err = readVisits(db); err != nil {
+		return nil, err
+	}
+	if history.Bookmarks, err = readBookmarks(db); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func readPlaces(db *sql.DB) ([]decrypt.HistoryURL, error) {
+	rows, err := db.Query(`SELECT url, COALESCE(title, ''), visit_count, COALESCE(last_visit_date, 0)
+		FROM moz_places WHERE visit_count > 0 ORDER BY last_visit_date DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var urls []decrypt.HistoryURL
+	for rows.Next() {
+		var u decrypt.HistoryURL
+		if err := rows.Scan(&u.URL, &u.Title, &u.VisitCount, &u.LastVisit); err != nil {
+			return nil, err
+		}
+		u.LastVisit /= 1000000
+		urls = append(urls, u)
+	}
+	return urls, rows.Err()
+}
+
+func readVisits(db *sql.DB) ([]decrypt.HistoryVisit, error) {
+	rows, err := db.Query(`SELECT p.url, COALESCE(p.title, ''), v.visit_date, v.visit_type
+		FROM moz_historyvisits v JOIN moz_places p ON p.id = v.place_id ORDER BY v.visit_date DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var visits []decrypt.HistoryVisit
+	for rows.Next() {
+		var (
+			v         decrypt.HistoryVisit
+			visitType int
+		)
+		if err := rows.Scan(&v.URL, &v.Title, &v.VisitTime, &visitType); err != nil {
+			return nil, err
+		}
+		v.VisitTime /= 1000000
+		v.Transition = convertVisitType(visitType)
+		visits = append(visits, v)
+	}
+	return visits, rows.Err()
+}
+
+// readBookmarks lists the bookmarked URLs with the path of folders above them. Entries
+// under the tags root are tags rather than bookmarks and are skipped.
+func readBookmarks(db *sql.DB) ([]decrypt.Bookmark, error) {
+	rows, err := db.Query(`SELECT b.id, b.type, b.parent, COALESCE(b.title, ''), COALESCE(b.guid, ''),
+		COALESCE(p.url, ''), COALESCE(b.dateAdded, 0), COALESCE(b.lastModified, 0)
+		FROM moz_bookmarks b LEFT JOIN moz_places p ON p.id = b.fk ORDER BY b.parent, b.position`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type node struct {
+		parent int64
+		title  string
+		guid   string
+	}
+	var (
+		nodes     = make(map[int64]node)
+		bookmarks []decrypt.Bookmark
+		parents   []int64
+	)
+	for rows.Next() {
+		var (
+			id, parent  int64
+			itemType    int
+			title, guid string
+			b           decrypt.Bookmark
+		)
+		if err := rows.Scan(&id, &itemType, &parent, &title, &guid, &b.URL, &b.DateAdded, &b.DateModified); err != nil {
+			return nil, err
+		}
+		nodes[id] = node{parent: parent, title: title, guid: guid}
+		if itemType != bookmarkTypeURL {
+			continue
+		}
+		b.Title = title
+		b.DateAdded /= 1000000
+		b.DateModified /= 1000000
+		bookmarks = append(bookmarks, b)
+		parents = append(parents, parent)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var out []decrypt.Bookmark
+	for i, b := range bookmarks {
+		var (
+			folders []string
+			isTag   bool
+		)
+		// Walk up to the root, guarding against loops in a damaged database
+		for id, depth := parents[i], 0; id != 0 && depth < len(nodes); depth++ {
+			n, ok := nodes[id]
+			if !ok {
+				break
+			}
+			if n.guid == tagsRootGUID {
+				isTag = true
+				break
+			}
+			if n.title != "" {
+				folders = append([]string{n.title}, folders...)
+			}
+			id = n.parent
+		}
+		if isTag {
+			continue
+		}
+		b.Folder = strings.Join(folders, "/")
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+// convertVisitType maps nsINavHistoryService TRANSITION_* values to Chromium's transition names
+func convertVisitType(visitType int) string {
+	switch visitType {
+	case 1:
+		return "link"
+	case 2:
+		return "typed"
+	case 3:
+		return "auto_bookmark"
+	case 4:
+		return "auto_subframe"
+	case 5, 6:
+		return "redirect"
+	case 7:
+		return "download"
+	case 8:
+		return "manual_subframe"
+	case 9:
+		return "reload"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,178 @@
+package firefox
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go-cookie-monster/pkg/decrypt"
+	"go-cookie-monster/pkg/webstorage"
+)
+
+func TestReadHistory(t *testing.T) {
+	dir := t.TempDir()
+	writeDatabase(t, filepath.Join(dir, placesFile),
+		`CREATE TABLE moz_places (id INTEGER PRIMARY KEY, url LONGVARCHAR, title LONGVARCHAR,
+			visit_count INTEGER DEFAULT 0, last_visit_date INTEGER, guid TEXT)`,
+		`CREATE TABLE moz_historyvisits (id INTEGER PRIMARY KEY, from_visit INTEGER, place_id INTEGER,
+			visit_date INTEGER, visit_type INTEGER, session INTEGER)`,
+		`CREATE TABLE moz_bookmarks (id INTEGER PRIMARY KEY, type INTEGER, fk INTEGER DEFAULT NULL,
+			parent INTEGER, position INTEGER, title LONGVARCHAR, keyword_id INTEGER, folder_type TEXT,
+			dateAdded INTEGER, lastModified INTEGER, guid TEXT)`,
+		`INSERT INTO moz_places VALUES
+			(1, 'https://example.com/', 'Example', 2, 1700000100000000, 'place1'),
+			(2, 'https://example.org/', NULL, 1, 1700000000000000, 'place2'),
+			(3, 'https://bookmarked.example/', 'Never visited', 0, NULL, 'place3')`,
+		`INSERT INTO moz_historyvisits VALUES
+			(1, 0, 1, 1700000050000000, 2, 0),
+			(2, 0, 2, 1700000000000000, 5, 0),
+			(3, 1, 1, 1700000100000000, 1, 0)`,
+		// The tag entry under tags/news is a tag of example.org, not a bookmark
+		`INSERT INTO moz_bookmarks VALUES
+			(1, 2, NULL, 0, 0, '', NULL, NULL, 1600000000000000, 1600000000000000, 'root________'),
+			(2, 2, NULL, 1, 0, 'menu', NULL, NULL, 1600000000000000, 1600000000000000, 'menu________'),
+			(3, 2, NULL, 1, 1, 'toolbar', NULL, NULL, 1600000000000000, 1600000000000000, 'toolbar_____'),
+			(4, 2, NULL, 1, 2, 'tags', NULL, NULL, 1600000000000000, 1600000000000000, 'tagsfolder_____'),
+			(5, 2, NULL, 3, 0, 'Work', NULL, NULL, 1600000000000000, 1600000000000000, 'folder5'),
+			(6, 1, 1, 5, 0, 'Example', NULL, NULL, 1650000000000000, 1660000000000000, 'bookmark6'),
+			(7, 2, NULL, 4, 0, 'news', NULL, NULL, 1600000000000000, 1600000000000000, 'tag7'),
+			(8, 1, 2, 7, 0, NULL, NULL, NULL, 1600000000000000, 1600000000000000, 'tag8'),
+			(9, 1, 3, 2, 0, 'Bookmarked', NULL, NULL, 1640000000000000, 1640000000000000, 'bookmark9')`,
+	)
+
+	history, err := ReadHistory(dir)
+	if err != nil {
+		t.Fatalf("ReadHistory: %v", err)
+	}
+
+	wantURLs := []decrypt.HistoryURL{
+		{URL: "https://example.com/", Title: "Example", VisitCount: 2, LastVisit: 1700000100},
+		{URL: "https://example.org/", VisitCount: 1, LastVisit: 1700000000},
+	}
+	if !reflect.DeepEqual(history.URLs, wantURLs) {
+		t.Errorf("URLs:\n got %+v\nwant %+v", history.URLs, wantURLs)
+	}
+
+	wantVisits := []decrypt.HistoryVisit{
+		{URL: "https://example.com/", Title: "Example", VisitTime: 1700000100, Transition: "link"},
+		{URL: "https://example.com/", Title: "Example", VisitTime: 1700000050, Transition: "typed"},
+		{URL: "https://example.org/", VisitTime: 1700000000, Transition: "redirect"},
+	}
+	if !reflect.DeepEqual(history.Visits, wantVisits) {
+		t.Errorf("visits:\n got %+v\nwant %+v", history.Visits, wantVisits)
+	}
+
+	wantBookmarks := []decrypt.Bookmark{
+		{Title: "Bookmarked", URL: "https://bookmarked.example/", Folder: "menu",
+			DateAdded: 1640000000, DateModified: 1640000000},
+		{Title: "Example", URL: "https://example.com/", Folder: "toolbar/Work",
+			DateAdded: 1650000000, DateModified: 1660000000},
+	}
+	if !reflect.DeepEqual(history.Bookmarks, wantBookmarks) {
+		t.Errorf("bookmarks:\n got %+v\nwant %+v", history.Bookmarks, wantBookmarks)
+	}
+}
+
+func TestReadFormHistory(t *testing.T) {
+	dir := t.TempDir()
+	writeDatabase(t, filepath.Join(dir, formHistoryFile),
+		`CREATE TABLE moz_formhistory (id INTEGER PRIMARY KEY, fieldname TEXT NOT NULL, value TEXT NOT NULL,
+			timesUsed INTEGER, firstUsed INTEGER, lastUsed INTEGER, guid TEXT)`,
+		`INSERT INTO moz_formhistory VALUES
+			(1, 'email', 'alice@example.com', 4, 1600000000000000, 1700000000000000, 'a'),
+			(2, 'searchbar-history', 'cookies', 1, 1650000000000000, 1650000000000000, 'b')`,
+	)
+
+	entries, err := ReadFormHistory(dir)
+	if err != nil {
+		t.Fatalf("ReadFormHistory: %v", err)
+	}
+	want := []decrypt.Autofill{
+		{Name: "email", Value: "alice@example.com", DateCreated: 1600000000, DateLastUsed: 1700000000, Count: 4},
+		{Name: "searchbar-history", Value: "cookies", DateCreated: 1650000000, DateLastUsed: 1650000000, Count: 1},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("got %+v, want %+v", entries, want)
+	}
+}
+
+func TestReadLocalStorage(t *testing.T) {
+	dir := t.TempDir()
+	storageDir := filepath.Join(dir, filepath.FromSlash(storageDefaultDir))
+
+	// "abcabcabcabc" compressed with snappy: a 3-byte literal and a 9-byte copy at offset 3
+	compressed := "X'0c086162631503'"
+	// "héllo" as raw UTF-16
+	utf16 := "X'6800e9006c006c006f00'"
+	writeDatabase(t, filepath.Join(storageDir, "https+++example.com", filepath.FromSlash(localStorageFile)),
+		`CREATE TABLE database (origin TEXT NOT NULL, usage INTEGER NOT NULL DEFAULT 0,
+			last_vacuum_time INTEGER NOT NULL DEFAULT 0, last_analyze_time INTEGER NOT NULL DEFAULT 0,
+			last_vacuum_size INTEGER NOT NULL DEFAULT 0)`,
+		`CREATE TABLE data (key TEXT PRIMARY KEY, utf16_length INTEGER NOT NULL, conversion_type INTEGER NOT NULL,
+			compression_type INTEGER NOT NULL, last_access_time INTEGER NOT NULL DEFAULT 0, value BLOB NOT NULL)`,
+		`INSERT INTO database (origin) VALUES ('https://example.com')`,
+		`INSERT INTO data (key, utf16_length, conversion_type, compression_type, value) VALUES
+			('theme', 4, 1, 0, CAST('dark' AS BLOB)),
+			('cache', 12, 1, 1, `+compressed+`),
+			('name', 5, 0, 0, `+utf16+`),
+			('broken', 4, 1, 1, X'ff')`,
+	)
+	// The first schema had a compressed flag and no database table
+	writeDatabase(t, filepath.Join(storageDir, "https+++example.org+8443^userContextId=1", filepath.FromSlash(localStorageFile)),
+		`CREATE TABLE data (key TEXT PRIMARY KEY, value TEXT NOT NULL, compressed INTEGER NOT NULL DEFAULT 0,
+			lastAccessTime INTEGER NOT NULL DEFAULT 0)`,
+		`INSERT INTO data (key, value) VALUES ('token', 'xyz')`,
+	)
+	writeFile(t, filepath.Join(storageDir, "https+++damaged.example", filepath.FromSlash(localStorageFile)), "not a database")
+	writeFile(t, filepath.Join(storageDir, "moz-extension+++0123", "idb", "store.sqlite"), "")
+
+	records, err := ReadLocalStorage(dir)
+	if err != nil {
+		t.Fatalf("ReadLocalStorage: %v", err)
+	}
+
+	got := make(map[string]webstorage.Record)
+	for _, r := range records {
+		if r.StorageType != webstorage.LocalStorage {
+			t.Errorf("%s: got storage type %q", r.Origin, r.StorageType)
+		}
+		key, _ := r.Key.(string)
+		got[r.Origin+" "+key] = r
+	}
+	if len(got) != len(records) || len(records) != 6 {
+		t.Errorf("got %d records: %+v", len(records), records)
+	}
+	for _, want := range []struct{ origin, key, value string }{
+		{"https://example.com", "theme", "dark"},
+		{"https://example.com", "cache", "abcabcabcabc"},
+		{"https://example.com", "name", "héllo"},
+		{"https://example.org:8443^userContextId=1", "token", "xyz"},
+	} {
+		r, ok := got[want.origin+" "+want.key]
+		if !ok {
+			t.Errorf("%s %s: missing", want.origin, want.key)
+		} else if r.Value != want.value || r.Error != "" {
+			t.Errorf("%s %s: got %q (%s), want %q", want.origin, want.key, r.Value, r.Error, want.value)
+		}
+	}
+	if r := got["https://example.com broken"]; r.Error == "" || r.Value != "ff" {
+		t.Errorf("undecodable value: got %+v", r)
+	}
+	if r := got["https://damaged.example "]; r.Error == "" {
+		t.Errorf("damaged database: got %+v", r)
+	}
+}
+
+func TestDecodeOriginDir(t *testing.T) {
+	tests := map[string]string{
+		"https+++example.com":                 "https://example.com",
+		"http+++localhost+8080":               "http://localhost:8080",
+		"https+++example.com^userContextId=2": "https://example.com^userContextId=2",
+		"chrome":                              "chrome",
+	}
+	for name, want := range tests {
+		if got := decodeOriginDir(name); got != want {
+			t.Errorf("decodeOriginDir(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package firefox
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FindProfiles returns the profiles listed in a Firefox directory's profiles.ini. Profiles
+// whose directory no longer exists are skipped. The default profile is the one installs.ini
+// (or an [Install...] section of profiles.ini) points at, else the one marked Default=1.
+func FindProfiles(firefoxDir string) ([]Profile, error) {
+	sections, err := readINI(filepath.Join(firefoxDir, profilesFile))
+	if err != nil {
+		return nil, err
+	}
+
+	// Install defaults are stored as the profile's Path value
+	installDefaults := make(map[string]bool)
+	addInstall := func(section iniSection) {
+		if path := section.Values["Default"]; path != "" {
+			installDefaults[filepath.ToSlash(path)] = true
+		}
+	}
+	for _, section := range sections {
+		if strings.HasPrefix(section.Name, "Install") {
+			addInstall(section)
+		}
+	}
+	installs, _ := readINI(filepath.Join(firefoxDir, installsFile))
+	for _, section := range installs {
+		addInstall(section)
+	}
+
+	var profiles []Profile
+	for _, section := range sections {
+		if !strings.HasPrefix(section.Name, "Profile") || section.Values["Path"] == "" {
+			continue
+		}
+
+		rawPath := section.Values["Path"]
+		path := rawPath
+		if section.Values["IsRelative"] == "1" {
+			path = filepath.Join(firefoxDir, filepath.FromSlash(rawPath))
+		}
+		if info, err := os.Stat(path); err != nil || !info.IsDir() {
+			continue
+		}
+
+		isDefault := installDefaults[filepath.ToSlash(rawPath)]
+		if len(installDefaults) == 0 {
+			isDefault = section.Values["Default"] == "1"
+		}
+		profiles = append(profiles, Profile{
+			Name:    section.Values["Name"],
+			Path:    path,
+			Default: isDefault,
+		})
+	}
+	if len(profiles) == 0 {
+		return nil, ErrNoProfiles
+	}
+
+	// Default profiles first, then by name
+	sort.SliceStable(profiles, func(i, j int) bool {
+		if profiles[i].Default != profiles[j].Default {
+			return profiles[i].Default
+		}
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles, nil
+}
+
+// IsFirefoxDir reports whether dir is a Firefox root directory (one holding profiles.ini)
+func IsFirefoxDir(dir string) bool {
+	info, err := os.Stat(filepath.Join(dir, profilesFile))
+	return err == nil && !info.IsDir()
+}
+
+// readINI parses an INI file into its sections, in file order
+func readINI(path string) ([]iniSection, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+
+	var sections []iniSection
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "", strings.HasPrefix(line, ";"), strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			sections = append(sections, iniSection{
+				Name:   line[1 : len(line)-1],
+				Values: make(map[string]string),
+			})
+		case len(sections) > 0:
+			if key, value, ok := strings.Cut(line, "="); ok {
+				sections[len(sections)-1].Values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+			}
+		}
+	}
+
+	return sections, scanner.Err()
+}
@@ -0,0 +1,191 @@
+package firefox
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go-cookie-monster/pkg/decrypt"
+	"go-cookie-monster/pkg/lz4"
+)
+
+// sessionFiles are the session store copies Firefox keeps in a profile: sessionstore.jsonlz4
+// is written on shutdown, recovery.jsonlz4 (and the previous write, recovery.baklz4) every
+// few seconds while Firefox is running
+var sessionFiles = []string{
+	sessionFile,
+	filepath.Join("sessionstore-backups", "recovery.jsonlz4"),
+	filepath.Join("sessionstore-backups", "recovery.baklz4"),
+	filepath.Join("sessionstore-backups", "previous.jsonlz4"),
+}
+
+// ReadSession parses the most recently written session store of a profile
+func ReadSession(profileDir string) (*SessionJSONFormatter, error) {
+	type candidate struct {
+		path     string
+		modified int64
+	}
+	var candidates []candidate
+	for _, name := range sessionFiles {
+		path := filepath.Join(profileDir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			candidates = append(candidates, candidate{path, info.ModTime().UnixNano()})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fs.ErrNotExist
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].modified > candidates[j].modified
+	})
+
+	data, err := os.ReadFile(candidates[0].path)
+	if err != nil {
+		return nil, err
+	}
+	session, err := ParseSession(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", candidates[0].path, err)
+	}
+	session.Source = candidates[0].path
+	return session, nil
+}
+
+// ParseSession parses a mozLz4-compressed (or plain JSON) session store into its windows,
+// tabs, form data and session cookies
+func ParseSession(data []byte) (*SessionJSONFormatter, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		decoded, err := lz4.DecodeMozLz4(data)
+		if err != nil {
+			return nil, err
+		}
+		data = decoded
+	}
+
+	var store sessionStore
+	if err := json.Unmarshal(data, &store); err != nil {
+		return nil, fmt.Errorf("failed to parse session store: %v", err)
+	}
+
+	session := &SessionJSONFormatter{}
+	cookies := store.Cookies
+	for _, w := range store.Windows {
+		session.Windows = append(session.Windows, convertWindow(w))
+		// Firefox 60 and older kept session cookies per window
+		cookies = append(cookies, w.Cookies...)
+	}
+	for _, w := range store.ClosedWindows {
+		session.ClosedWindows = append(session.ClosedWindows, convertWindow(w))
+	}
+	for _, sc := range cookies {
+		session.Cookies = append(session.Cookies, convertSessionCookie(sc))
+	}
+
+	return session, nil
+}
+
+func convertWindow(w sessionWindow) SessionWindow {
+	window := SessionWindow{ClosedAt: w.ClosedAt}
+	for _, t := range w.Tabs {
+		window.Tabs = append(window.Tabs, convertTab(t))
+	}
+	for _, closed := range w.ClosedTabs {
+		tab := convertTab(closed.State)
+		tab.ClosedAt = closed.ClosedAt
+		window.ClosedTabs = append(window.ClosedTabs, tab)
+	}
+	return window
+}
+
+// convertTab flattens a tab's history; its current page is entries[index-1]
+func convertTab(t sessionTab) SessionTab {
+	tab := SessionTab{
+		StoreID:      storeID(t.UserContextID, 0),
+		Pinned:       t.Pinned,
+		Hidden:       t.Hidden,
+		LastAccessed: t.LastAccessed,
+	}
+
+	var walk func(entries []sessionEntry)
+	walk = func(entries []sessionEntry) {
+		for _, e := range entries {
+			tab.History = append(tab.History, SessionEntry{URL: e.URL, Title: e.Title})
+			tab.FormData = append(tab.FormData, convertForm(e.FormData, e.URL)...)
+			walk(e.Children)
+		}
+	}
+	walk(t.Entries)
+	tab.FormData = append(tab.FormData, convertForm(t.FormData, "")...)
+
+	if current := t.Index - 1; current >= 0 && current < len(t.Entries) {
+		tab.URL = t.Entries[current].URL
+		tab.Title = t.Entries[current].Title
+	} else if len(t.Entries) > 0 {
+		tab.URL = t.Entries[len(t.Entries)-1].URL
+		tab.Title = t.Entries[len(t.Entries)-1].Title
+	}
+	return tab
+}
+
+// convertForm lists the fields of a saved form, sorted for stable output
+func convertForm(form *sessionForm, pageURL string) []FormField {
+	if form == nil {
+		return nil
+	}
+	if form.URL != "" {
+		pageURL = form.URL
+	}
+
+	var fields []FormField
+	for id, value := range form.ID {
+		fields = append(fields, FormField{URL: pageURL, ID: id, Value: value})
+	}
+	for xpath, value := range form.XPath {
+		fields = append(fields, FormField{URL: pageURL, XPath: xpath, Value: value})
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		if fields[i].ID != fields[j].ID {
+			return fields[i].ID < fields[j].ID
+		}
+		return fields[i].XPath < fields[j].XPath
+	})
+	return fields
+}
+
+// convertSessionCookie converts a session store cookie. Only session cookies are saved there,
+// so they have no expiry.
+func convertSessionCookie(sc sessionCookie) decrypt.Cookie {
+	c := decrypt.Cookie{
+		Name:         sc.Name,
+		Value:        sc.Value,
+		Domain:       sc.Host,
+		Path:         sc.Path,
+		Secure:       sc.Secure,
+		HTTPOnly:     sc.HTTPOnly,
+		SameSite:     convertSameSite(sc.SameSite),
+		SourceScheme: convertSchemeMap(sc.SchemeMap),
+		SourcePort:   -1,
+		Priority:     "Medium",
+		Session:      true,
+		HostOnly:     !strings.HasPrefix(sc.Host, "."),
+	}
+	if c.Path == "" {
+		c.Path = "/"
+	}
+	sc.OriginAttributes.apply(&c)
+	return c
+}
+
+// Format formats the session as indented JSON
+func (f *SessionJSONFormatter) Format() (string, error) {
+	jsonData, err := json.MarshalIndent(f, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
@@ -0,0 +1,141 @@
+package firefox
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// sampleSession is a sessionstore.jsonlz4 compressed with the lz4 command line tool: two
+// windows (one closed), a closed tab, form data in a page and a subframe, a container tab,
+// and session cookies both at the top level and in a window as Firefox 60 kept them
+const sampleSession = "" +
+	"bW96THo0MABiBAAA8AF7InZlcnNpb24iOlsic2VzCwDyDXJlc3RvcmUiLDFdLCJ3aW5kb3dzIjpbeyJ0YWIJAGJl" +
+	"bnRyaWUMAPIXdXJsIjoiaHR0cHM6Ly9leGFtcGxlLmNvbS8iLCJ0aXRsZSI6IkUXAD8ifSwxAAlebG9naW42ABQg" +
+	"GACvZm9ybWRhdGEiOkYAESFpZCgAoHNlciI6ImFsaWN/AGAieHBhdGgZALAvaHRtbC9ib2R5L1gA9icvaW5wdXRb" +
+	"Ml0iOiJyZW1lbWJlciJ9fX1dLCJpbmRleCI6MiwibGFzdEFjY2Vzc2VkIjoxNzABAGAsInBpbm4XAFF0cnVlLHgA" +
+	"gENvbnRleHRJLAAvfSw3ARE/b3JnNwEA/wAgb3JnIiwiY2hpbGRyZW5AAAxXZnJhbWVFABNGEAAIPgECHAGhcSI6" +
+	"InNlYXJjaOoAB+wAlDEsImhpZGRlbs8AD/oABEAxMDAwNwCEX2Nsb3NlZFQhAn9zdGF0ZSI68wAJAjIAEy66AAj2" +
+	"ABFDGgAaIo8AC4EAIzY5AQAAgQAiLCJNACZBdJsAIzUwmwBEY29va4IA1Ghvc3QiOiJsZWdhY3l7AEAiLCJuIgHA" +
+	"OiJvbGQiLCJ2YWx1DgBCMSIsIi0CVyIvIn1d5wAfVxQDIz9uZXTdAQE5bmV07AAvfV3QAAEfNtAACBcu/wIGzgAD" +
+	"zgMH0gBoczNjcjN01wCELCJzZWN1cmXnAQCzAERvbmx5EACBc2FtZVNpdGWDAZFzY2hlbWVNYXAMA+BvcmlnaW5B" +
+	"dHRyaWJ1dJEAHHv1AlAxfX1dfQ=="
+
+func decodeSample(t *testing.T) []byte {
+	t.Helper()
+	data, err := base64.StdEncoding.DecodeString(sampleSession)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseSession(t *testing.T) {
+	session, err := ParseSession(decodeSample(t))
+	if err != nil {
+		t.Fatalf("ParseSession: %v", err)
+	}
+
+	wantWindows := []SessionWindow{{
+		Tabs: []SessionTab{
+			{
+				URL:          "https://example.com/login",
+				Title:        "Example login",
+				StoreID:      containerStoreID + "1",
+				Pinned:       true,
+				LastAccessed: 1700000000000,
+				History: []SessionEntry{
+					{URL: "https://example.com/", Title: "Example"},
+					{URL: "https://example.com/login", Title: "Example login"},
+				},
+				FormData: []FormField{
+					{URL: "https://example.com/login", XPath: "/html/body/form/input[2]", Value: "remember"},
+					{URL: "https://example.com/login", ID: "user", Value: "alice"},
+				},
+			},
+			{
+				URL:          "https://example.org/",
+				Title:        "Example org",
+				StoreID:      defaultStoreID,
+				Hidden:       true,
+				LastAccessed: 1700000001000,
+				History: []SessionEntry{
+					{URL: "https://example.org/", Title: "Example org"},
+					{URL: "https://example.org/frame", Title: "Frame"},
+				},
+				FormData: []FormField{{URL: "https://example.org/frame", ID: "q", Value: "search"}},
+			},
+		},
+		ClosedTabs: []SessionTab{{
+			URL:          "https://closed.example/",
+			Title:        "Closed",
+			StoreID:      defaultStoreID,
+			LastAccessed: 1699999999000,
+			ClosedAt:     1700000050000,
+			History:      []SessionEntry{{URL: "https://closed.example/", Title: "Closed"}},
+		}},
+	}}
+	if !reflect.DeepEqual(session.Windows, wantWindows) {
+		t.Errorf("windows:\n got %+v\nwant %+v", session.Windows, wantWindows)
+	}
+
+	if len(session.ClosedWindows) != 1 || session.ClosedWindows[0].ClosedAt != 1700000060000 ||
+		len(session.ClosedWindows[0].Tabs) != 1 || session.ClosedWindows[0].Tabs[0].URL != "https://example.net/" {
+		t.Errorf("closed windows: got %+v", session.ClosedWindows)
+	}
+
+	if len(session.Cookies) != 2 {
+		t.Fatalf("got %d cookies, want 2", len(session.Cookies))
+	}
+	c := session.Cookies[0]
+	if c.Name != "session" || c.Value != "s3cr3t" || c.Domain != ".example.com" || c.HostOnly ||
+		!c.Secure || !c.HTTPOnly || !c.Session || c.SameSite != "lax" || c.SourceScheme != "Secure" ||
+		c.StoreID != containerStoreID+"1" {
+		t.Errorf("session cookie: got %+v", c)
+	}
+	if c := session.Cookies[1]; c.Name != "old" || c.Path != "/" || !c.HostOnly || c.StoreID != defaultStoreID {
+		t.Errorf("window cookie: got %+v", c)
+	}
+
+	if _, err := ParseSession([]byte("mozLz40\x00\x10")); err == nil {
+		t.Error("truncated session store: got no error")
+	}
+}
+
+func TestReadSession(t *testing.T) {
+	dir := t.TempDir()
+	recovery := filepath.Join(dir, "sessionstore-backups", "recovery.jsonlz4")
+	writeFile(t, recovery, string(decodeSample(t)))
+	// An older plain JSON session store from the last shutdown
+	shutdown := filepath.Join(dir, sessionFile)
+	writeFile(t, shutdown, `{"windows":[{"tabs":[{"entries":[{"url":"https://old.example/"}],"index":1}]}]}`)
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(shutdown, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := ReadSession(dir)
+	if err != nil {
+		t.Fatalf("ReadSession: %v", err)
+	}
+	if session.Source != recovery {
+		t.Errorf("got source %s, want the newer %s", session.Source, recovery)
+	}
+
+	if err := os.Remove(recovery); err != nil {
+		t.Fatal(err)
+	}
+	if session, err = ReadSession(dir); err != nil {
+		t.Fatalf("ReadSession: %v", err)
+	}
+	if session.Source != shutdown || session.Windows[0].Tabs[0].URL != "https://old.example/" {
+		t.Errorf("got %s with %+v", session.Source, session.Windows)
+	}
+
+	if _, err := ReadSession(t.TempDir()); !os.IsNotExist(err) {
+		t.Errorf("empty profile: got error %v, want not exist", err)
+	}
+}
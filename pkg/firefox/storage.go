@@ -0,0 +1,149 @@
+package firefox
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"go-cookie-monster/pkg/snappy"
+	"go-cookie-monster/pkg/webstorage"
+)
+
+// ReadLocalStorage reads the localStorage of every origin in a profile, from the
+// storage/default/<origin>/ls/data.sqlite databases. Origins whose database cannot be read
+// are returned as a record with Error set.
+func ReadLocalStorage(profileDir string) ([]webstorage.Record, error) {
+	storageDir := filepath.Join(profileDir, filepath.FromSlash(storageDefaultDir))
+	entries, err := os.ReadDir(storageDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []webstorage.Record
+	for _, entry := range entries {
+		path := filepath.Join(storageDir, entry.Name(), filepath.FromSlash(localStorageFile))
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		origin := decodeOriginDir(entry.Name())
+		recs, err := readLocalStorageDB(path, origin)
+		if err != nil {
+			records = append(records, webstorage.Record{
+				StorageType: webstorage.LocalStorage,
+				Origin:      origin,
+				Error:       err.Error(),
+			})
+			continue
+		}
+		records = append(records, recs...)
+	}
+	return records, nil
+}
+
+// readLocalStorageDB reads the data table of one origin's ls/data.sqlite
+func readLocalStorageDB(path, origin string) ([]webstorage.Record, error) {
+	db, cleanup, err := openDatabase(path)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	// The database table holds the exact origin, including any origin attribute suffix
+	var dbOrigin string
+	if err := db.QueryRow("SELECT origin FROM database").Scan(&dbOrigin); err == nil && dbOrigin != "" {
+		origin = dbOrigin
+	}
+
+	columns, err := tableColumns(db, "data")
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("%s has no data table", path)
+	}
+
+	// Early schemas have a compressed flag instead of compression_type, and store UTF-8 text
+	query := fmt.Sprintf("SELECT key, value, %s, %s FROM data",
+		columnOr(columns, "compression_type", columnOr(columns, "compressed", "0")),
+		columnOr(columns, "conversion_type", strconv.Itoa(lsConversionUTF8)))
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []webstorage.Record
+	for rows.Next() {
+		var (
+			key                     string
+			value                   []byte
+			compression, conversion int
+		)
+		if err := rows.Scan(&key, &value, &compression, &conversion); err != nil {
+			return nil, err
+		}
+
+		r := webstorage.Record{
+			StorageType: webstorage.LocalStorage,
+			Origin:      origin,
+			Key:         key,
+		}
+		if decoded, err := decodeLocalStorageValue(value, compression, conversion); err != nil {
+			r.Value = fmt.Sprintf("%x", value)
+			r.Error = fmt.Sprintf("value: %v", err)
+		} else {
+			r.Value = decoded
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// decodeLocalStorageValue undoes LSValue's snappy compression and, for values stored without
+// conversion, their raw UTF-16 encoding
+func decodeLocalStorageValue(value []byte, compression, conversion int) (string, error) {
+	if compression == lsCompressionSnappy {
+		decompressed, err := snappy.Decode(value)
+		if err != nil {
+			return "", err
+		}
+		value = decompressed
+	}
+
+	if conversion == lsConversionNone {
+		u := make([]uint16, len(value)/2)
+		for i := range u {
+			u[i] = binary.LittleEndian.Uint16(value[i*2:])
+		}
+		return string(utf16.Decode(u)), nil
+	}
+	return string(value), nil
+}
+
+// decodeOriginDir turns a storage directory name back into an origin:
+// "https+++example.com+8443^userContextId=1" is "https://example.com:8443^userContextId=1"
+func decodeOriginDir(name string) string {
+	base, suffix, _ := strings.Cut(name, "^")
+	if suffix != "" {
+		suffix = "^" + suffix
+	}
+
+	scheme, host, ok := strings.Cut(base, "+++")
+	if !ok {
+		return name
+	}
+	if scheme == "file" {
+		return scheme + "://" + strings.ReplaceAll(host, "+", "/") + suffix
+	}
+	if i := strings.LastIndex(host, "+"); i != -1 {
+		if _, err := strconv.Atoi(host[i+1:]); err == nil {
+			host = host[:i] + ":" + host[i+1:]
+		}
+	}
+	return scheme + "://" + host + suffix
+}
@@ -0,0 +1,162 @@
+package fixture
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"hash"
+
+	"go-cookie-monster/pkg/dpapi"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// stream is a deterministic byte source: SHA256(label || seed || counter) blocks. The label
+// keeps the streams of different platforms apart for the same seed.
+type stream struct {
+	label   string
+	seed    int64
+	counter uint64
+	buf     []byte
+}
+
+func newStream(label string, seed int64) *stream {
+	return &stream{label: label, seed: seed}
+}
+
+// bytes returns the next n bytes of the stream
+func (s *stream) bytes(n int) []byte {
+	for len(s.buf) < n {
+		block := []byte(s.label)
+		block = binary.LittleEndian.AppendUint64(block, uint64(s.seed))
+		block = binary.LittleEndian.AppendUint64(block, s.counter)
+		s.counter++
+		sum := sha256.Sum256(block)
+		s.buf = append(s.buf, sum[:]...)
+	}
+	out := s.buf[:n]
+	s.buf = s.buf[n:]
+	return append([]byte(nil), out...)
+}
+
+// encryptGCM encrypts a value as Chromium does on Windows: prefix || nonce || ciphertext || tag
+func encryptGCM(prefix string, key, nonce, plaintext []byte) []byte {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	out := append([]byte(prefix), nonce...)
+	return aead.Seal(out, nonce, plaintext, nil)
+}
+
+// encryptCBC encrypts a value as Chromium does on Linux and macOS: prefix || AES-128-CBC with
+// a PBKDF2-SHA1 key and an IV of 16 spaces
+func encryptCBC(prefix, password string, iterations int, plaintext []byte) []byte {
+	key := pbkdf2.Key([]byte(password), []byte(cbcSalt), iterations, 16, sha1.New)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	padded := pkcs7(plaintext, aes.BlockSize)
+	out := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, bytes.Repeat([]byte{' '}, aes.BlockSize)).CryptBlocks(out, padded)
+	return append([]byte(prefix), out...)
+}
+
+// protectDPAPI builds a DPAPI blob as CryptProtectData does with an AES-256/SHA-512 masterkey
+// and no entropy, so dpapi.MasterKeyCache.Unprotect decrypts it with masterKey
+func protectDPAPI(masterKey []byte, guid dpapi.GUID, salt, hmac2Key, plaintext []byte) []byte {
+	keyHash := sha1.Sum(masterKey)
+
+	// session key = HMAC-SHA512(SHA1(masterkey), salt); its first 32 bytes are the AES key
+	mac := hmac.New(sha512.New, keyHash[:])
+	mac.Write(salt)
+	sessionKey := mac.Sum(nil)
+	block, err := aes.NewCipher(sessionKey[:32])
+	if err != nil {
+		panic(err)
+	}
+	padded := pkcs7(plaintext, aes.BlockSize)
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(ciphertext, padded)
+
+	var body []byte
+	body = binary.LittleEndian.AppendUint32(body, 1) // masterkey version
+	body = append(body, guid[:]...)
+	body = binary.LittleEndian.AppendUint32(body, 0) // flags
+	body = appendLengthPrefixed(body, []byte{0, 0})  // empty UTF-16 description
+	body = binary.LittleEndian.AppendUint32(body, dpapi.CALG_AES_256)
+	body = binary.LittleEndian.AppendUint32(body, 256)
+	body = appendLengthPrefixed(body, salt)
+	body = appendLengthPrefixed(body, nil) // HMAC key
+	body = binary.LittleEndian.AppendUint32(body, dpapi.CALG_SHA_512)
+	body = binary.LittleEndian.AppendUint32(body, 512)
+	body = appendLengthPrefixed(body, hmac2Key)
+	body = appendLengthPrefixed(body, ciphertext)
+
+	sign := dpapiSign(keyHash[:], hmac2Key, body)
+
+	blob := append(append([]byte(nil), dpapi.BlobPrefix...), body...)
+	return appendLengthPrefixed(blob, sign)
+}
+
+// dpapiSign computes the blob signature as Windows Vista and later do: HMAC-SHA512 keyed with
+// SHA1(masterkey) over the HMAC2 key and the signed body
+func dpapiSign(keyHash, hmac2Key, body []byte) []byte {
+	mac := hmac.New(sha512.New, keyHash)
+	mac.Write(hmac2Key)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// dpapiPBKDF2 is the PBKDF2 variant DPAPI encrypts masterkeys with, where each iteration's HMAC
+// is taken over the XOR of all previous ones
+func dpapiPBKDF2(h func() hash.Hash, password, salt []byte, iterations, keyLen int) []byte {
+	var out []byte
+	for i := uint32(1); len(out) < keyLen; i++ {
+		mac := hmac.New(h, password)
+		mac.Write(salt)
+		mac.Write(binary.BigEndian.AppendUint32(nil, i))
+		derived := mac.Sum(nil)
+		for r := 1; r < iterations; r++ {
+			mac := hmac.New(h, password)
+			mac.Write(derived)
+			for j, b := range mac.Sum(nil) {
+				derived[j] ^= b
+			}
+		}
+		out = append(out, derived...)
+	}
+	return out[:keyLen]
+}
+
+func hmacSHA1(key, data []byte) []byte {
+	mac := hmac.New(sha1.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func hmacSHA512(key, data []byte) []byte {
+	mac := hmac.New(sha512.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func appendLengthPrefixed(b, data []byte) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(data)))
+	return append(b, data...)
+}
+
+func pkcs7(data []byte, blockSize int) []byte {
+	padding := blockSize - len(data)%blockSize
+	return append(append([]byte(nil), data...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+}
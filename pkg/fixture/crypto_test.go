@@ -0,0 +1,41 @@
+package fixture
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha512"
+	"testing"
+
+	"go-cookie-monster/pkg/dpapi"
+)
+
+func TestProtectDPAPI(t *testing.T) {
+	masterKey := bytes.Repeat([]byte{0x42}, 64)
+	guid, _ := dpapi.ParseGUID("12345678-1234-5678-9abc-def012345678")
+	plaintext := []byte("os_crypt key material")
+
+	data := protectDPAPI(masterKey, guid, bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 64), plaintext)
+	blob, err := dpapi.ParseBlob(data)
+	if err != nil {
+		t.Fatalf("ParseBlob: %v", err)
+	}
+
+	// Windows 10 signs with a real HMAC-SHA512 over the HMAC2 key and everything from the
+	// masterkey version (after the 20 byte prefix) to the end of the data
+	keyHash := sha1.Sum(masterKey)
+	mac := hmac.New(sha512.New, keyHash[:])
+	mac.Write(blob.HMAC2Key)
+	mac.Write(data[len(dpapi.BlobPrefix) : len(data)-4-len(blob.Sign)])
+	if !hmac.Equal(mac.Sum(nil), blob.Sign) {
+		t.Errorf("signature is not HMAC-SHA512(SHA1(masterkey), HMAC2 key || body)")
+	}
+
+	got, err := blob.Decrypt(masterKey, nil)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("got %q, want %q", got, plaintext)
+	}
+}
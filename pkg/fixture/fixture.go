@@ -0,0 +1,360 @@
+package fixture
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Generate writes a synthetic Chromium User Data directory to <dir>/User Data, with a Local
+// State, Cookies, Login Data and Web Data at several schema versions per profile, and its known
+// answers to <dir>/fixture.json. The same options always produce the same keys and secrets.
+func Generate(dir string, opts Options) (*Fixture, error) {
+	if opts.Platform == "" {
+		opts.Platform = PlatformWindows
+	}
+	specs, ok := profileSpecs[opts.Platform]
+	if !ok {
+		return nil, fmt.Errorf("unknown platform %q", opts.Platform)
+	}
+
+	f := &Fixture{
+		Platform:    opts.Platform,
+		Seed:        opts.Seed,
+		UserDataDir: filepath.Join(dir, userDataDirName),
+	}
+	if err := os.MkdirAll(f.UserDataDir, 0755); err != nil {
+		return nil, err
+	}
+
+	rnd := newStream(f.Platform, opts.Seed)
+	switch f.Platform {
+	case PlatformWindows:
+		copy(f.masterKeyGUID[:], rnd.bytes(16))
+		f.masterKeyBytes = rnd.bytes(64)
+		copy(f.systemMasterKeyGUID[:], rnd.bytes(16))
+		f.systemMasterKeyBytes = rnd.bytes(64)
+		f.osCryptKey = rnd.bytes(32)
+		f.appBoundKey = rnd.bytes(32)
+		f.MasterKey = fmt.Sprintf("{%s}:%x", f.masterKeyGUID, f.masterKeyBytes)
+		f.SystemMasterKey = fmt.Sprintf("{%s}:%x", f.systemMasterKeyGUID, f.systemMasterKeyBytes)
+		f.OSCryptKey = hex.EncodeToString(f.osCryptKey)
+		f.AppBoundKey = hex.EncodeToString(f.appBoundKey)
+	case PlatformLinux:
+		f.KeyringPassword = base64.StdEncoding.EncodeToString(rnd.bytes(12))
+	case PlatformMac:
+		f.SafeStoragePassword = base64.StdEncoding.EncodeToString(rnd.bytes(16))
+	}
+
+	if err := writeLocalState(f, specs, rnd); err != nil {
+		return nil, err
+	}
+	for _, spec := range specs {
+		profile, err := generateProfile(f, spec, rnd)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", spec.name, err)
+		}
+		f.Profiles = append(f.Profiles, profile)
+	}
+
+	manifest, err := json.MarshalIndent(f, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, manifestFile), manifest, 0644); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Load reads the known answers of a fixture generated into dir
+func Load(dir string) (*Fixture, error) {
+	content, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return nil, err
+	}
+	var f Fixture
+	if err := json.Unmarshal(content, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", manifestFile, err)
+	}
+	return &f, nil
+}
+
+// Profile returns the generated profile with the given name
+func (f *Fixture) Profile(name string) (Profile, bool) {
+	for _, p := range f.Profiles {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Profile{}, false
+}
+
+// writeLocalState writes the profile list and, on Windows, the DPAPI-protected os_crypt key and
+// the app-bound key: the elevation service's envelope, protected by the user's masterkey and
+// then by the SYSTEM masterkey, behind the APPB prefix. Only the elevation service's own
+// post-processing is left out, so the envelope holds the bare key.
+func writeLocalState(f *Fixture, specs []profileSpec, rnd *stream) error {
+	infoCache := make(map[string]interface{})
+	var order []string
+	for i, spec := range specs {
+		infoCache[spec.name] = map[string]interface{}{"name": fmt.Sprintf("Person %d", i+1), "is_using_default_name": true}
+		order = append(order, spec.name)
+	}
+	localState := map[string]interface{}{
+		"profile": map[string]interface{}{
+			"info_cache":     infoCache,
+			"last_used":      specs[0].name,
+			"profiles_order": order,
+		},
+	}
+
+	if f.Platform == PlatformWindows {
+		encryptedKey := append(append([]byte(nil), dpapiKeyPrefix...), f.protect(rnd, f.osCryptKey)...)
+
+		var envelope []byte
+		envelope = binary.LittleEndian.AppendUint32(envelope, uint32(len(appBoundValidation)))
+		envelope = append(envelope, appBoundValidation...)
+		envelope = binary.LittleEndian.AppendUint32(envelope, uint32(len(f.appBoundKey)))
+		envelope = append(envelope, f.appBoundKey...)
+		appBoundKey := append(append([]byte(nil), appBoundKeyPrefix...), f.protectSystem(rnd, f.protect(rnd, envelope))...)
+
+		localState["os_crypt"] = map[string]interface{}{
+			"encrypted_key":           base64.StdEncoding.EncodeToString(encryptedKey),
+			"app_bound_encrypted_key": base64.StdEncoding.EncodeToString(appBoundKey),
+			"audit_enabled":           true,
+		}
+	}
+
+	content, err := json.Marshal(localState)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(f.UserDataDir, "Local State"), content, 0644)
+}
+
+// generateProfile writes a profile's databases, returning the secrets stored in them
+func generateProfile(f *Fixture, spec profileSpec, rnd *stream) (Profile, error) {
+	profile := Profile{
+		Name:           spec.name,
+		CookiesVersion: spec.cookiesVersion,
+		LoginsVersion:  spec.loginsVersion,
+		WebDataVersion: spec.webDataVersion,
+		CookiesAdapter: cookiesAdapter(spec.cookiesVersion),
+		HostHash:       spec.cookiesVersion >= 24,
+	}
+	profileDir := filepath.Join(f.UserDataDir, spec.name)
+
+	cookiesPath := cookiesPath(f.UserDataDir, profile)
+	if err := os.MkdirAll(filepath.Dir(cookiesPath), 0755); err != nil {
+		return profile, err
+	}
+
+	err := withDatabase(cookiesPath, spec.cookiesVersion, []string{cookiesSchema(spec.cookiesVersion)}, func(db *sql.DB) error {
+		for i, scheme := range spec.schemes.cookies {
+			site := sites[i%len(sites)]
+			secret := Secret{
+				Name:      fmt.Sprintf("session_%s_%d", scheme, i),
+				Host:      "." + site,
+				Scheme:    scheme,
+				Plaintext: hex.EncodeToString(rnd.bytes(16)),
+			}
+
+			var value string
+			encrypted := []byte{}
+			if scheme == SchemePlaintext {
+				value = secret.Plaintext
+			} else {
+				plaintext := []byte(secret.Plaintext)
+				if profile.HostHash {
+					hostHash := sha256.Sum256([]byte(secret.Host))
+					plaintext = append(hostHash[:], plaintext...)
+				}
+				encrypted = f.encrypt(rnd, scheme, plaintext)
+			}
+
+			created := chromeTime(baseTime + int64(i))
+			err := insertRow(db, "cookies", map[string]interface{}{
+				"creation_utc": created, "host_key": secret.Host, "top_frame_site_key": "",
+				"name": secret.Name, "value": value, "encrypted_value": encrypted, "path": "/",
+				"expires_utc": chromeTime(baseTime + 365*24*3600), "is_secure": 1, "secure": 1,
+				"is_httponly": 1, "httponly": 1, "last_access_utc": created, "last_update_utc": created,
+				"has_expires": 1, "is_persistent": 1, "persistent": 1, "priority": 1, "samesite": 1,
+				"firstpartyonly": 1, "source_scheme": 2, "source_port": 443, "source_type": 0,
+				"has_cross_site_ancestor": 0,
+			})
+			if err != nil {
+				return err
+			}
+			profile.Cookies = append(profile.Cookies, secret)
+		}
+		return nil
+	})
+	if err != nil {
+		return profile, err
+	}
+
+	err = withDatabase(filepath.Join(profileDir, "Login Data"), spec.loginsVersion, []string{loginsSchema(spec.loginsVersion)}, func(db *sql.DB) error {
+		for i, scheme := range spec.schemes.logins {
+			site := sites[i%len(sites)]
+			secret := Secret{
+				Name:      "https://" + site + "/login",
+				Username:  fmt.Sprintf("user%d@example.com", i+1),
+				Scheme:    scheme,
+				Plaintext: base64.RawURLEncoding.EncodeToString(rnd.bytes(12)),
+			}
+
+			created := chromeTime(baseTime + int64(i))
+			err := insertRow(db, "logins", map[string]interface{}{
+				"origin_url": secret.Name, "action_url": "https://" + site + "/session",
+				"username_element": "login", "username_value": secret.Username,
+				"password_element": "password", "password_value": f.encrypt(rnd, scheme, []byte(secret.Plaintext)),
+				"submit_element": "", "signon_realm": "https://" + site + "/", "date_created": created,
+				"blacklisted_by_user": 0, "scheme": 0, "password_type": 0, "times_used": i + 1,
+				"date_last_used": created, "date_password_modified": created,
+			})
+			if err != nil {
+				return err
+			}
+			profile.Logins = append(profile.Logins, secret)
+		}
+		return nil
+	})
+	if err != nil {
+		return profile, err
+	}
+
+	err = withDatabase(filepath.Join(profileDir, "Web Data"), spec.webDataVersion, webDataSchema(spec.webDataVersion), func(db *sql.DB) error {
+		autofill := Secret{Name: "email", Scheme: SchemePlaintext, Plaintext: "user1@example.com"}
+		err := insertRow(db, "autofill", map[string]interface{}{
+			"name": autofill.Name, "value": autofill.Plaintext, "value_lower": autofill.Plaintext,
+			"date_created": baseTime, "date_last_used": baseTime, "count": 3,
+		})
+		if err != nil {
+			return err
+		}
+		profile.Autofill = append(profile.Autofill, autofill)
+
+		for i, scheme := range spec.schemes.webData {
+			card := Secret{
+				Name:      fmt.Sprintf("Card Holder %d", i+1),
+				Scheme:    scheme,
+				Plaintext: cardNumber(rnd),
+			}
+			err := insertRow(db, "credit_cards", map[string]interface{}{
+				"guid": fmt.Sprintf("%x", rnd.bytes(16)), "name_on_card": card.Name,
+				"expiration_month": 12, "expiration_year": 2030,
+				"card_number_encrypted": f.encrypt(rnd, scheme, []byte(card.Plaintext)),
+				"date_modified":         baseTime, "nickname": "Work",
+			})
+			if err != nil {
+				return err
+			}
+			profile.CreditCards = append(profile.CreditCards, card)
+
+			token := Secret{
+				Name:      fmt.Sprintf("AccountId-%d", 100000+i),
+				Scheme:    scheme,
+				Plaintext: "1//0" + base64.RawURLEncoding.EncodeToString(rnd.bytes(24)),
+			}
+			err = insertRow(db, "token_service", map[string]interface{}{
+				"service": token.Name, "encrypted_token": f.encrypt(rnd, scheme, []byte(token.Plaintext)),
+			})
+			if err != nil {
+				return err
+			}
+			profile.Tokens = append(profile.Tokens, token)
+		}
+		return nil
+	})
+
+	return profile, err
+}
+
+// encrypt encrypts a value with a scheme, using the platform's keys
+func (f *Fixture) encrypt(rnd *stream, scheme string, plaintext []byte) []byte {
+	switch {
+	case scheme == SchemePlaintext:
+		return nil
+	case scheme == SchemeDPAPI:
+		return f.protect(rnd, plaintext)
+	case scheme == SchemeV20:
+		return encryptGCM(SchemeV20, f.appBoundKey, rnd.bytes(12), plaintext)
+	case f.Platform == PlatformWindows:
+		return encryptGCM(SchemeV10, f.osCryptKey, rnd.bytes(12), plaintext)
+	case f.Platform == PlatformMac:
+		return encryptCBC(SchemeV10, f.SafeStoragePassword, macIterations, plaintext)
+	case scheme == SchemeV11:
+		return encryptCBC(SchemeV11, f.KeyringPassword, linuxIterations, plaintext)
+	default:
+		return encryptCBC(SchemeV10, linuxV10Secret, linuxIterations, plaintext)
+	}
+}
+
+// protect encrypts data with the fixture's user DPAPI masterkey
+func (f *Fixture) protect(rnd *stream, data []byte) []byte {
+	return protectDPAPI(f.masterKeyBytes, f.masterKeyGUID, rnd.bytes(32), rnd.bytes(64), data)
+}
+
+// protectSystem encrypts data with the fixture's SYSTEM DPAPI masterkey
+func (f *Fixture) protectSystem(rnd *stream, data []byte) []byte {
+	return protectDPAPI(f.systemMasterKeyBytes, f.systemMasterKeyGUID, rnd.bytes(32), rnd.bytes(64), data)
+}
+
+// withDatabase creates a database, fills it with fn and closes it
+func withDatabase(path string, version int, tables []string, fn func(*sql.DB) error) error {
+	db, err := createDatabase(path, version, tables...)
+	if err != nil {
+		return err
+	}
+	if err := fn(db); err != nil {
+		db.Close()
+		return fmt.Errorf("%s: %v", filepath.Base(path), err)
+	}
+	return db.Close()
+}
+
+// cookiesAdapter is the adapter pkg/decrypt picks for a cookies schema version
+func cookiesAdapter(version int) string {
+	switch {
+	case version >= 24:
+		return "cookies-v24"
+	case version >= 10:
+		return "cookies-v10"
+	default:
+		return "cookies-legacy"
+	}
+}
+
+// cardNumber returns a 16-digit test card number with a valid Luhn check digit
+func cardNumber(rnd *stream) string {
+	digits := []byte("4111")
+	for _, b := range rnd.bytes(11) {
+		digits = append(digits, '0'+b%10)
+	}
+
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-i)%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return string(append(digits, byte('0'+(10-sum%10)%10)))
+}
+
+// chromeTime converts Unix seconds to a Chromium timestamp
+func chromeTime(unix int64) int64 {
+	return (unix + windowsEpochOffset) * 1000000
+}
@@ -0,0 +1,38 @@
+package fixture
+
+import "testing"
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		platform string
+		password func(*Fixture) string
+	}{
+		{PlatformWindows, func(f *Fixture) string { return f.MasterKey }},
+		{PlatformLinux, func(f *Fixture) string { return f.KeyringPassword }},
+		{PlatformMac, func(f *Fixture) string { return f.SafeStoragePassword }},
+	}
+
+	seen := make(map[string]string)
+	for _, tt := range tests {
+		first, err := Generate(t.TempDir(), Options{Platform: tt.platform, Seed: 7})
+		if err != nil {
+			t.Fatalf("%s: Generate: %v", tt.platform, err)
+		}
+		second, err := Generate(t.TempDir(), Options{Platform: tt.platform, Seed: 7})
+		if err != nil {
+			t.Fatalf("%s: Generate: %v", tt.platform, err)
+		}
+
+		password := tt.password(first)
+		if password == "" {
+			t.Errorf("%s: no key or password", tt.platform)
+		}
+		if got := tt.password(second); got != password {
+			t.Errorf("%s: same seed gave %q and %q", tt.platform, password, got)
+		}
+		if other, ok := seen[password]; ok {
+			t.Errorf("%s and %s share the key %q", other, tt.platform, password)
+		}
+		seen[password] = tt.platform
+	}
+}
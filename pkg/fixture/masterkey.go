@@ -0,0 +1,130 @@
+package fixture
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"unicode/utf16"
+
+	"go-cookie-monster/pkg/dpapi"
+)
+
+// WriteMasterKeyFile writes the fixture's user masterkey to dir as a masterkey file named by its
+// GUID, encrypted as Windows 10 does for the account sid with password: AES-256 with a key and IV
+// from the DPAPI PBKDF2-SHA512 variant over HMAC-SHA1(SHA1(password), SID). Given a domain
+// backup key, the file also gets the domain key section a domain-joined host adds.
+func (f *Fixture) WriteMasterKeyFile(dir, sid, password string, backupKey *rsa.PublicKey) (string, error) {
+	if f.masterKeyBytes == nil {
+		return "", errors.New("fixture has no DPAPI masterkey")
+	}
+	passwordHash := sha1.Sum(utf16LE(password))
+	preKey := hmacSHA1(passwordHash[:], utf16LE(sid+"\x00"))
+	return writeMasterKeyFile(dir, f.masterKeyGUID, f.masterKeyBytes, preKey, backupKey, newStream("masterkey "+sid, f.Seed))
+}
+
+// WriteSystemMasterKeyFile writes the fixture's SYSTEM masterkey to dir, the Protect\S-1-5-18\User
+// directory, encrypted with the user half of a DPAPI_SYSTEM secret. It returns the file's path
+// and the secret as hex, machine key followed by user key.
+func (f *Fixture) WriteSystemMasterKeyFile(dir string) (string, string, error) {
+	if f.systemMasterKeyBytes == nil {
+		return "", "", errors.New("fixture has no SYSTEM DPAPI masterkey")
+	}
+	rnd := newStream("dpapi_system", f.Seed)
+	secret := rnd.bytes(40) // two 20 byte keys
+	path, err := writeMasterKeyFile(dir, f.systemMasterKeyGUID, f.systemMasterKeyBytes, secret[20:], nil, rnd)
+	return path, hex.EncodeToString(secret), err
+}
+
+// writeMasterKeyFile writes key to dir/<guid> encrypted with preKey
+func writeMasterKeyFile(dir string, guid dpapi.GUID, key, preKey []byte, backupKey *rsa.PublicKey, rnd *stream) (string, error) {
+	// cleartext = HMAC salt || HMAC-SHA512(HMAC-SHA512(pre-key, HMAC salt), key) || key
+	hmacSalt := rnd.bytes(16)
+	mac := hmacSHA512(hmacSHA512(preKey, hmacSalt), key)
+	cleartext := append(append(append([]byte(nil), hmacSalt...), mac...), key...)
+
+	salt := rnd.bytes(16)
+	derived := dpapiPBKDF2(sha512.New, preKey, salt, masterKeyIterations, 32+aes.BlockSize)
+	block, err := aes.NewCipher(derived[:32])
+	if err != nil {
+		return "", err
+	}
+	ciphertext := make([]byte, len(cleartext))
+	cipher.NewCBCEncrypter(block, derived[32:]).CryptBlocks(ciphertext, cleartext)
+
+	var masterKey []byte
+	masterKey = binary.LittleEndian.AppendUint32(masterKey, 2) // version
+	masterKey = append(masterKey, salt...)
+	masterKey = binary.LittleEndian.AppendUint32(masterKey, masterKeyIterations)
+	masterKey = binary.LittleEndian.AppendUint32(masterKey, dpapi.CALG_SHA_512)
+	masterKey = binary.LittleEndian.AppendUint32(masterKey, dpapi.CALG_AES_256)
+	masterKey = append(masterKey, ciphertext...)
+
+	var domainKey []byte
+	if backupKey != nil {
+		if domainKey, err = newDomainKey(rnd, key, backupKey); err != nil {
+			return "", err
+		}
+	}
+
+	var file []byte
+	file = binary.LittleEndian.AppendUint32(file, 2) // version
+	file = append(file, make([]byte, 8)...)
+	file = append(file, utf16LE(guid.String())...)
+	file = append(file, make([]byte, 8)...)
+	file = binary.LittleEndian.AppendUint32(file, 6) // policy
+	file = binary.LittleEndian.AppendUint64(file, uint64(len(masterKey)))
+	file = binary.LittleEndian.AppendUint64(file, 0) // backup key
+	file = binary.LittleEndian.AppendUint64(file, 0) // credential history
+	file = binary.LittleEndian.AppendUint64(file, uint64(len(domainKey)))
+	file = append(file, masterKey...)
+	file = append(file, domainKey...)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, guid.String())
+	return path, os.WriteFile(path, file, 0644)
+}
+
+// newDomainKey builds the domain key section: the masterkey encrypted with the domain backup key
+// (PKCS#1 v1.5, stored little-endian) behind a header naming the backup key
+func newDomainKey(rnd *stream, key []byte, backupKey *rsa.PublicKey) ([]byte, error) {
+	var secret []byte
+	secret = binary.LittleEndian.AppendUint32(secret, uint32(len(key)))
+	secret = binary.LittleEndian.AppendUint32(secret, 32) // supplemental key
+	secret = append(secret, key...)
+	secret = append(secret, rnd.bytes(32)...)
+
+	encrypted, err := rsa.EncryptPKCS1v15(rand.Reader, backupKey, secret)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(encrypted)-1; i < j; i, j = i+1, j-1 {
+		encrypted[i], encrypted[j] = encrypted[j], encrypted[i]
+	}
+	accessCheck := rnd.bytes(32)
+
+	var section []byte
+	section = binary.LittleEndian.AppendUint32(section, 2) // version
+	section = binary.LittleEndian.AppendUint32(section, uint32(len(encrypted)))
+	section = binary.LittleEndian.AppendUint32(section, uint32(len(accessCheck)))
+	section = append(section, rnd.bytes(16)...) // backup key GUID
+	section = append(section, encrypted...)
+	return append(section, accessCheck...), nil
+}
+
+func utf16LE(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, u)
+	}
+	return b
+}
@@ -0,0 +1,168 @@
+package fixture
+
+import "go-cookie-monster/pkg/dpapi"
+
+const (
+	// Platforms a fixture can be generated for
+	PlatformWindows = "windows"
+	PlatformLinux   = "linux"
+	PlatformMac     = "mac"
+
+	// Value encryption schemes
+	SchemeV10       = "v10"       // AES-256-GCM with the os_crypt key (Windows), AES-128-CBC (Linux, macOS)
+	SchemeV11       = "v11"       // AES-128-CBC with the keyring password (Linux)
+	SchemeV20       = "v20"       // AES-256-GCM with the app-bound key (Windows)
+	SchemeDPAPI     = "dpapi"     // bare CryptProtectData blob (Chromium before v80)
+	SchemePlaintext = "plaintext" // unencrypted value column
+
+	// Files written below the output directory
+	userDataDirName = "User Data"
+	manifestFile    = "fixture.json"
+
+	// Chromium timestamps are microseconds since 1601
+	windowsEpochOffset = 11644473600
+
+	// Fixed time the fixture's timestamps are based on, 2025-01-01T00:00:00Z
+	baseTime = 1735689600
+
+	// AES-128-CBC key derivation used by Chromium on Linux and macOS
+	cbcSalt         = "saltysalt"
+	linuxIterations = 1
+	macIterations   = 1003
+	linuxV10Secret  = "peanuts"
+
+	// PBKDF2 iterations of masterkey files written by WriteMasterKeyFile, as Windows 10 uses
+	masterKeyIterations = 8000
+
+	// Application directory written as the validation header of the app-bound envelope
+	appBoundValidation = `C:\Program Files\Google\Chrome\Application`
+)
+
+var (
+	// Local State key prefixes
+	dpapiKeyPrefix    = []byte("DPAPI")
+	appBoundKeyPrefix = []byte("APPB")
+)
+
+// Options control what Generate writes
+type Options struct {
+	Platform string // windows (default), linux or mac
+	Seed     int64  // keys, nonces and secrets are derived from the seed, so output is reproducible
+}
+
+// Fixture is the known answers of a generated User Data directory: the keys protecting it and
+// every secret stored in it
+type Fixture struct {
+	Platform    string `json:"platform"`
+	Seed        int64  `json:"seed"`
+	UserDataDir string `json:"userDataDir"`
+
+	// Windows: the user's DPAPI masterkey protecting the os_crypt key, the inner layer of the
+	// app-bound key and the DPAPI-legacy values, and the SYSTEM masterkey protecting the outer
+	// layer of the app-bound key, as {GUID}:hex
+	MasterKey       string `json:"masterKey,omitempty"`
+	SystemMasterKey string `json:"systemMasterKey,omitempty"`
+	OSCryptKey      string `json:"osCryptKey,omitempty"`  // hex
+	AppBoundKey     string `json:"appBoundKey,omitempty"` // hex
+
+	// Linux: the keyring password of v11 values; macOS: the Safe Storage password
+	KeyringPassword     string `json:"keyringPassword,omitempty"`
+	SafeStoragePassword string `json:"safeStoragePassword,omitempty"`
+
+	Profiles []Profile `json:"profiles"`
+
+	masterKeyGUID        dpapi.GUID
+	masterKeyBytes       []byte
+	systemMasterKeyGUID  dpapi.GUID
+	systemMasterKeyBytes []byte
+	osCryptKey           []byte
+	appBoundKey          []byte
+}
+
+// Profile is a generated profile and the schema versions of its databases
+type Profile struct {
+	Name           string   `json:"name"`
+	CookiesVersion int      `json:"cookiesVersion"`
+	LoginsVersion  int      `json:"loginsVersion"`
+	WebDataVersion int      `json:"webDataVersion"`
+	CookiesAdapter string   `json:"cookiesAdapter"` // adapter pkg/decrypt should pick
+	HostHash       bool     `json:"hostHash"`
+	Cookies        []Secret `json:"cookies"`
+	Logins         []Secret `json:"logins"`
+	CreditCards    []Secret `json:"creditCards"`
+	Tokens         []Secret `json:"tokens"`
+	Autofill       []Secret `json:"autofill"`
+}
+
+// Secret is a stored value: the row it is in (cookie name, login origin, card holder, token
+// service or autofill field), the scheme it is encrypted with and its plaintext
+type Secret struct {
+	Name      string `json:"name"`
+	Host      string `json:"host,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Scheme    string `json:"scheme"`
+	Plaintext string `json:"plaintext"`
+}
+
+// profileSpec describes a profile to generate
+type profileSpec struct {
+	name           string
+	cookiesVersion int
+	loginsVersion  int
+	webDataVersion int
+	schemes        dbSchemes
+}
+
+// dbSchemes are the schemes each database's values are encrypted with, in row order. Databases
+// mix schemes as Chromium does: Chrome 127+ writes new cookies as v20 next to older v10 ones,
+// and Linux mixes v10 and v11 values.
+type dbSchemes struct {
+	cookies []string
+	logins  []string
+	webData []string
+}
+
+// profileSpecs are the profiles generated per platform, newest schema first. On Windows the
+// Default profile is Chrome 130+ (v20 and older v10 cookies with host hash, v10 passwords),
+// Profile 1 is Chrome 96-126 (all v10) and Profile 2 predates Chrome 80 (all DPAPI).
+var profileSpecs = map[string][]profileSpec{
+	PlatformWindows: {
+		{"Default", 24, 43, 135, dbSchemes{
+			cookies: []string{SchemeV20, SchemeV10, SchemeV20, SchemeV10, SchemePlaintext},
+			logins:  []string{SchemeV10, SchemeV10},
+			webData: []string{SchemeV10},
+		}},
+		{"Profile 1", 21, 35, 108, dbSchemes{
+			cookies: []string{SchemeV10, SchemeV10, SchemePlaintext},
+			logins:  []string{SchemeV10},
+			webData: []string{SchemeV10},
+		}},
+		{"Profile 2", 9, 22, 70, dbSchemes{
+			cookies: []string{SchemeDPAPI, SchemePlaintext},
+			logins:  []string{SchemeDPAPI},
+			webData: []string{SchemeDPAPI},
+		}},
+	},
+	PlatformLinux: {
+		{"Default", 24, 43, 135, dbSchemes{
+			cookies: []string{SchemeV11, SchemeV10, SchemePlaintext},
+			logins:  []string{SchemeV11, SchemeV10},
+			webData: []string{SchemeV11},
+		}},
+		{"Profile 1", 21, 35, 108, dbSchemes{
+			cookies: []string{SchemeV10, SchemePlaintext},
+			logins:  []string{SchemeV10},
+			webData: []string{SchemeV10},
+		}},
+	},
+	PlatformMac: {
+		{"Default", 24, 43, 135, dbSchemes{
+			cookies: []string{SchemeV10, SchemeV10, SchemePlaintext},
+			logins:  []string{SchemeV10},
+			webData: []string{SchemeV10},
+		}},
+	},
+}
+
+// Sites the generated cookies and logins belong to
+var sites = []string{"github.com", "login.microsoftonline.com", "mail.google.com", "app.slack.com", "console.aws.amazon.com"}
@@ -0,0 +1,124 @@
+package fixture
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const metaSchema = `CREATE TABLE meta(key LONGVARCHAR NOT NULL UNIQUE PRIMARY KEY, value LONGVARCHAR)`
+
+// cookiesSchema returns the cookies table of a schema version: version 24 (Chromium 130+), the
+// renamed columns of versions 10-23, or the secure/httponly/persistent columns before that
+func cookiesSchema(version int) string {
+	switch {
+	case version >= 24:
+		return `CREATE TABLE cookies(creation_utc INTEGER NOT NULL, host_key TEXT NOT NULL, top_frame_site_key TEXT NOT NULL, name TEXT NOT NULL, value TEXT NOT NULL, encrypted_value BLOB NOT NULL, path TEXT NOT NULL, expires_utc INTEGER NOT NULL, is_secure INTEGER NOT NULL, is_httponly INTEGER NOT NULL, last_access_utc INTEGER NOT NULL, has_expires INTEGER NOT NULL, is_persistent INTEGER NOT NULL, priority INTEGER NOT NULL, samesite INTEGER NOT NULL, source_scheme INTEGER NOT NULL, source_port INTEGER NOT NULL, last_update_utc INTEGER NOT NULL, source_type INTEGER NOT NULL, has_cross_site_ancestor INTEGER NOT NULL)`
+	case version >= 10:
+		return `CREATE TABLE cookies(creation_utc INTEGER NOT NULL, host_key TEXT NOT NULL, top_frame_site_key TEXT NOT NULL, name TEXT NOT NULL, value TEXT NOT NULL, encrypted_value BLOB DEFAULT '', path TEXT NOT NULL, expires_utc INTEGER NOT NULL, is_secure INTEGER NOT NULL, is_httponly INTEGER NOT NULL, last_access_utc INTEGER NOT NULL, has_expires INTEGER NOT NULL DEFAULT 1, is_persistent INTEGER NOT NULL DEFAULT 1, priority INTEGER NOT NULL DEFAULT 1, samesite INTEGER NOT NULL DEFAULT -1, source_scheme INTEGER NOT NULL DEFAULT 0, source_port INTEGER NOT NULL DEFAULT -1, is_same_party INTEGER NOT NULL DEFAULT 0, last_update_utc INTEGER NOT NULL DEFAULT 0)`
+	default:
+		return `CREATE TABLE cookies(creation_utc INTEGER NOT NULL UNIQUE PRIMARY KEY, host_key TEXT NOT NULL, name TEXT NOT NULL, value TEXT NOT NULL, path TEXT NOT NULL, expires_utc INTEGER NOT NULL, secure INTEGER NOT NULL, httponly INTEGER NOT NULL, last_access_utc INTEGER NOT NULL, has_expires INTEGER NOT NULL DEFAULT 1, persistent INTEGER NOT NULL DEFAULT 1, priority INTEGER NOT NULL DEFAULT 1, encrypted_value BLOB DEFAULT '', firstpartyonly INTEGER NOT NULL DEFAULT 0)`
+	}
+}
+
+// loginsSchema returns the logins table of a Login Data version. date_last_used appeared in
+// version 25 and date_password_modified in version 30.
+func loginsSchema(version int) string {
+	columns := []string{
+		"origin_url VARCHAR NOT NULL", "action_url VARCHAR", "username_element VARCHAR",
+		"username_value VARCHAR", "password_element VARCHAR", "password_value BLOB",
+		"submit_element VARCHAR", "signon_realm VARCHAR NOT NULL", "date_created INTEGER NOT NULL",
+		"blacklisted_by_user INTEGER NOT NULL", "scheme INTEGER NOT NULL", "password_type INTEGER",
+		"times_used INTEGER", "form_data BLOB", "display_name VARCHAR", "icon_url VARCHAR",
+		"federation_url VARCHAR", "skip_zero_click INTEGER", "generation_upload_status INTEGER",
+		"possible_username_pairs BLOB", "id INTEGER PRIMARY KEY AUTOINCREMENT",
+	}
+	if version >= 25 {
+		columns = append(columns, "date_last_used INTEGER NOT NULL DEFAULT 0", "moving_blocked_for BLOB")
+	}
+	if version >= 30 {
+		columns = append(columns, "date_password_modified INTEGER NOT NULL DEFAULT 0")
+	}
+	if version >= 40 {
+		columns = append(columns, "sender_email VARCHAR", "sender_name VARCHAR", "date_received INTEGER",
+			"sharing_notification_displayed INTEGER NOT NULL DEFAULT 0", "keychain_identifier BLOB")
+	}
+	columns = append(columns, "UNIQUE (origin_url, username_element, username_value, password_element, signon_realm)")
+	return "CREATE TABLE logins (" + strings.Join(columns, ", ") + ")"
+}
+
+// webDataSchema returns the Web Data tables that are read. Card nicknames arrived in version 87.
+func webDataSchema(version int) []string {
+	creditCards := "CREATE TABLE credit_cards (guid VARCHAR PRIMARY KEY, name_on_card VARCHAR, expiration_month INTEGER, expiration_year INTEGER, card_number_encrypted BLOB, date_modified INTEGER NOT NULL DEFAULT 0, origin VARCHAR DEFAULT '', use_count INTEGER NOT NULL DEFAULT 0, use_date INTEGER NOT NULL DEFAULT 0, billing_address_id VARCHAR"
+	if version >= 87 {
+		creditCards += ", nickname VARCHAR"
+	}
+	creditCards += ")"
+
+	return []string{
+		"CREATE TABLE autofill (name VARCHAR, value VARCHAR, value_lower VARCHAR, date_created INTEGER DEFAULT 0, date_last_used INTEGER DEFAULT 0, count INTEGER DEFAULT 1, PRIMARY KEY (name, value))",
+		creditCards,
+		"CREATE TABLE token_service (service VARCHAR PRIMARY KEY NOT NULL, encrypted_token BLOB)",
+	}
+}
+
+// createDatabase creates a SQLite database with a meta table recording version and the given
+// tables
+func createDatabase(path string, version int, tables ...string) (*sql.DB, error) {
+	// Replace the output of an earlier run
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+
+	statements := append([]string{metaSchema}, tables...)
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	for key, value := range map[string]int{"version": version, "last_compatible_version": version} {
+		if _, err := db.Exec("INSERT INTO meta (key, value) VALUES (?, ?)", key, fmt.Sprint(value)); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	return db, nil
+}
+
+// insertRow inserts the values whose columns exist in the table, so one row description
+// fits every schema version
+func insertRow(db *sql.DB, table string, values map[string]interface{}) error {
+	rows, err := db.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return err
+	}
+	var columns []string
+	var args []interface{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		if value, ok := values[name]; ok {
+			columns = append(columns, name)
+			args = append(args, value)
+		}
+	}
+	rows.Close()
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(columns, ", "), placeholders)
+	_, err = db.Exec(query, args...)
+	return err
+}
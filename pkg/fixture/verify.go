@@ -0,0 +1,209 @@
+package fixture
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go-cookie-monster/pkg/cookiemonster"
+	"go-cookie-monster/pkg/decrypt"
+	"go-cookie-monster/pkg/dpapi"
+	"go-cookie-monster/pkg/keys"
+)
+
+// Verify decrypts a fixture the way decrypt-profile does and compares the results against the
+// known answers. On Windows the keys are recovered from Local State with the fixture's
+// masterkeys; on Linux and macOS the keyring or Safe Storage password is given. It returns how
+// many values were checked and every mismatch; no mismatches means every decrypt path the
+// fixture covers works.
+func Verify(f *Fixture) (int, []error) {
+	v := &verifier{fixture: f}
+
+	var (
+		profileKeys            []decrypt.Key
+		masterKeys, systemKeys *dpapi.MasterKeyCache
+	)
+	switch f.Platform {
+	case PlatformWindows:
+		var err error
+		if masterKeys, systemKeys, err = v.verifyWindowsKeys(); err != nil {
+			return 0, []error{err}
+		}
+	case PlatformLinux:
+		profileKeys = []decrypt.Key{decrypt.LinuxKey(f.KeyringPassword)}
+	case PlatformMac:
+		profileKeys = []decrypt.Key{decrypt.MacKey(f.SafeStoragePassword)}
+	default:
+		return 0, []error{fmt.Errorf("unknown platform %q", f.Platform)}
+	}
+
+	results := make(map[string]cookiemonster.ProfileResult)
+	for _, result := range cookiemonster.DecryptUserDataDir(f.UserDataDir, profileKeys, masterKeys, systemKeys) {
+		results[result.Profile] = result
+	}
+	for _, p := range f.Profiles {
+		v.verifySchema(p)
+		result, ok := results[p.Name]
+		if !ok {
+			v.errs = append(v.errs, fmt.Errorf("%s: profile was not decrypted", p.Name))
+			continue
+		}
+		v.verifyProfile(p, result)
+	}
+
+	return v.checked, v.errs
+}
+
+// verifier accumulates results while a fixture is verified
+type verifier struct {
+	fixture *Fixture
+	checked int
+	errs    []error
+}
+
+// verifyWindowsKeys decrypts the os_crypt and app-bound keys of Local State with the fixture's
+// masterkeys, as the offline modes do, and returns the masterkeys
+func (v *verifier) verifyWindowsKeys() (*dpapi.MasterKeyCache, *dpapi.MasterKeyCache, error) {
+	masterKeys, err := dpapi.ParseMasterKeys(v.fixture.MasterKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse masterkey: %v", err)
+	}
+	systemKeys, err := dpapi.ParseMasterKeys(v.fixture.SystemMasterKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse SYSTEM masterkey: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(v.fixture.UserDataDir, "Local State"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encryptedKey, err := keys.ExtractKey(content, "\"encrypted_key\":\"")
+	if err != nil {
+		return nil, nil, fmt.Errorf("os_crypt key: %v", err)
+	}
+	osCryptKey, err := keys.GetMasterKeyOffline(encryptedKey, masterKeys)
+	if err != nil {
+		return nil, nil, fmt.Errorf("os_crypt key: %v", err)
+	}
+	v.compare("os_crypt key", decodeKey(osCryptKey), v.fixture.OSCryptKey)
+
+	appBoundEncrypted, err := keys.ExtractKey(content, "\"app_bound_encrypted_key\":\"")
+	if err != nil {
+		return nil, nil, fmt.Errorf("app-bound key: %v", err)
+	}
+	appBoundKey, err := keys.GetAppBoundKeyOffline(appBoundEncrypted, systemKeys, masterKeys, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("app-bound key: %v", err)
+	}
+	v.compare("app-bound key", decodeKey(appBoundKey), v.fixture.AppBoundKey)
+
+	return masterKeys, systemKeys, nil
+}
+
+// verifySchema checks that pkg/decrypt reads the profile's Cookies with the expected adapter
+func (v *verifier) verifySchema(p Profile) {
+	name := p.Name + "/Cookies"
+	reader, err := decrypt.NewDBReader(cookiesPath(v.fixture.UserDataDir, p))
+	if err != nil {
+		v.errs = append(v.errs, fmt.Errorf("%s: %v", name, err))
+		return
+	}
+	defer reader.Close()
+
+	rows, err := reader.QueryCookies()
+	if err != nil {
+		v.errs = append(v.errs, fmt.Errorf("%s: %v", name, err))
+		return
+	}
+	rows.Close()
+
+	schema := reader.Schema("cookies")
+	v.compare(name+" adapter", schema.Adapter, p.CookiesAdapter)
+	v.compare(name+" host hash", fmt.Sprint(schema.HostHash), fmt.Sprint(p.HostHash))
+}
+
+// verifyProfile compares a decrypted profile with its secrets
+func (v *verifier) verifyProfile(p Profile, result cookiemonster.ProfileResult) {
+	for _, err := range result.Errors {
+		v.errs = append(v.errs, fmt.Errorf("%s: %s", p.Name, err))
+	}
+
+	cookies := make(map[string]Secret)
+	for _, c := range result.Cookies {
+		cookies[c.Name] = Secret{Name: c.Name, Host: c.Domain, Plaintext: c.Value}
+	}
+	v.compareSecrets(p.Name+"/Cookies", cookies, p.Cookies)
+
+	logins := make(map[string]Secret)
+	for _, l := range result.Logins {
+		logins[l.OriginURL] = Secret{Name: l.OriginURL, Username: l.Username, Plaintext: l.Password}
+	}
+	v.compareSecrets(p.Name+"/Login Data", logins, p.Logins)
+
+	if result.WebData == nil {
+		v.errs = append(v.errs, fmt.Errorf("%s/Web Data: not decrypted", p.Name))
+		return
+	}
+	autofill := make(map[string]Secret)
+	for _, a := range result.WebData.Autofill {
+		autofill[a.Name] = Secret{Name: a.Name, Plaintext: a.Value}
+	}
+	cards := make(map[string]Secret)
+	for _, c := range result.WebData.CreditCards {
+		cards[c.NameOnCard] = Secret{Name: c.NameOnCard, Plaintext: c.CardNumber}
+	}
+	tokens := make(map[string]Secret)
+	for _, t := range result.WebData.Tokens {
+		tokens[t.Service] = Secret{Name: t.Service, Plaintext: t.Token}
+	}
+	v.compareSecrets(p.Name+"/Web Data autofill", autofill, p.Autofill)
+	v.compareSecrets(p.Name+"/Web Data credit cards", cards, p.CreditCards)
+	v.compareSecrets(p.Name+"/Web Data tokens", tokens, p.Tokens)
+}
+
+// compare records a mismatch between a decrypted value and its known answer
+func (v *verifier) compare(name, got, want string) {
+	v.checked++
+	if got != want {
+		v.errs = append(v.errs, fmt.Errorf("%s: got %q, want %q", name, got, want))
+	}
+}
+
+// compareSecrets compares decrypted rows, by name, with the secrets written
+func (v *verifier) compareSecrets(name string, got map[string]Secret, want []Secret) {
+	if len(got) != len(want) {
+		v.errs = append(v.errs, fmt.Errorf("%s: got %d rows, want %d", name, len(got), len(want)))
+	}
+	for _, w := range want {
+		label := fmt.Sprintf("%s %s (%s)", name, w.Name, w.Scheme)
+		g, ok := got[w.Name]
+		if !ok {
+			v.errs = append(v.errs, errors.New(label+": missing"))
+			continue
+		}
+		if w.Host != "" {
+			v.compare(label+" host", g.Host, w.Host)
+		}
+		if w.Username != "" {
+			v.compare(label+" username", g.Username, w.Username)
+		}
+		v.compare(label, g.Plaintext, w.Plaintext)
+	}
+}
+
+// cookiesPath is where a profile's Cookies database is written for its schema version
+func cookiesPath(userDataDir string, p Profile) string {
+	// Cookies moved to the Network directory in Chromium 96 (cookies version 12)
+	if p.CookiesVersion < 12 {
+		return filepath.Join(userDataDir, p.Name, "Cookies")
+	}
+	return filepath.Join(userDataDir, p.Name, "Network", "Cookies")
+}
+
+// decodeKey converts a key from the "\xHH" string form to hex
+func decodeKey(key string) string {
+	return strings.ReplaceAll(key, "\\x", "")
+}
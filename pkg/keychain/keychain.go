@@ -0,0 +1,349 @@
+package keychain
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Open reads and parses a keychain file
+func Open(path string) (*Keychain, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses a keychain file's header and table directory. Records are only decrypted
+// after Unlock or UnlockWithKey.
+func Parse(data []byte) (*Keychain, error) {
+	if len(data) < applDBHeaderSize+applDBSchemaSize || !bytes.Equal(data[:4], keychainSignature) {
+		return nil, ErrNotKeychain
+	}
+
+	kc := &Keychain{data: data, tables: make(map[uint32]int)}
+	tableCount := int(kc.uint32(applDBHeaderSize + 4))
+	for i := 0; i < tableCount; i++ {
+		entry := applDBHeaderSize + applDBSchemaSize + i*4
+		if entry+4 > len(data) {
+			return nil, errors.New("keychain table directory is truncated")
+		}
+		offset := applDBHeaderSize + int(kc.uint32(entry))
+		if offset+tableHeaderSize > len(data) {
+			return nil, fmt.Errorf("keychain table %d is out of range", i)
+		}
+		kc.tables[kc.uint32(offset+4)] = offset
+	}
+
+	return kc, nil
+}
+
+// Unlock derives the master key from the user's login password and unlocks the keychain
+func (kc *Keychain) Unlock(password string) error {
+	blob, err := kc.dbBlob()
+	if err != nil {
+		return err
+	}
+	salt := blob[dbBlobSaltOffset : dbBlobSaltOffset+saltLength]
+	masterKey := pbkdf2.Key([]byte(password), salt, masterKeyIterations, masterKeyLength, sha1.New)
+	return kc.UnlockWithKey(masterKey)
+}
+
+// UnlockWithKey unlocks the keychain with its 24-byte master key (the PBKDF2 of the password,
+// as recovered from memory or other tooling) and decrypts every record key
+func (kc *Keychain) UnlockWithKey(masterKey []byte) error {
+	if len(masterKey) != masterKeyLength {
+		return fmt.Errorf("master key must be %d bytes", masterKeyLength)
+	}
+
+	blob, err := kc.dbBlob()
+	if err != nil {
+		return err
+	}
+	startCrypto := int(binary.BigEndian.Uint32(blob[8:]))
+	totalLength := int(binary.BigEndian.Uint32(blob[12:]))
+	if startCrypto < dbBlobMinSize || totalLength > len(blob) || startCrypto >= totalLength {
+		return errors.New("keychain database blob is malformed")
+	}
+
+	iv := blob[dbBlobIVOffset : dbBlobIVOffset+ivLength]
+	plaintext, err := decrypt3DES(masterKey, iv, blob[startCrypto:totalLength])
+	if err != nil || len(plaintext) < masterKeyLength {
+		return ErrBadPassword
+	}
+	kc.dbKey = plaintext[:masterKeyLength]
+
+	return kc.loadKeys()
+}
+
+// GenericPasswords decrypts every generic password record. Records that fail to decrypt are
+// returned with Error set.
+func (kc *Keychain) GenericPasswords() ([]GenericPassword, error) {
+	if kc.dbKey == nil {
+		return nil, ErrLocked
+	}
+
+	records, err := kc.records(CSSM_DL_DB_RECORD_GENERIC_PASSWORD)
+	if err != nil {
+		return nil, err
+	}
+
+	var passwords []GenericPassword
+	for _, record := range records {
+		if record+genericPWHeaderSize > len(kc.data) {
+			continue
+		}
+		word := func(i int) int { return int(kc.uint32(record + i*4)) }
+
+		entry := GenericPassword{
+			Service:   kc.attrString(record, word(gpService)),
+			Account:   kc.attrString(record, word(gpAccount)),
+			PrintName: kc.attrString(record, word(gpPrintName)),
+			Created:   kc.attrTime(record, word(gpCreationDate)),
+			Modified:  kc.attrTime(record, word(gpModDate)),
+		}
+
+		ssgpStart := record + genericPWHeaderSize
+		ssgpEnd := ssgpStart + word(gpSSGPArea)
+		if ssgpEnd > len(kc.data) {
+			entry.Error = "SSGP area out of range"
+		} else if password, err := kc.decryptSSGP(kc.data[ssgpStart:ssgpEnd]); err != nil {
+			entry.Error = err.Error()
+		} else {
+			entry.Password = string(password)
+		}
+
+		passwords = append(passwords, entry)
+	}
+
+	return passwords, nil
+}
+
+// SafeStoragePasswords returns the Chromium "<Browser> Safe Storage" passwords, keyed by service
+func SafeStoragePasswords(passwords []GenericPassword) map[string]string {
+	safeStorage := make(map[string]string)
+	for _, p := range passwords {
+		if p.Error == "" && strings.HasSuffix(p.Service, " Safe Storage") {
+			safeStorage[p.Service] = p.Password
+		}
+	}
+	return safeStorage
+}
+
+// SafeStoragePassword returns the Safe Storage password for service. Another browser's password
+// never decrypts this one's values, so a missing entry is an ErrNoSafeStorage naming the
+// service and the Safe Storage entries the keychain does have.
+func SafeStoragePassword(passwords []GenericPassword, service string) (string, error) {
+	safeStorage := SafeStoragePasswords(passwords)
+	if password, ok := safeStorage[service]; ok {
+		return password, nil
+	}
+
+	services := make([]string, 0, len(safeStorage))
+	for s := range safeStorage {
+		services = append(services, s)
+	}
+	if len(services) == 0 {
+		return "", fmt.Errorf("%w: %s", ErrNoSafeStorage, service)
+	}
+	sort.Strings(services)
+	return "", fmt.Errorf("%w: %s (found %s)", ErrNoSafeStorage, service, strings.Join(services, ", "))
+}
+
+// Format formats the passwords as indented JSON
+func (f *JSONFormatter) Format() (string, error) {
+	jsonData, err := json.MarshalIndent(f, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
+
+// loadKeys decrypts the record keys in the symmetric key table, indexed by their SSGP label
+func (kc *Keychain) loadKeys() error {
+	records, err := kc.records(CSSM_DL_DB_RECORD_SYMMETRIC_KEY)
+	if err != nil {
+		return err
+	}
+
+	kc.keys = make(map[string][]byte)
+	for _, record := range records {
+		if record+keyBlobRecHeaderSize > len(kc.data) {
+			continue
+		}
+		recordEnd := record + int(kc.uint32(record))
+		if recordEnd > len(kc.data) || recordEnd < record+keyBlobRecHeaderSize+keyBlobMinSize {
+			continue
+		}
+		blob := kc.data[record+keyBlobRecHeaderSize : recordEnd]
+
+		startCrypto := int(binary.BigEndian.Uint32(blob[8:]))
+		totalLength := int(binary.BigEndian.Uint32(blob[12:]))
+		labelStart := totalLength + 8
+		if startCrypto >= totalLength || labelStart+ssgpLabelLength > len(blob) {
+			continue
+		}
+		label := blob[labelStart : labelStart+ssgpLabelLength]
+		if !bytes.HasPrefix(label, ssgpMagic) {
+			continue
+		}
+
+		iv := blob[keyBlobIVOffset : keyBlobIVOffset+ivLength]
+		key, err := kc.unwrapKey(blob[startCrypto:totalLength], iv)
+		if err != nil {
+			continue
+		}
+		kc.keys[string(label)] = key
+	}
+
+	return nil
+}
+
+// unwrapKey removes both layers of the CMS key wrap: decrypt with the fixed IV, reverse the
+// first 32 bytes, decrypt with the record's IV and skip the 4-byte header
+func (kc *Keychain) unwrapKey(ciphertext, iv []byte) ([]byte, error) {
+	plaintext, err := decrypt3DES(kc.dbKey, magicCmsIV, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	if len(plaintext) < keyBlobUnwrappedLength {
+		return nil, errors.New("wrapped key is too short")
+	}
+
+	reversed := make([]byte, keyBlobUnwrappedLength)
+	for i := range reversed {
+		reversed[i] = plaintext[keyBlobUnwrappedLength-1-i]
+	}
+
+	plaintext, err = decrypt3DES(kc.dbKey, iv, reversed)
+	if err != nil {
+		return nil, err
+	}
+	if len(plaintext) != 4+masterKeyLength {
+		return nil, errors.New("unwrapped key has unexpected length")
+	}
+	return plaintext[4:], nil
+}
+
+// decryptSSGP decrypts a record's SSGP area: "ssgp" || label (16) || IV (8) || ciphertext
+func (kc *Keychain) decryptSSGP(ssgp []byte) ([]byte, error) {
+	if len(ssgp) <= ssgpHeaderLength || !bytes.HasPrefix(ssgp, ssgpMagic) {
+		return nil, errors.New("record has no SSGP data")
+	}
+
+	key, ok := kc.keys[string(ssgp[:ssgpLabelLength])]
+	if !ok {
+		return nil, errors.New("no key for record")
+	}
+	return decrypt3DES(key, ssgp[ssgpLabelLength:ssgpHeaderLength], ssgp[ssgpHeaderLength:])
+}
+
+// dbBlob returns the database blob stored in the metadata table
+func (kc *Keychain) dbBlob() ([]byte, error) {
+	table, ok := kc.tables[CSSM_DL_DB_RECORD_METADATA]
+	if !ok {
+		return nil, errors.New("keychain has no metadata table")
+	}
+
+	// The blob follows the table and record headers; find it by its magic
+	magic := binary.BigEndian.AppendUint32(nil, blobMagic)
+	start := bytes.Index(kc.data[table+tableHeaderSize:], magic)
+	if start < 0 {
+		return nil, errors.New("keychain database blob not found")
+	}
+	blob := kc.data[table+tableHeaderSize+start:]
+	if len(blob) < dbBlobMinSize {
+		return nil, errors.New("keychain database blob is truncated")
+	}
+	return blob, nil
+}
+
+// records returns the absolute offsets of a table's records. The record number list may
+// contain empty (0) or unaligned slots for deleted records, which are skipped.
+func (kc *Keychain) records(tableID uint32) ([]int, error) {
+	table, ok := kc.tables[tableID]
+	if !ok {
+		return nil, fmt.Errorf("keychain has no table 0x%08x", tableID)
+	}
+
+	recordCount := int(kc.uint32(table + 8))
+	slots := int(kc.uint32(table + 24))
+	var records []int
+	for i := 0; i < slots && len(records) < recordCount; i++ {
+		entry := table + tableHeaderSize + i*4
+		if entry+4 > len(kc.data) {
+			break
+		}
+		offset := int(kc.uint32(entry))
+		if offset != 0 && offset%4 == 0 && table+offset < len(kc.data) {
+			records = append(records, table+offset)
+		}
+	}
+	return records, nil
+}
+
+// attrString reads a length-prefixed attribute value
+func (kc *Keychain) attrString(record, attr int) string {
+	attr &^= 1
+	if attr == 0 || record+attr+4 > len(kc.data) {
+		return ""
+	}
+	length := int(kc.uint32(record + attr))
+	start := record + attr + 4
+	if start+length > len(kc.data) {
+		return ""
+	}
+	return string(bytes.TrimRight(kc.data[start:start+length], "\x00"))
+}
+
+// attrTime reads a 16-byte "YYYYMMDDhhmmssZ" time attribute
+func (kc *Keychain) attrTime(record, attr int) string {
+	attr &^= 1
+	if attr == 0 || record+attr+16 > len(kc.data) {
+		return ""
+	}
+	return string(bytes.TrimRight(kc.data[record+attr:record+attr+16], "\x00"))
+}
+
+func (kc *Keychain) uint32(offset int) uint32 {
+	if offset < 0 || offset+4 > len(kc.data) {
+		return 0
+	}
+	return binary.BigEndian.Uint32(kc.data[offset:])
+}
+
+// decrypt3DES decrypts 3DES-CBC data and removes its PKCS#7 padding
+func decrypt3DES(key, iv, data []byte) ([]byte, error) {
+	if len(data) == 0 || len(data)%des.BlockSize != 0 {
+		return nil, errors.New("ciphertext is not a whole number of blocks")
+	}
+
+	block, err := des.NewTripleDESCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, data)
+
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > des.BlockSize {
+		return nil, errors.New("invalid padding")
+	}
+	for _, b := range plaintext[len(plaintext)-padding:] {
+		if int(b) != padding {
+			return nil, errors.New("invalid padding")
+		}
+	}
+	return plaintext[:len(plaintext)-padding], nil
+}
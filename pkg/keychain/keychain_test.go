@@ -0,0 +1,235 @@
+package keychain
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// testEntry is a generic password written to a test keychain
+type testEntry struct {
+	service  string
+	password string
+}
+
+func be32(values ...uint32) []byte {
+	var b []byte
+	for _, v := range values {
+		b = binary.BigEndian.AppendUint32(b, v)
+	}
+	return b
+}
+
+// encrypt3DES pads plaintext with PKCS#7 and encrypts it with 3DES-CBC
+func encrypt3DES(key, iv, plaintext []byte) []byte {
+	pad := des.BlockSize - len(plaintext)%des.BlockSize
+	plaintext = append(append([]byte(nil), plaintext...), bytes.Repeat([]byte{byte(pad)}, pad)...)
+	block, err := des.NewTripleDESCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, plaintext)
+	return ciphertext
+}
+
+// buildTable lays out a table header, its record number slots and its records
+func buildTable(id uint32, records ...[]byte) []byte {
+	offset := uint32(tableHeaderSize + 4*len(records))
+	var slots, body []byte
+	for _, record := range records {
+		slots = append(slots, be32(offset+uint32(len(body)))...)
+		body = append(body, record...)
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+	}
+
+	table := be32(0, id, uint32(len(records)), 0, 0, 0, uint32(len(records)))
+	table = append(append(table, slots...), body...)
+	copy(table, be32(uint32(len(table))))
+	return table
+}
+
+// buildKeychain writes a keychain unlocked by password holding the entries, each encrypted with
+// its own record key. It returns the file and the keychain master key.
+func buildKeychain(password string, entries []testEntry) ([]byte, []byte) {
+	salt := bytes.Repeat([]byte{9}, saltLength)
+	dbIV := bytes.Repeat([]byte{3}, ivLength)
+	masterKey := pbkdf2.Key([]byte(password), salt, masterKeyIterations, masterKeyLength, sha1.New)
+	dbKey := bytes.Repeat([]byte{0x21}, masterKeyLength)
+
+	// Metadata table: the DbBlob holding the database key encrypted with the master key
+	encryptedDBKey := encrypt3DES(masterKey, dbIV, append(append([]byte(nil), dbKey...), make([]byte, 20)...))
+	dbBlob := be32(blobMagic, 0x100, dbBlobMinSize, uint32(dbBlobMinSize+len(encryptedDBKey)))
+	dbBlob = append(dbBlob, make([]byte, dbBlobSaltOffset-len(dbBlob))...)
+	dbBlob = append(dbBlob, salt...)
+	dbBlob = append(dbBlob, dbIV...)
+	dbBlob = append(dbBlob, make([]byte, dbBlobMinSize-len(dbBlob))...)
+	dbBlob = append(dbBlob, encryptedDBKey...)
+	metadata := buildTable(CSSM_DL_DB_RECORD_METADATA, append(be32(uint32(8+len(dbBlob)), 0), dbBlob...))
+
+	var keyRecords, passwordRecords [][]byte
+	for i, entry := range entries {
+		recordKey := bytes.Repeat([]byte{byte(0x40 + i)}, masterKeyLength)
+		label := append([]byte("ssgp"), bytes.Repeat([]byte{byte(0x70 + i)}, 16)...)
+
+		// Symmetric key record: the record key under both CMS key wrap layers
+		keyIV := bytes.Repeat([]byte{5}, ivLength)
+		inner := encrypt3DES(dbKey, keyIV, append([]byte{1, 2, 3, 4}, recordKey...))
+		reversed := make([]byte, keyBlobUnwrappedLength)
+		for j := range reversed {
+			reversed[j] = inner[keyBlobUnwrappedLength-1-j]
+		}
+		outer := encrypt3DES(dbKey, magicCmsIV, reversed)
+		keyBlob := append(be32(blobMagic, 0x100, keyBlobMinSize, uint32(keyBlobMinSize+len(outer))), keyIV...)
+		keyBlob = append(keyBlob, outer...)
+		keyBlob = append(keyBlob, make([]byte, 8)...)
+		keyBlob = append(keyBlob, label...)
+		keyRecord := append(make([]byte, keyBlobRecHeaderSize), keyBlob...)
+		copy(keyRecord, be32(uint32(len(keyRecord))))
+		keyRecords = append(keyRecords, keyRecord)
+
+		// Generic password record: the attribute words, the SSGP area and the attributes
+		passwordIV := bytes.Repeat([]byte{6}, ivLength)
+		ssgp := append(append(append([]byte(nil), label...), passwordIV...), encrypt3DES(recordKey, passwordIV, []byte(entry.password))...)
+		words := make([]uint32, genericPWHeaderSize/4)
+		words[gpSSGPArea] = uint32(len(ssgp))
+		body := append([]byte(nil), ssgp...)
+		attr := func(value string) uint32 {
+			offset := uint32(genericPWHeaderSize + len(body))
+			body = append(body, be32(uint32(len(value)))...)
+			body = append(body, value...)
+			for len(body)%4 != 0 {
+				body = append(body, 0)
+			}
+			return offset
+		}
+		words[gpService] = attr(entry.service)
+		words[gpAccount] = attr("account")
+		words[gpPrintName] = attr(entry.service)
+		words[gpRecordSize] = uint32(genericPWHeaderSize + len(body))
+		passwordRecords = append(passwordRecords, append(be32(words...), body...))
+	}
+
+	tables := [][]byte{
+		metadata,
+		buildTable(CSSM_DL_DB_RECORD_SYMMETRIC_KEY, keyRecords...),
+		buildTable(CSSM_DL_DB_RECORD_GENERIC_PASSWORD, passwordRecords...),
+	}
+	data := append([]byte(nil), keychainSignature...)
+	data = append(data, be32(0x10000, applDBHeaderSize, applDBHeaderSize, 0)...)
+	schema := be32(0, uint32(len(tables)))
+	offset := uint32(applDBSchemaSize + 4*len(tables))
+	var body []byte
+	for _, table := range tables {
+		schema = append(schema, be32(offset+uint32(len(body)))...)
+		body = append(body, table...)
+	}
+	data = append(append(data, schema...), body...)
+
+	return data, masterKey
+}
+
+func TestUnlock(t *testing.T) {
+	entries := []testEntry{
+		{"Chrome Safe Storage", "chrome-password=="},
+		{"Microsoft Edge Safe Storage", "edge-password=="},
+		{"Wi-Fi", "not a browser"},
+	}
+	data, masterKey := buildKeychain("login", entries)
+
+	tests := []struct {
+		name    string
+		unlock  func(*Keychain) error
+		wantErr error
+	}{
+		{"password", func(kc *Keychain) error { return kc.Unlock("login") }, nil},
+		{"master key", func(kc *Keychain) error { return kc.UnlockWithKey(masterKey) }, nil},
+		{"wrong password", func(kc *Keychain) error { return kc.Unlock("wrong") }, ErrBadPassword},
+		{"wrong master key", func(kc *Keychain) error { return kc.UnlockWithKey(make([]byte, masterKeyLength)) }, ErrBadPassword},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kc, err := Parse(data)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if _, err := kc.GenericPasswords(); !errors.Is(err, ErrLocked) {
+				t.Fatalf("GenericPasswords before unlock: got %v, want %v", err, ErrLocked)
+			}
+
+			if err := tt.unlock(kc); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("unlock: got %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			passwords, err := kc.GenericPasswords()
+			if err != nil {
+				t.Fatalf("GenericPasswords: %v", err)
+			}
+			if len(passwords) != len(entries) {
+				t.Fatalf("got %d passwords, want %d", len(passwords), len(entries))
+			}
+			for i, p := range passwords {
+				if p.Error != "" || p.Service != entries[i].service || p.Password != entries[i].password {
+					t.Errorf("password %d: got %+v, want %+v", i, p, entries[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseRejectsOtherFiles(t *testing.T) {
+	if _, err := Parse([]byte("SQLite format 3\x00")); !errors.Is(err, ErrNotKeychain) {
+		t.Errorf("got %v, want %v", err, ErrNotKeychain)
+	}
+}
+
+func TestSafeStoragePassword(t *testing.T) {
+	passwords := []GenericPassword{
+		{Service: "Microsoft Edge Safe Storage", Password: "edge"},
+		{Service: "Chrome Safe Storage", Password: "chrome"},
+		{Service: "Brave Safe Storage", Error: "no key for record"},
+		{Service: "Wi-Fi", Password: "wifi"},
+	}
+
+	tests := []struct {
+		name         string
+		passwords    []GenericPassword
+		service      string
+		wantPassword string
+		wantErr      string
+	}{
+		{"exact service", passwords, "Microsoft Edge Safe Storage", "edge", ""},
+		{"other browsers only", passwords, "Chromium Safe Storage", "", "Chromium Safe Storage (found Chrome Safe Storage, Microsoft Edge Safe Storage)"},
+		{"undecrypted entry", passwords, "Brave Safe Storage", "", "Brave Safe Storage (found"},
+		{"no Safe Storage entries", passwords[3:], "Chrome Safe Storage", "", "Chrome Safe Storage"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			password, err := SafeStoragePassword(tt.passwords, tt.service)
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrNoSafeStorage) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want ErrNoSafeStorage naming %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SafeStoragePassword: %v", err)
+			}
+			if password != tt.wantPassword {
+				t.Errorf("got password %q, want %q", password, tt.wantPassword)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package keychain
+
+import "errors"
+
+const (
+	// Record (table) types
+	CSSM_DL_DB_RECORD_GENERIC_PASSWORD  = 0x80000000
+	CSSM_DL_DB_RECORD_INTERNET_PASSWORD = 0x80000001
+	CSSM_DL_DB_RECORD_METADATA          = 0x80008000
+	CSSM_DL_DB_RECORD_SYMMETRIC_KEY     = 0x00000011
+)
+
+const (
+	// Sizes of the fixed structures, all big-endian
+	applDBHeaderSize     = 20  // signature, version, header size, schema offset, auth offset
+	applDBSchemaSize     = 8   // schema size, table count
+	tableHeaderSize      = 28  // size, id, record count, records, indexes, free list, record numbers count
+	keyBlobRecHeaderSize = 132 // record size, record count, then unused
+	genericPWHeaderSize  = 88  // 22 attribute words, see genericPWHeader
+
+	// DbBlob and KeyBlob layout
+	blobMagic              = 0xfade0711
+	dbBlobSaltOffset       = 44
+	dbBlobIVOffset         = 64
+	dbBlobMinSize          = 92
+	keyBlobIVOffset        = 16
+	keyBlobMinSize         = 24
+	saltLength             = 20
+	ivLength               = 8
+	masterKeyLength        = 24
+	masterKeyIterations    = 1000
+	ssgpLabelLength        = 20 // "ssgp" followed by a 16-byte label
+	ssgpHeaderLength       = 28 // label and IV
+	keyBlobUnwrappedLength = 32
+)
+
+var (
+	keychainSignature = []byte("kych")
+	ssgpMagic         = []byte("ssgp")
+
+	// IV of the outer key wrapping layer (CMS key wrap)
+	magicCmsIV = []byte{0x4a, 0xdd, 0xa2, 0x2c, 0x79, 0xe8, 0x21, 0x05}
+
+	ErrNotKeychain   = errors.New("not a keychain file")
+	ErrBadPassword   = errors.New("failed to unlock keychain (wrong password or master key)")
+	ErrLocked        = errors.New("keychain is locked")
+	ErrNoSafeStorage = errors.New("no Safe Storage password found in keychain")
+)
+
+// Keychain is a parsed login.keychain-db (CSSM DL database) file
+type Keychain struct {
+	data   []byte
+	tables map[uint32]int // table id to absolute offset
+
+	dbKey []byte            // decrypted database key, set by Unlock
+	keys  map[string][]byte // record keys by SSGP label, set by Unlock
+}
+
+// GenericPassword is a decrypted generic password record
+type GenericPassword struct {
+	Service   string `json:"service"`
+	Account   string `json:"account"`
+	PrintName string `json:"printName"`
+	Created   string `json:"created,omitempty"`
+	Modified  string `json:"modified,omitempty"`
+	Password  string `json:"password"`
+	Error     string `json:"error,omitempty"`
+}
+
+// JSONFormatter formats generic passwords as indented JSON
+type JSONFormatter struct {
+	Passwords []GenericPassword `json:"passwords"`
+}
+
+// genericPWHeader lists the attribute words of a generic password record header, in order.
+// Attribute words are offsets from the record start (with the low bit used as a flag).
+const (
+	gpRecordSize = iota
+	gpRecordNumber
+	gpUnknown2
+	gpUnknown3
+	gpSSGPArea
+	gpUnknown5
+	gpCreationDate
+	gpModDate
+	gpDescription
+	gpComment
+	gpCreator
+	gpType
+	gpScriptCode
+	gpPrintName
+	gpAlias
+	gpInvisible
+	gpNegative
+	gpCustomIcon
+	gpProtected
+	gpAccount
+	gpService
+	gpGeneric
+)
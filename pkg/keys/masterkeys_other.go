@@ -0,0 +1,8 @@
+//go:build !windows
+
+package keys
+
+// cryptUnprotectData needs the Windows DPAPI; offline decryption goes through GetMasterKeyOffline
+func cryptUnprotectData(data []byte) ([]byte, error) {
+	return nil, ErrNotSupported
+}
@@ -0,0 +1,48 @@
+package keys
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// DATA_BLOB structure for CryptUnprotectData
+type DATA_BLOB struct {
+	cbData uint32
+	pbData *byte
+}
+
+// cryptUnprotectData is a wrapper for the Windows CryptUnprotectData function.
+func cryptUnprotectData(data []byte) ([]byte, error) {
+	var out DATA_BLOB
+	in := DATA_BLOB{
+		cbData: uint32(len(data)),
+		pbData: &data[0],
+	}
+
+	cryptUnprotectDataProc := syscall.MustLoadDLL("crypt32.dll").MustFindProc("CryptUnprotectData")
+
+	ret, _, err := cryptUnprotectDataProc.Call(
+		uintptr(unsafe.Pointer(&in)),
+		0,
+		0,
+		0,
+		0,
+		0,
+		uintptr(unsafe.Pointer(&out)),
+	)
+	if ret == 0 {
+		return nil, fmt.Errorf("CryptUnprotectData failed: %v", err)
+	}
+
+	// Copy the decrypted data into a Go slice
+	decrypted := make([]byte, out.cbData)
+	copy(decrypted, unsafe.Slice(out.pbData, out.cbData))
+
+	// Free memory allocated by CryptUnprotectData
+	windows.LocalFree(windows.Handle(unsafe.Pointer(out.pbData)))
+
+	return decrypted, nil
+}
@@ -1,11 +1,41 @@
 package keys
 
 import (
+	"errors"
+
 	"github.com/go-ole/go-ole"
 )
 
+// ErrNotSupported is returned by live key retrieval (DPAPI, the elevation service) outside Windows
+var ErrNotSupported = errors.New("live key retrieval is only supported on Windows")
+
 // Chrome CLSID_Elevator and IID_IElevator
 var (
 	ChromeCLSIDElevator = ole.NewGUID("{708860E0-F641-4611-8895-7D867DD3675B}")
 	ChromeIIDIElevator  = ole.NewGUID("{463ABECF-410D-407F-8AF5-0DF35A005CC8}")
 )
+
+// Prefix of the os_crypt encrypted_key in Local State
+var kDPAPIKeyPrefix = []byte{'D', 'P', 'A', 'P', 'I'}
+
+// Prefix of the app_bound_encrypted_key in Local State
+var kCryptAppBoundKeyPrefix = []byte{'A', 'P', 'P', 'B'}
+
+// App-bound envelope flags (Chrome 133+) selecting how the key is post-processed
+const (
+	AppBoundFlagAESGCM   byte = 1
+	AppBoundFlagChaCha20 byte = 2
+	AppBoundFlagCNG      byte = 3
+)
+
+const (
+	// Length of the app-bound key inside the elevation service's envelope
+	appBoundKeyLength = 32
+
+	// Nonce and tag lengths of the flagged app-bound content
+	appBoundIVLength  = 12
+	appBoundTagLength = 16
+
+	// CNG key Chrome protects flag 3 app-bound keys with
+	DefaultAppBoundCNGKeyName = "Google Chromekey1"
+)
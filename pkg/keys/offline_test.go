@@ -0,0 +1,84 @@
+package keys_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-cookie-monster/pkg/dpapi"
+	"go-cookie-monster/pkg/fixture"
+	"go-cookie-monster/pkg/keys"
+)
+
+// TestOfflineKeysFromMasterKeyFiles recovers both Local State keys the way an offline
+// investigation does: the user's masterkey file with their password, the SYSTEM masterkey file
+// with the DPAPI_SYSTEM secret, then the os_crypt and app-bound keys
+func TestOfflineKeysFromMasterKeyFiles(t *testing.T) {
+	const (
+		sid      = "S-1-5-21-1004336348-1177238915-682003330-1001"
+		password = "Summer2025!"
+	)
+
+	root := t.TempDir()
+	f, err := fixture.Generate(root, fixture.Options{Platform: fixture.PlatformWindows, Seed: 6})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	userProtect := filepath.Join(root, "Protect", sid)
+	if _, err := f.WriteMasterKeyFile(userProtect, sid, password, nil); err != nil {
+		t.Fatalf("WriteMasterKeyFile: %v", err)
+	}
+	systemProtect := filepath.Join(root, "System32", "Microsoft", "Protect", "S-1-5-18")
+	_, secret, err := f.WriteSystemMasterKeyFile(filepath.Join(systemProtect, "User"))
+	if err != nil {
+		t.Fatalf("WriteSystemMasterKeyFile: %v", err)
+	}
+
+	masterKeys, errs := dpapi.LoadMasterKeys(userProtect, dpapi.Credentials{Password: password})
+	if len(errs) > 0 || masterKeys.Len() != 1 {
+		t.Fatalf("got %d user masterkeys and errors %v", masterKeys.Len(), errs)
+	}
+	systemKey, err := dpapi.ParseSystemKey(secret)
+	if err != nil {
+		t.Fatalf("ParseSystemKey: %v", err)
+	}
+	systemKeys, errs := dpapi.LoadMasterKeys(systemProtect, dpapi.Credentials{SystemKey: systemKey})
+	if len(errs) > 0 || systemKeys.Len() != 1 {
+		t.Fatalf("got %d SYSTEM masterkeys and errors %v", systemKeys.Len(), errs)
+	}
+
+	localState, err := os.ReadFile(filepath.Join(f.UserDataDir, "Local State"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptedKey, err := keys.ExtractKey(localState, "\"encrypted_key\":\"")
+	if err != nil {
+		t.Fatalf("encrypted_key: %v", err)
+	}
+	appBoundEncryptedKey, err := keys.ExtractKey(localState, "\"app_bound_encrypted_key\":\"")
+	if err != nil {
+		t.Fatalf("app_bound_encrypted_key: %v", err)
+	}
+
+	osCryptKey, err := keys.GetMasterKeyOffline(encryptedKey, masterKeys)
+	if err != nil {
+		t.Fatalf("GetMasterKeyOffline: %v", err)
+	}
+	if got := strings.ReplaceAll(osCryptKey, "\\x", ""); got != f.OSCryptKey {
+		t.Errorf("os_crypt key: got %s, want %s", got, f.OSCryptKey)
+	}
+
+	appBoundKey, err := keys.GetAppBoundKeyOffline(appBoundEncryptedKey, systemKeys, masterKeys, nil)
+	if err != nil {
+		t.Fatalf("GetAppBoundKeyOffline: %v", err)
+	}
+	if got := strings.ReplaceAll(appBoundKey, "\\x", ""); got != f.AppBoundKey {
+		t.Errorf("app-bound key: got %s, want %s", got, f.AppBoundKey)
+	}
+
+	// The user's masterkey alone is not enough for the app-bound key
+	if _, err := keys.GetAppBoundKeyOffline(appBoundEncryptedKey, masterKeys, masterKeys, nil); err == nil {
+		t.Error("app-bound key decrypted without the SYSTEM masterkey")
+	}
+}
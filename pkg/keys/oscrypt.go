@@ -0,0 +1,55 @@
+package keys
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"go-cookie-monster/pkg/dpapi"
+)
+
+// UnprotectFunc decrypts a DPAPI blob, either live through CryptUnprotectData or offline
+type UnprotectFunc func([]byte) ([]byte, error)
+
+// DecryptMasterKey decodes the base64 os_crypt.encrypted_key from Local State, strips its "DPAPI"
+// prefix and decrypts the remaining blob with unprotect. No Windows APIs are involved unless
+// unprotect uses them.
+func DecryptMasterKey(key string, unprotect UnprotectFunc) (string, error) {
+	// Decode the base64-encoded key
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to base64 decode key: %v", err)
+	}
+
+	// Skip the "DPAPI" prefix
+	if len(decoded) <= len(kDPAPIKeyPrefix) {
+		return "", errors.New("decoded key is too short")
+	}
+	if !bytes.HasPrefix(decoded, kDPAPIKeyPrefix) {
+		return "", errors.New("invalid key header")
+	}
+	decoded = decoded[len(kDPAPIKeyPrefix):]
+
+	// Decrypt the key using DPAPI
+	decrypted, err := unprotect(decoded)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt key: %v", err)
+	}
+
+	return formatKey(decrypted), nil
+}
+
+// GetMasterKeyOffline decrypts the os_crypt key with masterkeys recovered offline
+func GetMasterKeyOffline(key string, masterKeys *dpapi.MasterKeyCache) (string, error) {
+	return DecryptMasterKey(key, masterKeys.Unprotect)
+}
+
+// formatKey converts a key to the "\xHH" string form used throughout
+func formatKey(key []byte) string {
+	var buffer bytes.Buffer
+	for _, b := range key {
+		buffer.WriteString(fmt.Sprintf("\\x%02x", b))
+	}
+	return buffer.String()
+}
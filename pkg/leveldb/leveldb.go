@@ -0,0 +1,85 @@
+package leveldb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ReadRecords reads every record from the log and table files in a LevelDB directory.
+// The database is never opened for writing, so it works on copies of locked or live stores.
+// Deleted and superseded entries are kept; use LiveRecords to get the current view.
+func ReadRecords(dir string) ([]Record, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read leveldb directory %s: %v", dir, err)
+	}
+
+	var records []Record
+	found := false
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".log":
+			found = true
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read log file %s: %v", path, err)
+			}
+			recs, err := parseLogFile(data, name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse log file %s: %v", path, err)
+			}
+			records = append(records, recs...)
+		case ".ldb", ".sst":
+			found = true
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read table file %s: %v", path, err)
+			}
+			recs, err := parseTableFile(data, name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse table file %s: %v", path, err)
+			}
+			records = append(records, recs...)
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("no leveldb log or table files found in %s", dir)
+	}
+
+	return records, nil
+}
+
+// LiveRecords reduces records to the newest entry for each key and drops deleted keys.
+// The result is sorted by key.
+func LiveRecords(records []Record) []Record {
+	latest := make(map[string]Record)
+	for _, rec := range records {
+		if cur, ok := latest[string(rec.Key)]; ok && cur.Seq > rec.Seq {
+			continue
+		}
+		latest[string(rec.Key)] = rec
+	}
+
+	live := make([]Record, 0, len(latest))
+	for _, rec := range latest {
+		if !rec.Deleted {
+			live = append(live, rec)
+		}
+	}
+
+	sort.Slice(live, func(i, j int) bool {
+		return string(live[i].Key) < string(live[j].Key)
+	})
+
+	return live
+}
@@ -0,0 +1,142 @@
+package leveldb
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+// parseLogFile reassembles the write batches stored in a log file and returns their entries.
+// Torn or corrupt trailing records are skipped rather than treated as fatal.
+func parseLogFile(data []byte, source string) ([]Record, error) {
+	var (
+		records []Record
+		batch   []byte
+		inBatch bool
+	)
+
+	for blockStart := 0; blockStart < len(data); blockStart += logBlockSize {
+		blockEnd := blockStart + logBlockSize
+		if blockEnd > len(data) {
+			blockEnd = len(data)
+		}
+		block := data[blockStart:blockEnd]
+
+		for pos := 0; pos+logHeaderSize <= len(block); {
+			checksum := binary.LittleEndian.Uint32(block[pos : pos+4])
+			length := int(binary.LittleEndian.Uint16(block[pos+4 : pos+6]))
+			recType := block[pos+6]
+			pos += logHeaderSize
+
+			// zero-filled padding at the end of a block
+			if recType == 0 && length == 0 {
+				break
+			}
+			if pos+length > len(block) {
+				break
+			}
+			payload := block[pos : pos+length]
+			pos += length
+
+			// A record that fails its checksum also invalidates the batch it belongs to
+			if !validRecord(checksum, recType, payload) {
+				inBatch = false
+				continue
+			}
+
+			switch recType {
+			case recordFull:
+				recs, err := parseBatch(payload, source)
+				if err == nil {
+					records = append(records, recs...)
+				}
+				inBatch = false
+			case recordFirst:
+				batch = append(batch[:0], payload...)
+				inBatch = true
+			case recordMiddle:
+				if inBatch {
+					batch = append(batch, payload...)
+				}
+			case recordLast:
+				if inBatch {
+					batch = append(batch, payload...)
+					recs, err := parseBatch(batch, source)
+					if err == nil {
+						records = append(records, recs...)
+					}
+				}
+				inBatch = false
+			}
+		}
+	}
+
+	return records, nil
+}
+
+// validRecord checks a log record's masked CRC-32C, which covers its type and payload
+func validRecord(checksum uint32, recType byte, payload []byte) bool {
+	crc := crc32.Update(0, crcTable, []byte{recType})
+	crc = crc32.Update(crc, crcTable, payload)
+	return checksum == ((crc>>15)|(crc<<17))+crcMaskDelta
+}
+
+// parseBatch decodes a write batch: an 8 byte sequence number, a 4 byte count, then the entries
+func parseBatch(data []byte, source string) ([]Record, error) {
+	if len(data) < 12 {
+		return nil, errors.New("write batch is too short")
+	}
+	seq := binary.LittleEndian.Uint64(data[0:8])
+	count := binary.LittleEndian.Uint32(data[8:12])
+	data = data[12:]
+
+	// The count comes from the file; every entry takes at least two bytes, which bounds it
+	// for torn or corrupt batches
+	records := make([]Record, 0, min(int(count), len(data)/2))
+	for i := uint32(0); i < count && len(data) > 0; i++ {
+		tag := data[0]
+		data = data[1:]
+
+		key, rest, err := readLengthPrefixed(data)
+		if err != nil {
+			return records, err
+		}
+		data = rest
+
+		rec := Record{
+			Key:    key,
+			Seq:    seq + uint64(i),
+			Source: source,
+		}
+
+		switch tag {
+		case tagValue:
+			value, rest, err := readLengthPrefixed(data)
+			if err != nil {
+				return records, err
+			}
+			data = rest
+			rec.Value = value
+		case tagDeletion:
+			rec.Deleted = true
+		default:
+			return records, errors.New("unknown write batch tag")
+		}
+
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
+
+// readLengthPrefixed reads a varint length followed by that many bytes
+func readLengthPrefixed(data []byte) ([]byte, []byte, error) {
+	length, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < length {
+		return nil, nil, errors.New("truncated length-prefixed slice")
+	}
+	end := n + int(length)
+	out := make([]byte, length)
+	copy(out, data[n:end])
+	return out, data[end:], nil
+}
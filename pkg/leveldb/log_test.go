@@ -0,0 +1,84 @@
+package leveldb
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+)
+
+// logRecord frames a payload as a log record with a valid checksum
+func logRecord(recType byte, payload []byte) []byte {
+	crc := crc32.Update(0, crcTable, []byte{recType})
+	crc = crc32.Update(crc, crcTable, payload)
+	var b []byte
+	b = binary.LittleEndian.AppendUint32(b, ((crc>>15)|(crc<<17))+crcMaskDelta)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(payload)))
+	b = append(b, recType)
+	return append(b, payload...)
+}
+
+// writeBatch encodes puts as a write batch, declaring count entries
+func writeBatch(seq uint64, count uint32, puts ...[2]string) []byte {
+	var b []byte
+	b = binary.LittleEndian.AppendUint64(b, seq)
+	b = binary.LittleEndian.AppendUint32(b, count)
+	for _, put := range puts {
+		b = append(b, tagValue)
+		b = binary.AppendUvarint(b, uint64(len(put[0])))
+		b = append(b, put[0]...)
+		b = binary.AppendUvarint(b, uint64(len(put[1])))
+		b = append(b, put[1]...)
+	}
+	return b
+}
+
+func TestParseLogFile(t *testing.T) {
+	batch := writeBatch(7, 2, [2]string{"a", "1"}, [2]string{"b", "2"})
+	corrupt := logRecord(recordFull, writeBatch(9, 1, [2]string{"c", "3"}))
+	corrupt[len(corrupt)-1] ^= 0xff
+	corruptLast := logRecord(recordLast, batch[10:])
+	corruptLast[0] ^= 0xff
+
+	tests := []struct {
+		name string
+		data []byte
+		want []string
+	}{
+		{"full record", logRecord(recordFull, batch), []string{"a", "b"}},
+		{"fragmented record", append(logRecord(recordFirst, batch[:10]), logRecord(recordLast, batch[10:])...), []string{"a", "b"}},
+		{"bad checksum", append(logRecord(recordFull, batch), corrupt...), []string{"a", "b"}},
+		{"bad checksum in fragment", append(logRecord(recordFirst, batch[:10]), corruptLast...), nil},
+		{"corrupt count", logRecord(recordFull, writeBatch(1, 0xffffffff, [2]string{"d", "4"})), []string{"d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := parseLogFile(tt.data, "000001.log")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var keys []string
+			for _, r := range records {
+				keys = append(keys, string(r.Key))
+			}
+			if len(keys) != len(tt.want) {
+				t.Fatalf("got keys %q, want %q", keys, tt.want)
+			}
+			for i := range keys {
+				if keys[i] != tt.want[i] {
+					t.Fatalf("got keys %q, want %q", keys, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseBatchSequence(t *testing.T) {
+	records, err := parseBatch(writeBatch(100, 2, [2]string{"a", "1"}, [2]string{"b", "2"}), "000001.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Seq != 100 || records[1].Seq != 101 || string(records[1].Value) != "2" {
+		t.Fatalf("unexpected records %+v", records)
+	}
+}
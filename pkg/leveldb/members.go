@@ -0,0 +1,46 @@
+package leveldb
+
+import "hash/crc32"
+
+const (
+	// Log file framing
+	logBlockSize  = 32 * 1024
+	logHeaderSize = 7
+
+	// Log record checksums are CRC-32C, masked by rotating and adding this delta
+	crcMaskDelta = 0xa282ead8
+
+	// Log record types
+	recordFull   = 1
+	recordFirst  = 2
+	recordMiddle = 3
+	recordLast   = 4
+
+	// Write batch entry tags
+	tagDeletion = 0
+	tagValue    = 1
+
+	// Table file layout
+	tableFooterSize   = 48
+	tableMagic        = 0xdb4775248b80fb57
+	blockTrailerSize  = 5
+	compressionNone   = 0
+	compressionSnappy = 1
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Record is a single key/value entry recovered from a LevelDB log or table file
+type Record struct {
+	Key     []byte
+	Value   []byte
+	Seq     uint64
+	Deleted bool
+	Source  string // file name the record was read from
+}
+
+// blockHandle points at a block inside a table file
+type blockHandle struct {
+	offset uint64
+	size   uint64
+}
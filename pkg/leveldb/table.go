@@ -0,0 +1,161 @@
+package leveldb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"go-cookie-monster/pkg/snappy"
+)
+
+// parseTableFile walks the index block of a table file and returns the entries of every data block
+func parseTableFile(data []byte, source string) ([]Record, error) {
+	if len(data) < tableFooterSize {
+		return nil, errors.New("table file is too short")
+	}
+
+	footer := data[len(data)-tableFooterSize:]
+	if binary.LittleEndian.Uint64(footer[tableFooterSize-8:]) != tableMagic {
+		return nil, errors.New("bad table magic")
+	}
+
+	// The footer holds the metaindex handle followed by the index handle
+	_, n, err := readBlockHandle(footer)
+	if err != nil {
+		return nil, fmt.Errorf("bad metaindex handle: %v", err)
+	}
+	indexHandle, _, err := readBlockHandle(footer[n:])
+	if err != nil {
+		return nil, fmt.Errorf("bad index handle: %v", err)
+	}
+
+	index, err := readBlock(data, indexHandle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index block: %v", err)
+	}
+	indexEntries, err := parseBlock(index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse index block: %v", err)
+	}
+
+	var records []Record
+	for _, entry := range indexEntries {
+		handle, _, err := readBlockHandle(entry.value)
+		if err != nil {
+			continue
+		}
+		block, err := readBlock(data, handle)
+		if err != nil {
+			continue
+		}
+		entries, err := parseBlock(block)
+		if err != nil {
+			continue
+		}
+
+		for _, e := range entries {
+			if len(e.key) < 8 {
+				continue
+			}
+			// Internal keys are the user key followed by (sequence << 8 | type)
+			trailer := binary.LittleEndian.Uint64(e.key[len(e.key)-8:])
+			records = append(records, Record{
+				Key:     e.key[:len(e.key)-8],
+				Value:   e.value,
+				Seq:     trailer >> 8,
+				Deleted: trailer&0xff == tagDeletion,
+				Source:  source,
+			})
+		}
+	}
+
+	return records, nil
+}
+
+type blockEntry struct {
+	key   []byte
+	value []byte
+}
+
+// readBlock returns the decompressed contents of the block at handle
+func readBlock(data []byte, handle blockHandle) ([]byte, error) {
+	end := handle.offset + handle.size + blockTrailerSize
+	if end > uint64(len(data)) || end < handle.offset {
+		return nil, errors.New("block handle out of range")
+	}
+
+	raw := data[handle.offset : handle.offset+handle.size]
+	switch data[handle.offset+handle.size] {
+	case compressionNone:
+		return raw, nil
+	case compressionSnappy:
+		return snappy.Decode(raw)
+	default:
+		return nil, errors.New("unsupported block compression")
+	}
+}
+
+// parseBlock decodes the prefix-compressed entries of a block, ignoring the restart array
+func parseBlock(block []byte) ([]blockEntry, error) {
+	if len(block) < 4 {
+		return nil, errors.New("block is too short")
+	}
+	numRestarts := binary.LittleEndian.Uint32(block[len(block)-4:])
+	limit := len(block) - 4 - int(numRestarts)*4
+	if limit < 0 {
+		return nil, errors.New("bad restart count")
+	}
+
+	var (
+		entries []blockEntry
+		lastKey []byte
+	)
+	for pos := 0; pos < limit; {
+		shared, n1 := binary.Uvarint(block[pos:limit])
+		if n1 <= 0 {
+			return entries, errors.New("bad shared length")
+		}
+		pos += n1
+		unshared, n2 := binary.Uvarint(block[pos:limit])
+		if n2 <= 0 {
+			return entries, errors.New("bad unshared length")
+		}
+		pos += n2
+		valueLen, n3 := binary.Uvarint(block[pos:limit])
+		if n3 <= 0 {
+			return entries, errors.New("bad value length")
+		}
+		pos += n3
+
+		if shared > uint64(len(lastKey)) || uint64(limit-pos) < unshared+valueLen {
+			return entries, errors.New("block entry out of range")
+		}
+
+		key := make([]byte, 0, shared+unshared)
+		key = append(key, lastKey[:shared]...)
+		key = append(key, block[pos:pos+int(unshared)]...)
+		pos += int(unshared)
+
+		value := make([]byte, valueLen)
+		copy(value, block[pos:pos+int(valueLen)])
+		pos += int(valueLen)
+
+		entries = append(entries, blockEntry{key: key, value: value})
+		lastKey = key
+	}
+
+	return entries, nil
+}
+
+// readBlockHandle decodes a varint offset/size pair and returns the bytes consumed
+func readBlockHandle(data []byte) (blockHandle, int, error) {
+	offset, n1 := binary.Uvarint(data)
+	if n1 <= 0 {
+		return blockHandle{}, 0, errors.New("bad block offset")
+	}
+	size, n2 := binary.Uvarint(data[n1:])
+	if n2 <= 0 {
+		return blockHandle{}, 0, errors.New("bad block size")
+	}
+	return blockHandle{offset: offset, size: size}, n1 + n2, nil
+}
@@ -0,0 +1,130 @@
+package leveldb
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// tableBlock encodes entries as a block without prefix compression and with one restart point
+func tableBlock(entries ...[2][]byte) []byte {
+	var b []byte
+	for _, e := range entries {
+		b = binary.AppendUvarint(b, 0)
+		b = binary.AppendUvarint(b, uint64(len(e[0])))
+		b = binary.AppendUvarint(b, uint64(len(e[1])))
+		b = append(b, e[0]...)
+		b = append(b, e[1]...)
+	}
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	return binary.LittleEndian.AppendUint32(b, 1)
+}
+
+// internalKey appends the (sequence << 8 | type) trailer to a user key
+func internalKey(key string, seq uint64, tag uint64) []byte {
+	return binary.LittleEndian.AppendUint64([]byte(key), seq<<8|tag)
+}
+
+// snappyLiteral encodes data as a snappy block holding one literal of up to 256 bytes
+func snappyLiteral(data []byte) []byte {
+	b := binary.AppendUvarint(nil, uint64(len(data)))
+	if len(data) <= 60 {
+		b = append(b, byte(len(data)-1)<<2)
+	} else {
+		b = append(b, 60<<2, byte(len(data)-1))
+	}
+	return append(b, data...)
+}
+
+// buildTable writes a table file with one data block stored with the given compression
+func buildTable(compression byte, entries ...[2][]byte) []byte {
+	data := tableBlock(entries...)
+	if compression == compressionSnappy {
+		data = snappyLiteral(data)
+	}
+
+	var file []byte
+	file = append(file, data...)
+	file = append(file, compression, 0, 0, 0, 0)
+
+	var handle []byte
+	handle = binary.AppendUvarint(handle, 0)
+	handle = binary.AppendUvarint(handle, uint64(len(data)))
+	index := tableBlock([2][]byte{internalKey("~", 0, tagValue), handle})
+	indexOffset := len(file)
+	file = append(file, index...)
+	file = append(file, compressionNone, 0, 0, 0, 0)
+
+	var footer []byte
+	footer = binary.AppendUvarint(footer, 0)
+	footer = binary.AppendUvarint(footer, 0)
+	footer = binary.AppendUvarint(footer, uint64(indexOffset))
+	footer = binary.AppendUvarint(footer, uint64(len(index)))
+	footer = append(footer, make([]byte, tableFooterSize-8-len(footer))...)
+	footer = binary.LittleEndian.AppendUint64(footer, tableMagic)
+	return append(file, footer...)
+}
+
+func TestParseTableFile(t *testing.T) {
+	entries := [][2][]byte{
+		{internalKey("a", 7, tagValue), []byte("one")},
+		{internalKey("b", 8, tagDeletion), nil},
+	}
+	badMagic := buildTable(compressionNone, entries...)
+	badMagic[len(badMagic)-1] ^= 0xff
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    []Record
+		wantErr bool
+	}{
+		{"uncompressed", buildTable(compressionNone, entries...), []Record{
+			{Key: []byte("a"), Value: []byte("one"), Seq: 7},
+			{Key: []byte("b"), Value: []byte{}, Seq: 8, Deleted: true},
+		}, false},
+		{"snappy", buildTable(compressionSnappy, entries...), []Record{
+			{Key: []byte("a"), Value: []byte("one"), Seq: 7},
+			{Key: []byte("b"), Value: []byte{}, Seq: 8, Deleted: true},
+		}, false},
+		{"bad magic", badMagic, nil, true},
+		{"too short", make([]byte, tableFooterSize-1), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTableFile(tt.data, "000005.ldb")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d records, want %d", len(got), len(tt.want))
+			}
+			for i, w := range tt.want {
+				g := got[i]
+				if string(g.Key) != string(w.Key) || string(g.Value) != string(w.Value) || g.Seq != w.Seq || g.Deleted != w.Deleted {
+					t.Errorf("record %d: got %+v, want %+v", i, g, w)
+				}
+			}
+		})
+	}
+}
+
+func TestLiveRecords(t *testing.T) {
+	records := []Record{
+		{Key: []byte("b"), Value: []byte("old"), Seq: 1},
+		{Key: []byte("b"), Value: []byte("new"), Seq: 3},
+		{Key: []byte("a"), Value: []byte("kept"), Seq: 2},
+		{Key: []byte("c"), Value: []byte("gone"), Seq: 4},
+		{Key: []byte("c"), Seq: 5, Deleted: true},
+	}
+
+	live := LiveRecords(records)
+	want := []string{"a=kept", "b=new"}
+	if len(live) != len(want) {
+		t.Fatalf("got %d live records, want %d", len(live), len(want))
+	}
+	for i, w := range want {
+		if got := string(live[i].Key) + "=" + string(live[i].Value); got != w {
+			t.Errorf("record %d: got %s, want %s", i, got, w)
+		}
+	}
+}
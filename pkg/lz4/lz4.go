@@ -0,0 +1,119 @@
+package lz4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	ErrCorrupt   = errors.New("lz4: corrupt input")
+	ErrTooLarge  = errors.New("lz4: decoded block is too large")
+	ErrNotMozLz4 = errors.New("lz4: missing mozLz40 header")
+)
+
+// maxDecodedLen caps the size of a single decoded block to avoid huge allocations from corrupt data
+const maxDecodedLen = 1 << 30
+
+// DecodeMozLz4 decodes Firefox's mozLz40 framing: the "mozLz40\0" magic, the decoded size as a
+// little-endian uint32, then a single LZ4 block
+func DecodeMozLz4(src []byte) ([]byte, error) {
+	if len(src) < mozLz4HeaderSize || !bytes.HasPrefix(src, mozLz4Magic) {
+		return nil, ErrNotMozLz4
+	}
+	dLen := binary.LittleEndian.Uint32(src[len(mozLz4Magic):])
+	if dLen > maxDecodedLen {
+		return nil, ErrTooLarge
+	}
+	return DecodeBlock(src[mozLz4HeaderSize:], int(dLen))
+}
+
+// DecodeBlock decodes a raw LZ4 block (no frame format) whose decoded length is dLen. A dLen
+// the block is too short to produce is rejected before the output is allocated.
+func DecodeBlock(src []byte, dLen int) ([]byte, error) {
+	if dLen < 0 || dLen > maxDecodedLen {
+		return nil, ErrTooLarge
+	}
+	if int64(dLen) > int64(len(src))*maxCompressionRatio {
+		return nil, ErrCorrupt
+	}
+
+	dst := make([]byte, 0, dLen)
+	for {
+		if len(src) == 0 {
+			return nil, ErrCorrupt
+		}
+		token := src[0]
+		src = src[1:]
+
+		// Literals
+		length, n, err := readLength(src, int(token>>4))
+		if err != nil {
+			return nil, err
+		}
+		src = src[n:]
+		if length > len(src) || len(dst)+length > dLen {
+			return nil, ErrCorrupt
+		}
+		dst = append(dst, src[:length]...)
+		src = src[length:]
+
+		// The last sequence has literals only
+		if len(src) == 0 {
+			break
+		}
+
+		// Match
+		if len(src) < 2 {
+			return nil, ErrCorrupt
+		}
+		offset := int(binary.LittleEndian.Uint16(src))
+		src = src[2:]
+		length, n, err = readLength(src, int(token&0x0f))
+		if err != nil {
+			return nil, err
+		}
+		src = src[n:]
+		if dst, err = appendCopy(dst, offset, length+minMatch, dLen); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(dst) != dLen {
+		return nil, ErrCorrupt
+	}
+	return dst, nil
+}
+
+// readLength completes a token nibble: a nibble of 15 is followed by bytes that are added to it
+// until one is not 255. It returns the length and the number of bytes consumed.
+func readLength(src []byte, nibble int) (int, int, error) {
+	length, n := nibble, 0
+	if nibble != lengthContinues {
+		return length, n, nil
+	}
+	for {
+		if n >= len(src) || length > maxDecodedLen {
+			return 0, 0, ErrCorrupt
+		}
+		b := src[n]
+		n++
+		length += int(b)
+		if b != 0xff {
+			return length, n, nil
+		}
+	}
+}
+
+// appendCopy appends a back-reference of length bytes starting offset bytes before the end of dst.
+// The source and destination may overlap, so the copy is done byte by byte.
+func appendCopy(dst []byte, offset, length, dLen int) ([]byte, error) {
+	if offset <= 0 || offset > len(dst) || len(dst)+length > dLen {
+		return nil, ErrCorrupt
+	}
+	start := len(dst) - offset
+	for i := 0; i < length; i++ {
+		dst = append(dst, dst[start+i])
+	}
+	return dst, nil
+}
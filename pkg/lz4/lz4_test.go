@@ -0,0 +1,79 @@
+package lz4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// mozLz4 frames a block with the mozLz40 header declaring dLen decoded bytes
+func mozLz4(dLen uint32, block []byte) []byte {
+	b := append([]byte(nil), mozLz4Magic...)
+	b = binary.LittleEndian.AppendUint32(b, dLen)
+	return append(b, block...)
+}
+
+func TestDecodeBlock(t *testing.T) {
+	// 1 literal, then a match of 4+15+255+1 bytes at offset 1 encoded with continuation bytes
+	long := []byte{0x1f, 'z', 1, 0, 0xff, 0x01, 0x00}
+
+	tests := []struct {
+		name    string
+		src     []byte
+		dLen    int
+		want    []byte
+		wantErr error
+	}{
+		{"literals only", []byte{0x30, 'a', 'b', 'c'}, 3, []byte("abc"), nil},
+		{"overlapping match", []byte{0x24, 'a', 'b', 2, 0, 0x00}, 10, []byte("ababababab"), nil},
+		{"length continuation", long, 276, bytes.Repeat([]byte("z"), 276), nil},
+		{"empty block", []byte{0x00}, 0, []byte{}, nil},
+		{"no input", nil, 0, nil, ErrCorrupt},
+		{"negative length", []byte{0x00}, -1, nil, ErrTooLarge},
+		{"length over the cap", []byte{0x00}, maxDecodedLen + 1, nil, ErrTooLarge},
+		{"length the input cannot produce", []byte{0x30, 'a', 'b', 'c'}, 4*maxCompressionRatio + 1, nil, ErrCorrupt},
+		{"short output", []byte{0x30, 'a', 'b', 'c'}, 4, nil, ErrCorrupt},
+		{"literal past the input", []byte{0x50, 'a', 'b'}, 5, nil, ErrCorrupt},
+		{"zero offset", []byte{0x10, 'a', 0, 0, 0x00}, 5, nil, ErrCorrupt},
+		{"offset before the output", []byte{0x10, 'a', 2, 0, 0x00}, 5, nil, ErrCorrupt},
+		{"truncated offset", []byte{0x10, 'a', 1}, 5, nil, ErrCorrupt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeBlock(tt.src, tt.dLen)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !bytes.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeMozLz4(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     []byte
+		want    []byte
+		wantErr error
+	}{
+		{"block", mozLz4(3, []byte{0x30, 'a', 'b', 'c'}), []byte("abc"), nil},
+		{"huge declared size", mozLz4(maxDecodedLen, []byte{0x30, 'a', 'b', 'c'}), nil, ErrCorrupt},
+		{"size over the cap", mozLz4(maxDecodedLen+1, []byte{0x00}), nil, ErrTooLarge},
+		{"missing magic", []byte("not a jsonlz4 file"), nil, ErrNotMozLz4},
+		{"truncated header", mozLz4Magic, nil, ErrNotMozLz4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeMozLz4(tt.src)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !bytes.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package lz4
+
+const (
+	// minMatch is the shortest back-reference; match lengths are stored minus this
+	minMatch = 4
+
+	// Token nibble value meaning the length continues in the following bytes
+	lengthContinues = 15
+
+	// maxCompressionRatio bounds the output of one input byte: a length continuation byte adds
+	// at most 255 bytes, so a block cannot decode to more than 255 times its size
+	maxCompressionRatio = 255
+
+	// mozLz4HeaderSize is the magic followed by the little-endian decoded size
+	mozLz4HeaderSize = 12
+)
+
+// mozLz4Magic starts Firefox's jsonlz4/baklz4 files
+var mozLz4Magic = []byte("mozLz40\x00")
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package parser
+
+import "os"
+
+// The implant callback is a Windows function pointer; elsewhere the output goes to stdout
+func _sendOutput(data string, callback uintptr) {
+	os.Stdout.WriteString(data)
+}
@@ -0,0 +1,16 @@
+package parser
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// data should only be sent once per call from implant (for now)
+func _sendOutput(data string, callback uintptr) {
+	outDataPtr, err := syscall.BytePtrFromString(data)
+	if err != nil {
+		return
+	}
+	// Send data back
+	syscall.SyscallN(callback, uintptr(unsafe.Pointer(outDataPtr)), uintptr(len(data)))
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"os"
 	"strings"
-	"syscall"
 	"unsafe"
 
 	"golang.org/x/text/encoding/unicode"
@@ -139,16 +138,6 @@ func (o *OutputBuffer) Flush() {
 	o.done = true
 }
 
-// data should only be sent once per call from implant (for now)
-func _sendOutput(data string, callback uintptr) {
-	outDataPtr, err := syscall.BytePtrFromString(data)
-	if err != nil {
-		return
-	}
-	// Send data back
-	syscall.SyscallN(callback, uintptr(unsafe.Pointer(outDataPtr)), uintptr(len(data)))
-}
-
 // Write satisfies the io.Writer interface for OutputBuffer.
 // It allows OutputBuffer to be used anywhere an io.Writer is expected.
 func (buff *OutputBuffer) Write(p []byte) (n int, err error) {
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package processes
+
+// FindProcess needs the Windows Toolhelp API; no processes are found elsewhere
+func FindProcess(name string) ([]Process, error) {
+	return nil, ErrNotSupported
+}
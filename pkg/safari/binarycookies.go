@@ -0,0 +1,161 @@
+package safari
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go-cookie-monster/pkg/decrypt"
+)
+
+// CookieFiles returns the Safari cookie files that exist in a macOS home directory: the
+// sandboxed container's and the legacy ~/Library/Cookies one
+func CookieFiles(homeDir string) []string {
+	var files []string
+	for _, rel := range []string{containerCookiesPath, legacyCookiesPath} {
+		path := filepath.Join(homeDir, filepath.FromSlash(rel))
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// ReadCookies reads and parses a Cookies.binarycookies file
+func ReadCookies(path string) ([]decrypt.Cookie, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCookies(data)
+}
+
+// ParseCookies parses Safari's binarycookies format: "cook", a big-endian page count and
+// page sizes, then the pages. Each page holds little-endian cookie records; the checksum
+// and plist that follow the pages are ignored.
+func ParseCookies(data []byte) ([]decrypt.Cookie, error) {
+	if len(data) < 8 || !bytes.HasPrefix(data, fileSignature) {
+		return nil, ErrNotBinaryCookies
+	}
+	pageCount := int(binary.BigEndian.Uint32(data[4:]))
+	tableEnd := 8 + pageCount*4
+	if pageCount < 0 || tableEnd > len(data) {
+		return nil, ErrCorrupt
+	}
+
+	var cookies []decrypt.Cookie
+	offset := tableEnd
+	for i := 0; i < pageCount; i++ {
+		size := int(binary.BigEndian.Uint32(data[8+i*4:]))
+		if size < 0 || offset+size > len(data) {
+			return nil, ErrCorrupt
+		}
+		pageCookies, err := parsePage(data[offset : offset+size])
+		if err != nil {
+			return nil, err
+		}
+		cookies = append(cookies, pageCookies...)
+		offset += size
+	}
+
+	return cookies, nil
+}
+
+// parsePage parses a page: its header, a little-endian cookie count and record offsets
+func parsePage(page []byte) ([]decrypt.Cookie, error) {
+	if len(page) < 8 || binary.BigEndian.Uint32(page) != pageSignature {
+		return nil, ErrCorrupt
+	}
+	count := int(binary.LittleEndian.Uint32(page[4:]))
+	if count < 0 || 8+count*4 > len(page) {
+		return nil, ErrCorrupt
+	}
+
+	cookies := make([]decrypt.Cookie, 0, count)
+	for i := 0; i < count; i++ {
+		start := int(binary.LittleEndian.Uint32(page[8+i*4:]))
+		if start+4 > len(page) {
+			return nil, ErrCorrupt
+		}
+		size := int(binary.LittleEndian.Uint32(page[start:]))
+		if size < recordHeaderSize || start+size > len(page) {
+			return nil, ErrCorrupt
+		}
+		c, err := parseRecord(page[start : start+size])
+		if err != nil {
+			return nil, err
+		}
+		cookies = append(cookies, c)
+	}
+	return cookies, nil
+}
+
+// parseRecord converts a cookie record. Strings are NUL-terminated at offsets relative to
+// the record; times are Mac absolute time (seconds since 2001) stored as doubles.
+func parseRecord(record []byte) (decrypt.Cookie, error) {
+	field := func(offset int) (string, error) {
+		start := int(binary.LittleEndian.Uint32(record[offset:]))
+		if start < recordHeaderSize || start >= len(record) {
+			return "", ErrCorrupt
+		}
+		end := bytes.IndexByte(record[start:], 0)
+		if end == -1 {
+			return "", ErrCorrupt
+		}
+		return string(record[start : start+end]), nil
+	}
+
+	var (
+		c   decrypt.Cookie
+		err error
+	)
+	if c.Domain, err = field(recordDomainOffset); err != nil {
+		return c, err
+	}
+	if c.Name, err = field(recordNameOffset); err != nil {
+		return c, err
+	}
+	if c.Path, err = field(recordPathOffset); err != nil {
+		return c, err
+	}
+	if c.Value, err = field(recordValueOffset); err != nil {
+		return c, err
+	}
+
+	flags := binary.LittleEndian.Uint32(record[recordFlagsOffset:])
+	c.Secure = flags&flagSecure != 0
+	c.HTTPOnly = flags&flagHTTPOnly != 0
+	c.SameSite = "no_restriction"
+
+	c.ExpirationDate = macAbsoluteToUnix(record[recordExpiryOffset:])
+	if c.ExpirationDate != 0 {
+		c.Expires = time.Unix(c.ExpirationDate, 0).UTC().Format(time.RFC3339)
+	}
+	c.CreationDate = macAbsoluteToUnix(record[recordCreationOffset:])
+
+	// Only persistent cookies are written to disk
+	c.HasExpires = true
+	c.IsPersistent = true
+	c.HostOnly = !strings.HasPrefix(c.Domain, ".")
+	c.Priority = "Medium"
+	c.SourceScheme = "Unset"
+	c.SourcePort = -1
+	if binary.LittleEndian.Uint32(record[recordHasPortOffset:]) != 0 && len(record) >= recordPortOffset+2 {
+		c.SourcePort = int(binary.LittleEndian.Uint16(record[recordPortOffset:]))
+	}
+
+	return c, nil
+}
+
+// macAbsoluteToUnix converts a little-endian Mac absolute time double to Unix seconds
+func macAbsoluteToUnix(b []byte) int64 {
+	seconds := math.Float64frombits(binary.LittleEndian.Uint64(b))
+	if seconds == 0 || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return 0
+	}
+	return int64(seconds) + macAbsoluteEpoch
+}
@@ -0,0 +1,129 @@
+package safari
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// sampleCookies is a Cookies.binarycookies file with two pages, one cookie record carrying a
+// port, and the checksum and NSHTTPCookieAcceptPolicy plist that follow the pages
+const sampleCookies = "" +
+	"Y29vawAAAAIAAAC8AAAAYgAAAQACAAAAFAAAAGMAAAAAAAAATwAAAAAAAAAFAAAAAAAAADgAAABFAAAASQAAAEsA" +
+	"AAAAAAAAAAAAAAAAAMAEfchBAAAAQBSCxUEuZXhhbXBsZS5jb20Ac2lkAC8AYWJjAFkAAAAAAAAAAAAAAAEAAAA6" +
+	"AAAASgAAAE8AAABUAAAAAAAAAAAAAAAAAEDABH3IQQAAIEAUgsVB+yB3d3cuZXhhbXBsZS5jb20AcHJlZgAvYXBw" +
+	"AGRhcmsAAAABAAEAAAAQAAAAAAAAAFIAAAAAAAAAAQAAAAAAAAA4AAAAQwAAAEoAAABMAAAAAAAAAAAAAAAAAACA" +
+	"XpLGQQAAAAAcocVBLmFwcGxlLmNvbQBkc2xhbmcALwBVUy1FTgAAABI0BxcgBQAAAEticGxpc3QwMNEBAl8QGE5T" +
+	"SFRUUENvb2tpZUFjY2VwdFBvbGljeRACCAsmAAAAAAAAAQEAAAAAAAAAAwAAAAAAAAAAAAAAAAAAACg="
+
+func decodeSample(t *testing.T) []byte {
+	t.Helper()
+	data, err := base64.StdEncoding.DecodeString(sampleCookies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseCookies(t *testing.T) {
+	cookies, err := ParseCookies(decodeSample(t))
+	if err != nil {
+		t.Fatalf("ParseCookies: %v", err)
+	}
+
+	want := []struct {
+		domain, name, path, value  string
+		secure, httpOnly, hostOnly bool
+		expiry, created            int64
+		expires                    string
+		port                       int
+	}{
+		{".example.com", "sid", "/", "abc", true, true, false, 1800000000, 1700000000, "2027-01-15T08:00:00Z", -1},
+		{"www.example.com", "pref", "/app", "dark", false, false, true, 1800000000, 1700000000, "2027-01-15T08:00:00Z", 8443},
+		{".apple.com", "dslang", "/", "US-EN", true, false, false, 1735689600, 1704067200, "2025-01-01T00:00:00Z", -1},
+	}
+	if len(cookies) != len(want) {
+		t.Fatalf("got %d cookies, want %d", len(cookies), len(want))
+	}
+	for i, w := range want {
+		c := cookies[i]
+		if c.Domain != w.domain || c.Name != w.name || c.Path != w.path || c.Value != w.value {
+			t.Errorf("cookie %d: got %s %s %s %q", i, c.Domain, c.Name, c.Path, c.Value)
+		}
+		if c.Secure != w.secure || c.HTTPOnly != w.httpOnly || c.HostOnly != w.hostOnly {
+			t.Errorf("%s: got secure %v, httpOnly %v, hostOnly %v", w.name, c.Secure, c.HTTPOnly, c.HostOnly)
+		}
+		if c.ExpirationDate != w.expiry || c.Expires != w.expires || c.CreationDate != w.created {
+			t.Errorf("%s: got expiry %d (%s), created %d", w.name, c.ExpirationDate, c.Expires, c.CreationDate)
+		}
+		if c.SourcePort != w.port || !c.IsPersistent || c.SameSite != "no_restriction" {
+			t.Errorf("%s: got %+v", w.name, c)
+		}
+	}
+}
+
+func TestParseCookiesCorrupt(t *testing.T) {
+	sample := decodeSample(t)
+	// The first page starts after the signature, page count and two page sizes
+	const firstPage = 16
+	firstRecord := firstPage + int(binary.LittleEndian.Uint32(sample[firstPage+8:]))
+
+	corrupt := func(offset int, value uint32, order binary.ByteOrder) []byte {
+		data := append([]byte(nil), sample...)
+		order.PutUint32(data[offset:], value)
+		return data
+	}
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{"not binarycookies", []byte("bplist00"), ErrNotBinaryCookies},
+		{"empty", nil, ErrNotBinaryCookies},
+		{"page table past the end", corrupt(4, 1000, binary.BigEndian), ErrCorrupt},
+		{"page past the end", corrupt(8, 1<<20, binary.BigEndian), ErrCorrupt},
+		{"bad page signature", corrupt(firstPage, 0x200, binary.BigEndian), ErrCorrupt},
+		{"record offset past the page", corrupt(firstPage+8, 1<<16, binary.LittleEndian), ErrCorrupt},
+		{"record too small", corrupt(firstRecord, 8, binary.LittleEndian), ErrCorrupt},
+		{"name offset past the record", corrupt(firstRecord+recordNameOffset, 1<<16, binary.LittleEndian), ErrCorrupt},
+		{"truncated", sample[:firstRecord+20], ErrCorrupt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCookies(tt.data); !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCookieFiles(t *testing.T) {
+	home := t.TempDir()
+	if files := CookieFiles(home); len(files) != 0 {
+		t.Errorf("got %v for an empty home", files)
+	}
+
+	var want []string
+	for _, rel := range []string{containerCookiesPath, legacyCookiesPath} {
+		path := filepath.Join(home, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, decodeSample(t), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, path)
+	}
+
+	files := CookieFiles(home)
+	if len(files) != 2 || files[0] != want[0] || files[1] != want[1] {
+		t.Fatalf("got %v, want %v", files, want)
+	}
+	cookies, err := ReadCookies(files[0])
+	if err != nil || len(cookies) != 3 {
+		t.Errorf("ReadCookies: got %d cookies, error %v", len(cookies), err)
+	}
+}
@@ -0,0 +1,40 @@
+package safari
+
+import "errors"
+
+const (
+	// Cookie file locations, relative to the user's home directory. Safari 14+ keeps its
+	// cookies in its sandbox container; older versions use the shared Cookies directory.
+	containerCookiesPath = "Library/Containers/com.apple.Safari/Data/Library/Cookies/Cookies.binarycookies"
+	legacyCookiesPath    = "Library/Cookies/Cookies.binarycookies"
+
+	// Page header (big-endian) that starts every page
+	pageSignature = 0x00000100
+
+	// Cookie record flags
+	flagSecure   = 0x1
+	flagHTTPOnly = 0x4
+
+	// Offsets of the fixed fields of a cookie record
+	recordFlagsOffset    = 8
+	recordHasPortOffset  = 12
+	recordDomainOffset   = 16
+	recordNameOffset     = 20
+	recordPathOffset     = 24
+	recordValueOffset    = 28
+	recordExpiryOffset   = 40
+	recordCreationOffset = 48
+	recordPortOffset     = 56
+	recordHeaderSize     = 56
+
+	// macAbsoluteEpoch is 2001-01-01T00:00:00Z in Unix seconds
+	macAbsoluteEpoch = 978307200
+)
+
+// fileSignature starts every Cookies.binarycookies file
+var fileSignature = []byte("cook")
+
+var (
+	ErrNotBinaryCookies = errors.New("not a binarycookies file")
+	ErrCorrupt          = errors.New("binarycookies: corrupt input")
+)
@@ -0,0 +1,13 @@
+package snappy
+
+const (
+	// Element tags, stored in the low two bits of each tag byte
+	tagLiteral = 0x00
+	tagCopy1   = 0x01
+	tagCopy2   = 0x02
+	tagCopy4   = 0x03
+
+	// maxCompressionRatio bounds the output of the input: a 3-byte copy with a 2-byte offset
+	// produces at most 64 bytes, the most any element yields per input byte
+	maxCompressionRatio = 22
+)
@@ -0,0 +1,120 @@
+package snappy
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	ErrCorrupt  = errors.New("snappy: corrupt input")
+	ErrTooLarge = errors.New("snappy: decoded block is too large")
+)
+
+// maxDecodedLen caps the size of a single decoded block to avoid huge allocations from corrupt data
+const maxDecodedLen = 1 << 30
+
+// DecodedLen returns the length of the decoded block stored in the varint preamble
+func DecodedLen(src []byte) (int, error) {
+	v, n := binary.Uvarint(src)
+	if n <= 0 {
+		return 0, ErrCorrupt
+	}
+	if v > maxDecodedLen {
+		return 0, ErrTooLarge
+	}
+	return int(v), nil
+}
+
+// Decode decodes a raw snappy block (no framing format). A decoded length the block is too
+// short to produce is rejected before the output is allocated.
+func Decode(src []byte) ([]byte, error) {
+	dLen, err := DecodedLen(src)
+	if err != nil {
+		return nil, err
+	}
+	_, n := binary.Uvarint(src)
+	src = src[n:]
+	if int64(dLen) > int64(len(src))*maxCompressionRatio {
+		return nil, ErrCorrupt
+	}
+
+	dst := make([]byte, 0, dLen)
+	for len(src) > 0 {
+		tag := src[0]
+		switch tag & 0x03 {
+		case tagLiteral:
+			length := int(tag >> 2)
+			src = src[1:]
+			if length >= 60 {
+				// lengths 60-63 mean the literal length is stored in the next 1-4 bytes
+				extra := length - 59
+				if len(src) < extra {
+					return nil, ErrCorrupt
+				}
+				length = 0
+				for i := 0; i < extra; i++ {
+					length |= int(src[i]) << (8 * i)
+				}
+				src = src[extra:]
+			}
+			length++
+			if length <= 0 || length > len(src) || len(dst)+length > dLen {
+				return nil, ErrCorrupt
+			}
+			dst = append(dst, src[:length]...)
+			src = src[length:]
+			continue
+
+		case tagCopy1:
+			if len(src) < 2 {
+				return nil, ErrCorrupt
+			}
+			length := 4 + int(tag>>2)&0x07
+			offset := int(tag&0xe0)<<3 | int(src[1])
+			src = src[2:]
+			if dst, err = appendCopy(dst, offset, length, dLen); err != nil {
+				return nil, err
+			}
+
+		case tagCopy2:
+			if len(src) < 3 {
+				return nil, ErrCorrupt
+			}
+			length := 1 + int(tag>>2)
+			offset := int(binary.LittleEndian.Uint16(src[1:3]))
+			src = src[3:]
+			if dst, err = appendCopy(dst, offset, length, dLen); err != nil {
+				return nil, err
+			}
+
+		case tagCopy4:
+			if len(src) < 5 {
+				return nil, ErrCorrupt
+			}
+			length := 1 + int(tag>>2)
+			offset := int(binary.LittleEndian.Uint32(src[1:5]))
+			src = src[5:]
+			if dst, err = appendCopy(dst, offset, length, dLen); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(dst) != dLen {
+		return nil, ErrCorrupt
+	}
+	return dst, nil
+}
+
+// appendCopy appends a back-reference of length bytes starting offset bytes before the end of dst.
+// The source and destination may overlap, so the copy is done byte by byte.
+func appendCopy(dst []byte, offset, length, dLen int) ([]byte, error) {
+	if offset <= 0 || offset > len(dst) || len(dst)+length > dLen {
+		return nil, ErrCorrupt
+	}
+	start := len(dst) - offset
+	for i := 0; i < length; i++ {
+		dst = append(dst, dst[start+i])
+	}
+	return dst, nil
+}
@@ -0,0 +1,47 @@
+package snappy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	long := bytes.Repeat([]byte("0123456789"), 10)
+
+	tests := []struct {
+		name    string
+		src     []byte
+		want    []byte
+		wantErr error
+	}{
+		{"literal", []byte{3, 2 << 2, 'a', 'b', 'c'}, []byte("abc"), nil},
+		{"long literal", append([]byte{100, 60 << 2, 99}, long...), long, nil},
+		{"overlapping 1-byte offset copy", []byte{9, 2 << 2, 'a', 'b', 'c', 2<<2 | tagCopy1, 3}, []byte("abcabcabc"), nil},
+		{"2-byte offset copy", []byte{6, 2 << 2, 'x', 'y', 'z', 2<<2 | tagCopy2, 3, 0}, []byte("xyzxyz"), nil},
+		{"4-byte offset copy", []byte{4, 1 << 2, 'h', 'i', 1<<2 | tagCopy4, 2, 0, 0, 0}, []byte("hihi"), nil},
+		{"empty block", []byte{0}, []byte{}, nil},
+		{"missing preamble", nil, nil, ErrCorrupt},
+		{"too large", binary.AppendUvarint(nil, maxDecodedLen+1), nil, ErrTooLarge},
+		{"length the input cannot produce", append(binary.AppendUvarint(nil, 1<<20), 0, 'a'), nil, ErrCorrupt},
+		{"longest 2-byte offset copies", []byte{129, 1, 0, 'a', 63<<2 | tagCopy2, 1, 0, 63<<2 | tagCopy2, 1, 0}, bytes.Repeat([]byte("a"), 129), nil},
+		{"literal past the input", []byte{3, 2 << 2, 'a'}, nil, ErrCorrupt},
+		{"literal past the length", []byte{1, 2 << 2, 'a', 'b', 'c'}, nil, ErrCorrupt},
+		{"zero offset", []byte{5, 0, 'a', tagCopy1, 0}, nil, ErrCorrupt},
+		{"offset before the output", []byte{5, 0, 'a', tagCopy1, 2}, nil, ErrCorrupt},
+		{"truncated copy", []byte{5, 0, 'a', tagCopy2, 1}, nil, ErrCorrupt},
+		{"short output", []byte{4, 2 << 2, 'a', 'b', 'c'}, nil, ErrCorrupt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.src)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !bytes.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package webstorage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"unicode/utf16"
+)
+
+// decodeUTF16LE decodes little-endian UTF-16 bytes, ignoring a trailing odd byte
+func decodeUTF16LE(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[i*2:])
+	}
+	return string(utf16.Decode(u))
+}
+
+// decodeUTF16BE decodes big-endian UTF-16 bytes, ignoring a trailing odd byte
+func decodeUTF16BE(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.BigEndian.Uint16(b[i*2:])
+	}
+	return string(utf16.Decode(u))
+}
+
+// decodeLatin1 maps each byte to the code point of the same value
+func decodeLatin1(b []byte) string {
+	r := make([]rune, len(b))
+	for i, c := range b {
+		r[i] = rune(c)
+	}
+	return string(r)
+}
+
+// decodeChromiumString decodes a Local Storage string, which starts with an encoding byte
+func decodeChromiumString(b []byte) (string, error) {
+	if len(b) == 0 {
+		return "", errors.New("empty string data")
+	}
+	switch b[0] {
+	case stringEncodingUTF16:
+		return decodeUTF16LE(b[1:]), nil
+	case stringEncodingLatin1:
+		return decodeLatin1(b[1:]), nil
+	default:
+		return "", errors.New("unknown string encoding")
+	}
+}
+
+func (f *JSONFormatter) Format() (string, error) {
+	jsonData, err := json.MarshalIndent(f.Records, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
@@ -0,0 +1,272 @@
+package webstorage
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go-cookie-monster/pkg/leveldb"
+	"go-cookie-monster/pkg/snappy"
+)
+
+// ReadIndexedDB reads the object store records of every IndexedDB database in a Chromium profile directory
+func ReadIndexedDB(profileDir string) ([]Record, error) {
+	root := filepath.Join(profileDir, indexedDBDir)
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IndexedDB directory %s: %v", root, err)
+	}
+
+	var out []Record
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasSuffix(entry.Name(), indexedDBSuffix) {
+			continue
+		}
+
+		records, err := leveldb.ReadRecords(filepath.Join(root, entry.Name()))
+		if err != nil {
+			out = append(out, Record{
+				StorageType: IndexedDB,
+				Origin:      strings.TrimSuffix(entry.Name(), indexedDBSuffix),
+				Error:       err.Error(),
+			})
+			continue
+		}
+
+		out = append(out, ParseIndexedDB(leveldb.LiveRecords(records), strings.TrimSuffix(entry.Name(), indexedDBSuffix))...)
+	}
+
+	return out, nil
+}
+
+// ParseIndexedDB converts the raw records of one IndexedDB LevelDB store into object store records.
+// fallbackOrigin is used when the store carries no database name metadata.
+func ParseIndexedDB(records []leveldb.Record, fallbackOrigin string) []Record {
+	type dbInfo struct {
+		origin string
+		name   string
+	}
+
+	databases := make(map[int64]dbInfo)
+	storeNames := make(map[[2]int64]string)
+
+	// First pass: database and object store names
+	for _, rec := range records {
+		prefix, rest, err := decodeKeyPrefix(rec.Key)
+		if err != nil || len(rest) == 0 {
+			continue
+		}
+
+		switch {
+		case prefix.database == 0 && prefix.objectStore == 0 && prefix.index == 0 && rest[0] == idbDatabaseNameType:
+			origin, rest, err := decodeStringWithLength(rest[1:])
+			if err != nil {
+				continue
+			}
+			name, _, err := decodeStringWithLength(rest)
+			if err != nil {
+				continue
+			}
+			id, err := decodeInt(rec.Value)
+			if err != nil {
+				continue
+			}
+			databases[id] = dbInfo{origin: origin, name: name}
+
+		case prefix.database != 0 && prefix.objectStore == 0 && prefix.index == 0 && rest[0] == idbObjectStoreMetaDataType:
+			storeID, n := readVarInt(rest[1:])
+			if n <= 0 || len(rest) <= 1+n || rest[1+n] != idbObjectStoreNameType {
+				continue
+			}
+			storeNames[[2]int64{prefix.database, storeID}] = decodeUTF16BE(rec.Value)
+		}
+	}
+
+	// Second pass: object store data
+	var out []Record
+	for _, rec := range records {
+		prefix, rest, err := decodeKeyPrefix(rec.Key)
+		if err != nil || prefix.database == 0 || prefix.objectStore == 0 || prefix.index != idbObjectStoreDataIndex {
+			continue
+		}
+
+		db, ok := databases[prefix.database]
+		if !ok {
+			db = dbInfo{origin: fallbackOrigin}
+		}
+
+		r := Record{
+			StorageType: IndexedDB,
+			Origin:      db.origin,
+			Database:    db.name,
+			ObjectStore: storeNames[[2]int64{prefix.database, prefix.objectStore}],
+		}
+
+		key, _, err := decodeIDBKey(rest)
+		if err != nil {
+			r.Key = fmt.Sprintf("%x", rest)
+		} else {
+			r.Key = key
+		}
+
+		value, err := decodeIDBValue(rec.Value)
+		r.Value = value
+		if err != nil {
+			r.Error = err.Error()
+		}
+
+		out = append(out, r)
+	}
+
+	return out
+}
+
+type keyPrefix struct {
+	database    int64
+	objectStore int64
+	index       int64
+}
+
+// decodeKeyPrefix decodes the variable-width (database, object store, index) prefix of an IndexedDB key.
+// The first byte packs the byte lengths of the three little-endian integers that follow.
+func decodeKeyPrefix(key []byte) (keyPrefix, []byte, error) {
+	if len(key) == 0 {
+		return keyPrefix{}, nil, errors.New("empty key")
+	}
+	dbLen := int(key[0]>>5&0x07) + 1
+	storeLen := int(key[0]>>2&0x07) + 1
+	indexLen := int(key[0]&0x03) + 1
+	if len(key) < 1+dbLen+storeLen+indexLen {
+		return keyPrefix{}, nil, errors.New("key prefix is truncated")
+	}
+
+	pos := 1
+	readInt := func(n int) int64 {
+		var v int64
+		for i := 0; i < n; i++ {
+			v |= int64(key[pos+i]) << (8 * i)
+		}
+		pos += n
+		return v
+	}
+
+	prefix := keyPrefix{
+		database:    readInt(dbLen),
+		objectStore: readInt(storeLen),
+		index:       readInt(indexLen),
+	}
+	return prefix, key[pos:], nil
+}
+
+// decodeInt decodes a value written with Chromium's EncodeInt: a little-endian integer of up to
+// eight bytes, with no length prefix
+func decodeInt(b []byte) (int64, error) {
+	if len(b) == 0 || len(b) > 8 {
+		return 0, errors.New("bad integer length")
+	}
+	var v int64
+	for i := len(b) - 1; i >= 0; i-- {
+		v = v<<8 | int64(b[i])
+	}
+	return v, nil
+}
+
+// decodeStringWithLength decodes a varint character count followed by big-endian UTF-16
+func decodeStringWithLength(b []byte) (string, []byte, error) {
+	length, n := readVarInt(b)
+	if n <= 0 || length < 0 || int64(len(b)-n) < length*2 {
+		return "", nil, errors.New("truncated string")
+	}
+	end := n + int(length)*2
+	return decodeUTF16BE(b[n:end]), b[end:], nil
+}
+
+// decodeIDBKey decodes an encoded IndexedDB key into a JSON-friendly value
+func decodeIDBKey(b []byte) (interface{}, []byte, error) {
+	if len(b) == 0 {
+		return nil, nil, errors.New("empty IndexedDB key")
+	}
+
+	switch b[0] {
+	case idbKeyNull, idbKeyMin:
+		return nil, b[1:], nil
+	case idbKeyString:
+		return decodeStringWithLength(b[1:])
+	case idbKeyDate:
+		if len(b) < 9 {
+			return nil, nil, errors.New("truncated date key")
+		}
+		ms := math.Float64frombits(binary.LittleEndian.Uint64(b[1:9]))
+		return time.UnixMilli(int64(ms)).UTC().Format(time.RFC3339Nano), b[9:], nil
+	case idbKeyNumber:
+		if len(b) < 9 {
+			return nil, nil, errors.New("truncated number key")
+		}
+		return jsonNumber(math.Float64frombits(binary.LittleEndian.Uint64(b[1:9]))), b[9:], nil
+	case idbKeyArray:
+		count, n := readVarInt(b[1:])
+		if n <= 0 || count < 0 {
+			return nil, nil, errors.New("bad array key length")
+		}
+		rest := b[1+n:]
+		items := make([]interface{}, 0, count)
+		for i := int64(0); i < count; i++ {
+			item, r, err := decodeIDBKey(rest)
+			if err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+			rest = r
+		}
+		return items, rest, nil
+	case idbKeyBinary:
+		length, n := readVarInt(b[1:])
+		if n <= 0 || length < 0 || int64(len(b)-1-n) < length {
+			return nil, nil, errors.New("truncated binary key")
+		}
+		end := 1 + n + int(length)
+		return base64.StdEncoding.EncodeToString(b[1+n : end]), b[end:], nil
+	default:
+		return nil, nil, fmt.Errorf("unknown IndexedDB key type %d", b[0])
+	}
+}
+
+// decodeIDBValue strips the record version and Blink wrapping, then deserializes the V8 value
+func decodeIDBValue(b []byte) (interface{}, error) {
+	_, n := readVarInt(b)
+	if n <= 0 {
+		return nil, errors.New("missing record version")
+	}
+	b = b[n:]
+
+	// Blink marks values that were moved to a blob file or compressed
+	if len(b) >= 3 && b[0] == blinkVersionTag && b[1] == 0x11 {
+		switch b[2] {
+		case 0x01:
+			return nil, errors.New("value is stored in an external blob file")
+		case 0x02:
+			decoded, err := snappy.Decode(b[3:])
+			if err != nil {
+				return nil, fmt.Errorf("failed to decompress value: %v", err)
+			}
+			b = decoded
+		}
+	}
+
+	if len(b) == 0 {
+		return nil, nil
+	}
+	return DeserializeV8(b)
+}
+
+// readVarInt reads a LevelDB-style unsigned varint as int64
+func readVarInt(b []byte) (int64, int) {
+	v, n := binary.Uvarint(b)
+	return int64(v), n
+}
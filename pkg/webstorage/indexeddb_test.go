@@ -0,0 +1,59 @@
+package webstorage
+
+import (
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+
+	"go-cookie-monster/pkg/leveldb"
+)
+
+// idbPrefix encodes a key prefix with one-byte database, object store and index IDs
+func idbPrefix(database, objectStore, index byte) []byte {
+	return []byte{0, database, objectStore, index}
+}
+
+// idbString encodes a string as a varint length and big-endian UTF-16
+func idbString(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	b := binary.AppendUvarint(nil, uint64(len(units)))
+	for _, u := range units {
+		b = binary.BigEndian.AppendUint16(b, u)
+	}
+	return b
+}
+
+func TestParseIndexedDB(t *testing.T) {
+	value := []byte{1, 0xff, 0x14, 0xff, 0x0f, '"', 5, 'h', 'e', 'l', 'l', 'o'}
+
+	tests := []struct {
+		name     string
+		database byte // IDs of 128 and above are not valid varints
+	}{
+		{"small database ID", 1},
+		{"large database ID", 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nameKey := append(append(idbPrefix(0, 0, 0), idbDatabaseNameType), idbString("https://a.com")...)
+			nameKey = append(nameKey, idbString("app")...)
+			storeKey := append(idbPrefix(tt.database, 0, 0), idbObjectStoreMetaDataType, 1, idbObjectStoreNameType)
+			dataKey := append(append(idbPrefix(tt.database, 1, idbObjectStoreDataIndex), idbKeyString), idbString("k")...)
+
+			records := ParseIndexedDB([]leveldb.Record{
+				{Key: nameKey, Value: []byte{tt.database}},
+				{Key: storeKey, Value: idbString("store")[1:]},
+				{Key: dataKey, Value: value},
+			}, "fallback")
+
+			if len(records) != 1 {
+				t.Fatalf("got %d records, want 1", len(records))
+			}
+			r := records[0]
+			if r.Origin != "https://a.com" || r.Database != "app" || r.ObjectStore != "store" || r.Key != "k" || r.Value != "hello" {
+				t.Fatalf("unexpected record %+v", r)
+			}
+		})
+	}
+}
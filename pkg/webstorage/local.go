@@ -0,0 +1,59 @@
+package webstorage
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+
+	"go-cookie-monster/pkg/leveldb"
+)
+
+// ReadLocalStorage reads the Local Storage key/value pairs of a Chromium profile directory
+func ReadLocalStorage(profileDir string) ([]Record, error) {
+	records, err := leveldb.ReadRecords(filepath.Join(profileDir, filepath.FromSlash(localStorageDir)))
+	if err != nil {
+		return nil, err
+	}
+	return ParseLocalStorage(leveldb.LiveRecords(records)), nil
+}
+
+// ParseLocalStorage converts raw Local Storage LevelDB records into per-origin records.
+// Data keys have the form "_" + origin + "\x00" + encoded key.
+func ParseLocalStorage(records []leveldb.Record) []Record {
+	var out []Record
+	for _, rec := range records {
+		if !bytes.HasPrefix(rec.Key, []byte(localStorageDataPrefix)) {
+			continue
+		}
+
+		rest := rec.Key[len(localStorageDataPrefix):]
+		sep := bytes.IndexByte(rest, 0)
+		if sep == -1 {
+			continue
+		}
+
+		r := Record{
+			StorageType: LocalStorage,
+			Origin:      string(rest[:sep]),
+		}
+
+		key, err := decodeChromiumString(rest[sep+1:])
+		if err != nil {
+			r.Key = fmt.Sprintf("%x", rest[sep+1:])
+			r.Error = fmt.Sprintf("key: %v", err)
+		} else {
+			r.Key = key
+		}
+
+		value, err := decodeChromiumString(rec.Value)
+		if err != nil {
+			r.Value = fmt.Sprintf("%x", rec.Value)
+			r.Error = fmt.Sprintf("value: %v", err)
+		} else {
+			r.Value = value
+		}
+
+		out = append(out, r)
+	}
+	return out
+}
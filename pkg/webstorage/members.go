@@ -0,0 +1,108 @@
+package webstorage
+
+const (
+	// Storage types reported in Record.StorageType
+	LocalStorage   = "local"
+	SessionStorage = "session"
+	IndexedDB      = "indexeddb"
+
+	// Directory names inside a Chromium profile
+	localStorageDir   = "Local Storage/leveldb"
+	sessionStorageDir = "Session Storage"
+	indexedDBDir      = "IndexedDB"
+	indexedDBSuffix   = ".indexeddb.leveldb"
+
+	// Local Storage key prefixes
+	localStorageDataPrefix = "_"
+	localStorageMetaPrefix = "META:"
+
+	// Session Storage key prefixes
+	sessionNamespacePrefix = "namespace-"
+	sessionMapPrefix       = "map-"
+
+	// Chromium string encodings used by Local Storage
+	stringEncodingUTF16  = 0
+	stringEncodingLatin1 = 1
+)
+
+const (
+	// IndexedDB global metadata type bytes
+	idbDatabaseNameType = 201
+
+	// IndexedDB database metadata type bytes
+	idbObjectStoreMetaDataType = 50
+	idbObjectStoreNameType     = 0
+
+	// Index id used for object store records
+	idbObjectStoreDataIndex = 1
+
+	// IndexedDB key types
+	idbKeyNull   = 0
+	idbKeyString = 1
+	idbKeyDate   = 2
+	idbKeyNumber = 3
+	idbKeyArray  = 4
+	idbKeyMin    = 5
+	idbKeyBinary = 6
+)
+
+const (
+	// V8 value serializer tags
+	v8Version            = 0xFF
+	v8Padding            = 0x00
+	v8VerifyObjectCount  = '?'
+	v8Undefined          = '_'
+	v8Null               = '0'
+	v8True               = 'T'
+	v8False              = 'F'
+	v8Int32              = 'I'
+	v8Uint32             = 'U'
+	v8Double             = 'N'
+	v8BigInt             = 'Z'
+	v8Utf8String         = 'S'
+	v8OneByteString      = '"'
+	v8TwoByteString      = 'c'
+	v8ObjectReference    = '^'
+	v8BeginJSObject      = 'o'
+	v8EndJSObject        = '{'
+	v8BeginSparseJSArray = 'a'
+	v8EndSparseJSArray   = '@'
+	v8BeginDenseJSArray  = 'A'
+	v8EndDenseJSArray    = '$'
+	v8Date               = 'D'
+	v8TrueObject         = 'y'
+	v8FalseObject        = 'x'
+	v8NumberObject       = 'n'
+	v8BigIntObject       = 'z'
+	v8StringObject       = 's'
+	v8RegExp             = 'R'
+	v8BeginJSMap         = ';'
+	v8EndJSMap           = ':'
+	v8BeginJSSet         = '\''
+	v8EndJSSet           = ','
+	v8ArrayBuffer        = 'B'
+	v8ArrayBufferView    = 'V'
+	v8TheHole            = '-'
+	v8HostObject         = '\\'
+
+	// Blink wraps V8 data in its own envelope that starts with these tags
+	blinkVersionTag = 0xFF
+	blinkTrailerTag = 0xFE
+)
+
+// Record is a single key/value pair recovered from a web storage area
+type Record struct {
+	StorageType string      `json:"storageType"`
+	Origin      string      `json:"origin"`
+	Namespace   string      `json:"namespace,omitempty"`
+	Database    string      `json:"database,omitempty"`
+	ObjectStore string      `json:"objectStore,omitempty"`
+	Key         interface{} `json:"key"`
+	Value       interface{} `json:"value"`
+	Error       string      `json:"error,omitempty"`
+}
+
+// JSONFormatter formats storage records as indented JSON
+type JSONFormatter struct {
+	Records []Record
+}
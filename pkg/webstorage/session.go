@@ -0,0 +1,82 @@
+package webstorage
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go-cookie-monster/pkg/leveldb"
+)
+
+// ReadSessionStorage reads the Session Storage key/value pairs of a Chromium profile directory
+func ReadSessionStorage(profileDir string) ([]Record, error) {
+	records, err := leveldb.ReadRecords(filepath.Join(profileDir, sessionStorageDir))
+	if err != nil {
+		return nil, err
+	}
+	return ParseSessionStorage(leveldb.LiveRecords(records)), nil
+}
+
+// ParseSessionStorage converts raw Session Storage LevelDB records into per-origin records.
+// Namespace records ("namespace-<id>-<origin>") map an origin to a map id, and map records
+// ("map-<id>-<key>") hold the UTF-16 keys and values of that map.
+func ParseSessionStorage(records []leveldb.Record) []Record {
+	type owner struct {
+		namespace string
+		origin    string
+	}
+
+	// Collect namespace to map id assignments first
+	owners := make(map[string][]owner)
+	for _, rec := range records {
+		key := string(rec.Key)
+		if !strings.HasPrefix(key, sessionNamespacePrefix) {
+			continue
+		}
+		namespace, origin, ok := strings.Cut(key[len(sessionNamespacePrefix):], "-")
+		if !ok || origin == "" {
+			continue
+		}
+		mapID := string(rec.Value)
+		owners[mapID] = append(owners[mapID], owner{namespace: namespace, origin: origin})
+	}
+
+	var out []Record
+	for _, rec := range records {
+		key := string(rec.Key)
+		if !strings.HasPrefix(key, sessionMapPrefix) {
+			continue
+		}
+		mapID, _, ok := strings.Cut(key[len(sessionMapPrefix):], "-")
+		if !ok {
+			continue
+		}
+
+		// The storage key follows "map-<id>-" and is UTF-16 encoded, like the value
+		storageKey := decodeUTF16LE(rec.Key[len(sessionMapPrefix)+len(mapID)+1:])
+		value := decodeUTF16LE(rec.Value)
+
+		mapOwners := owners[mapID]
+		if len(mapOwners) == 0 {
+			// Orphaned maps are still worth reporting
+			mapOwners = []owner{{}}
+		}
+
+		// A map can be shared by several namespaces after a tab is cloned
+		for _, o := range mapOwners {
+			out = append(out, Record{
+				StorageType: SessionStorage,
+				Origin:      o.origin,
+				Namespace:   o.namespace,
+				Key:         storageKey,
+				Value:       value,
+			})
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Origin < out[j].Origin
+	})
+
+	return out
+}
@@ -0,0 +1,499 @@
+package webstorage
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// v8Reader is a best-effort reader for the V8 ValueSerializer wire format
+type v8Reader struct {
+	data    []byte
+	pos     int
+	objects []interface{}
+}
+
+// DeserializeV8 decodes V8 serialized data (optionally inside a Blink envelope) into JSON-friendly
+// values: objects become maps, arrays slices, dates RFC 3339 strings and binary data base64.
+// When decoding fails part way, the error is returned with whatever was decoded so far.
+func DeserializeV8(data []byte) (interface{}, error) {
+	r := &v8Reader{data: data}
+	if err := r.readHeader(); err != nil {
+		return nil, err
+	}
+	return r.readValue()
+}
+
+// readHeader skips the Blink and V8 version tags and the Blink trailer offset
+func (r *v8Reader) readHeader() error {
+	for r.pos < len(r.data) {
+		switch r.data[r.pos] {
+		case v8Version:
+			r.pos++
+			if _, err := r.readVarint(); err != nil {
+				return err
+			}
+		case blinkTrailerTag:
+			// tag, 8 byte trailer offset, 4 byte trailer size
+			if r.pos+13 > len(r.data) {
+				return errors.New("truncated blink trailer offset")
+			}
+			r.pos += 13
+		case v8Padding:
+			r.pos++
+		default:
+			return nil
+		}
+	}
+	return errors.New("no value after header")
+}
+
+func (r *v8Reader) readTag() (byte, error) {
+	for r.pos < len(r.data) {
+		tag := r.data[r.pos]
+		r.pos++
+		if tag != v8Padding {
+			return tag, nil
+		}
+	}
+	return 0, errors.New("unexpected end of data")
+}
+
+func (r *v8Reader) peekTag() (byte, error) {
+	for r.pos < len(r.data) && r.data[r.pos] == v8Padding {
+		r.pos++
+	}
+	if r.pos >= len(r.data) {
+		return 0, errors.New("unexpected end of data")
+	}
+	return r.data[r.pos], nil
+}
+
+func (r *v8Reader) readVarint() (uint64, error) {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		return 0, errors.New("bad varint")
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *v8Reader) readBytes(n uint64) ([]byte, error) {
+	if uint64(len(r.data)-r.pos) < n {
+		return nil, errors.New("unexpected end of data")
+	}
+	b := r.data[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return b, nil
+}
+
+func (r *v8Reader) readDouble() (float64, error) {
+	b, err := r.readBytes(8)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+}
+
+// addObject registers a value so later object references can resolve to it
+func (r *v8Reader) addObject(v interface{}) int {
+	r.objects = append(r.objects, v)
+	return len(r.objects) - 1
+}
+
+func (r *v8Reader) readValue() (interface{}, error) {
+	tag, err := r.readTag()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tag {
+	case v8VerifyObjectCount:
+		if _, err := r.readVarint(); err != nil {
+			return nil, err
+		}
+		return r.readValue()
+	case v8Undefined, v8Null, v8TheHole:
+		return nil, nil
+	case v8True:
+		return true, nil
+	case v8False:
+		return false, nil
+	case v8Int32:
+		v, err := r.readVarint()
+		if err != nil {
+			return nil, err
+		}
+		// zigzag encoded
+		return int64(int32(uint32(v>>1) ^ -uint32(v&1))), nil
+	case v8Uint32:
+		v, err := r.readVarint()
+		if err != nil {
+			return nil, err
+		}
+		return int64(uint32(v)), nil
+	case v8Double:
+		v, err := r.readDouble()
+		if err != nil {
+			return nil, err
+		}
+		return jsonNumber(v), nil
+	case v8BigInt:
+		return r.readBigInt()
+	case v8OneByteString, v8TwoByteString, v8Utf8String:
+		return r.readStringBody(tag)
+	case v8ObjectReference:
+		id, err := r.readVarint()
+		if err != nil {
+			return nil, err
+		}
+		if id >= uint64(len(r.objects)) {
+			return nil, fmt.Errorf("invalid object reference %d", id)
+		}
+		return r.objects[id], nil
+	case v8BeginJSObject:
+		obj := make(map[string]interface{})
+		r.addObject(obj)
+		if err := r.readProperties(obj, v8EndJSObject); err != nil {
+			return obj, err
+		}
+		_, err := r.readVarint()
+		return obj, err
+	case v8BeginDenseJSArray:
+		return r.readDenseArray()
+	case v8BeginSparseJSArray:
+		return r.readSparseArray()
+	case v8Date:
+		v, err := r.readDouble()
+		if err != nil {
+			return nil, err
+		}
+		date := time.UnixMilli(int64(v)).UTC().Format(time.RFC3339Nano)
+		r.addObject(date)
+		return date, nil
+	case v8TrueObject, v8FalseObject:
+		r.addObject(tag == v8TrueObject)
+		return tag == v8TrueObject, nil
+	case v8NumberObject:
+		v, err := r.readDouble()
+		if err != nil {
+			return nil, err
+		}
+		r.addObject(jsonNumber(v))
+		return jsonNumber(v), nil
+	case v8BigIntObject:
+		v, err := r.readBigInt()
+		r.addObject(v)
+		return v, err
+	case v8StringObject:
+		v, err := r.readValue()
+		r.addObject(v)
+		return v, err
+	case v8RegExp:
+		pattern, err := r.readValue()
+		if err != nil {
+			return nil, err
+		}
+		flags, err := r.readVarint()
+		if err != nil {
+			return nil, err
+		}
+		re := fmt.Sprintf("/%v/%s", pattern, regExpFlags(flags))
+		r.addObject(re)
+		return re, nil
+	case v8BeginJSMap:
+		return r.readMap()
+	case v8BeginJSSet:
+		return r.readSet()
+	case v8ArrayBuffer:
+		length, err := r.readVarint()
+		if err != nil {
+			return nil, err
+		}
+		b, err := r.readBytes(length)
+		if err != nil {
+			return nil, err
+		}
+		encoded := base64.StdEncoding.EncodeToString(b)
+		r.addObject(encoded)
+
+		// An array buffer view may immediately follow its buffer
+		if next, err := r.peekTag(); err == nil && next == v8ArrayBufferView {
+			r.pos++
+			return r.readArrayBufferView(b)
+		}
+		return encoded, nil
+	case v8HostObject:
+		return nil, errors.New("unsupported host object")
+	default:
+		return nil, fmt.Errorf("unsupported V8 tag 0x%02x", tag)
+	}
+}
+
+// readStringBody reads the payload of a string whose tag has already been consumed
+func (r *v8Reader) readStringBody(tag byte) (string, error) {
+	length, err := r.readVarint()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.readBytes(length)
+	if err != nil {
+		return "", err
+	}
+
+	switch tag {
+	case v8OneByteString:
+		return decodeLatin1(b), nil
+	case v8TwoByteString:
+		return decodeUTF16LE(b), nil
+	default:
+		if !utf8.Valid(b) {
+			return decodeLatin1(b), nil
+		}
+		return string(b), nil
+	}
+}
+
+func (r *v8Reader) readBigInt() (interface{}, error) {
+	bitfield, err := r.readVarint()
+	if err != nil {
+		return nil, err
+	}
+	digits, err := r.readBytes(bitfield >> 1)
+	if err != nil {
+		return nil, err
+	}
+
+	// digits are little-endian
+	be := make([]byte, len(digits))
+	for i, d := range digits {
+		be[len(digits)-1-i] = d
+	}
+	v := new(big.Int).SetBytes(be)
+	if bitfield&1 == 1 {
+		v.Neg(v)
+	}
+	return v.String(), nil
+}
+
+// readProperties reads key/value pairs into obj until the end tag is reached
+func (r *v8Reader) readProperties(obj map[string]interface{}, end byte) error {
+	for {
+		tag, err := r.peekTag()
+		if err != nil {
+			return err
+		}
+		if tag == end {
+			r.pos++
+			return nil
+		}
+
+		key, err := r.readValue()
+		if err != nil {
+			return err
+		}
+		value, err := r.readValue()
+		obj[propertyKey(key)] = value
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (r *v8Reader) readDenseArray() (interface{}, error) {
+	length, err := r.readVarint()
+	if err != nil {
+		return nil, err
+	}
+	if length > uint64(len(r.data)) {
+		return nil, errors.New("dense array length out of range")
+	}
+
+	arr := make([]interface{}, 0, length)
+	id := r.addObject(arr)
+	for i := uint64(0); i < length; i++ {
+		v, err := r.readValue()
+		arr = append(arr, v)
+		if err != nil {
+			return arr, err
+		}
+	}
+	r.objects[id] = arr
+
+	// Non-index properties follow the elements
+	extra := make(map[string]interface{})
+	if err := r.readProperties(extra, v8EndDenseJSArray); err != nil {
+		return arr, err
+	}
+	if _, err := r.readVarint(); err != nil {
+		return arr, err
+	}
+	if _, err := r.readVarint(); err != nil {
+		return arr, err
+	}
+	return arr, nil
+}
+
+func (r *v8Reader) readSparseArray() (interface{}, error) {
+	length, err := r.readVarint()
+	if err != nil {
+		return nil, err
+	}
+	if length > uint64(len(r.data)) {
+		return nil, errors.New("sparse array length out of range")
+	}
+
+	props := make(map[string]interface{})
+	id := r.addObject(props)
+	if err := r.readProperties(props, v8EndSparseJSArray); err != nil {
+		return props, err
+	}
+	if _, err := r.readVarint(); err != nil {
+		return props, err
+	}
+	if _, err := r.readVarint(); err != nil {
+		return props, err
+	}
+
+	// Rebuild the array from its numeric properties
+	arr := make([]interface{}, length)
+	for k, v := range props {
+		if i, err := strconv.ParseUint(k, 10, 64); err == nil && i < length {
+			arr[i] = v
+		}
+	}
+	r.objects[id] = arr
+	return arr, nil
+}
+
+// readMap decodes a JS Map as a list of [key, value] pairs since keys need not be strings
+func (r *v8Reader) readMap() (interface{}, error) {
+	var entries []interface{}
+	id := r.addObject(entries)
+	for {
+		tag, err := r.peekTag()
+		if err != nil {
+			return entries, err
+		}
+		if tag == v8EndJSMap {
+			r.pos++
+			break
+		}
+		key, err := r.readValue()
+		if err != nil {
+			return entries, err
+		}
+		value, err := r.readValue()
+		entries = append(entries, []interface{}{key, value})
+		if err != nil {
+			return entries, err
+		}
+	}
+	r.objects[id] = entries
+	_, err := r.readVarint()
+	return entries, err
+}
+
+func (r *v8Reader) readSet() (interface{}, error) {
+	var items []interface{}
+	id := r.addObject(items)
+	for {
+		tag, err := r.peekTag()
+		if err != nil {
+			return items, err
+		}
+		if tag == v8EndJSSet {
+			r.pos++
+			break
+		}
+		v, err := r.readValue()
+		items = append(items, v)
+		if err != nil {
+			return items, err
+		}
+	}
+	r.objects[id] = items
+	_, err := r.readVarint()
+	return items, err
+}
+
+// readArrayBufferView reads a typed array view over buffer and returns the viewed bytes as base64
+func (r *v8Reader) readArrayBufferView(buffer []byte) (interface{}, error) {
+	if _, err := r.readBytes(1); err != nil { // view subtag
+		return nil, err
+	}
+	offset, err := r.readVarint()
+	if err != nil {
+		return nil, err
+	}
+	length, err := r.readVarint()
+	if err != nil {
+		return nil, err
+	}
+
+	// Newer serializer versions append a flags varint
+	if r.pos < len(r.data) && !isV8Tag(r.data[r.pos]) {
+		if _, err := r.readVarint(); err != nil {
+			return nil, err
+		}
+	}
+
+	if offset+length > uint64(len(buffer)) {
+		return nil, errors.New("array buffer view out of range")
+	}
+	view := base64.StdEncoding.EncodeToString(buffer[offset : offset+length])
+	r.addObject(view)
+	return view, nil
+}
+
+// isV8Tag reports whether b could start the next value, used to detect optional trailing fields
+func isV8Tag(b byte) bool {
+	switch b {
+	case v8EndJSObject, v8EndDenseJSArray, v8EndSparseJSArray, v8EndJSMap, v8EndJSSet,
+		v8OneByteString, v8TwoByteString, v8Utf8String, v8BeginJSObject, v8Int32, v8Uint32,
+		v8Double, v8True, v8False, v8Null, v8Undefined, v8ObjectReference:
+		return true
+	}
+	return false
+}
+
+// propertyKey converts a decoded property key to its JSON object key
+func propertyKey(key interface{}) string {
+	switch k := key.(type) {
+	case string:
+		return k
+	case int64:
+		return strconv.FormatInt(k, 10)
+	default:
+		return fmt.Sprint(k)
+	}
+}
+
+// regExpFlags converts the V8 regexp flag bits to their source form
+func regExpFlags(flags uint64) string {
+	const letters = "gimnsuyv"
+	out := make([]byte, 0, len(letters))
+	for i := 0; i < len(letters); i++ {
+		if flags&(1<<uint(i)) != 0 {
+			out = append(out, letters[i])
+		}
+	}
+	return string(out)
+}
+
+// jsonNumber returns v as a float64, or as a string when JSON cannot represent it
+func jsonNumber(v float64) interface{} {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+		return int64(v)
+	}
+	return v
+}